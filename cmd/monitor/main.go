@@ -20,6 +20,11 @@ func main() {
 	defer conn.Close()
 
 	fmt.Println("Connected to TCP sync:", addr)
+
+	// tuỳ chọn: JWT ở tham số thứ 2 để nhận notification riêng của user
+	if len(os.Args) > 2 {
+		fmt.Fprintf(conn, "AUTH %s\n", os.Args[2])
+	}
 	fmt.Println("Waiting for progress updates...")
 
 	sc := bufio.NewScanner(conn)
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/apierr"
+	"mangahub/internal/auth"
+	"mangahub/internal/logging"
+	"mangahub/internal/mail"
+	"mangahub/internal/user"
+)
+
+const (
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+// GET /me
+func handleGetMe(c *gin.Context, db *sql.DB) {
+	p, err := user.GetProfile(c.Request.Context(), db, c.GetString(auth.CtxUserIDKey))
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// PATCH /me: chỉ cập nhật field có trong body; đổi email => gửi lại mail xác thực
+func handleUpdateMe(c *gin.Context, db *sql.DB, mailer mail.Mailer) {
+	var req struct {
+		DisplayName *string `json:"display_name"`
+		AvatarURL   *string `json:"avatar_url"`
+		Bio         *string `json:"bio"`
+		Email       *string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Write(c, apierr.Invalid("invalid body"))
+		return
+	}
+	userID := c.GetString(auth.CtxUserIDKey)
+
+	if req.DisplayName != nil {
+		*req.DisplayName = strings.TrimSpace(*req.DisplayName)
+		if utf8.RuneCountInString(*req.DisplayName) > 50 {
+			apierr.Write(c, apierr.InvalidField("display_name", "too_long", "display_name must be at most 50 characters"))
+			return
+		}
+	}
+	if req.AvatarURL != nil {
+		*req.AvatarURL = strings.TrimSpace(*req.AvatarURL)
+		if err := validateAvatarURL(*req.AvatarURL); err != nil {
+			apierr.Write(c, err)
+			return
+		}
+	}
+	if req.Bio != nil && utf8.RuneCountInString(*req.Bio) > 500 {
+		apierr.Write(c, apierr.InvalidField("bio", "too_long", "bio must be at most 500 characters"))
+		return
+	}
+	var email string
+	if req.Email != nil {
+		var err error
+		if email, err = normalizeEmail(*req.Email); err != nil {
+			apierr.Write(c, err)
+			return
+		}
+	}
+
+	current, err := user.GetProfile(c.Request.Context(), db, userID)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	if err := user.UpdateProfile(c.Request.Context(), db, userID, user.ProfileUpdate{
+		DisplayName: req.DisplayName, AvatarURL: req.AvatarURL, Bio: req.Bio,
+	}); err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+
+	if req.Email != nil && email != current.Email {
+		err := user.SetEmail(c.Request.Context(), db, userID, email)
+		if errors.Is(err, user.ErrEmailTaken) {
+			apierr.Write(c, apierr.Conflict(err.Error()).WithReason("email_taken"))
+			return
+		}
+		if err != nil {
+			apierr.Write(c, apierr.DB(err, "user"))
+			return
+		}
+		if email != "" {
+			if err := sendVerificationEmail(c.Request.Context(), db, mailer, userID, current.Username, email); err != nil {
+				logger.WarnContext(c.Request.Context(), "send verification failed", slog.String("user_id", userID), logging.Err(err))
+			}
+		}
+	}
+	handleGetMe(c, db)
+}
+
+// POST /me/password: đổi password, mọi token cũ bị thu hồi, trả token mới cho session hiện tại
+func handleChangePassword(c *gin.Context, db *sql.DB) {
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+		apierr.Write(c, apierr.Invalid("current_password/new_password required"))
+		return
+	}
+	if err := validatePassword("new_password", req.NewPassword); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	userID := c.GetString(auth.CtxUserIDKey)
+
+	err := user.CheckPassword(c.Request.Context(), db, userID, req.CurrentPassword)
+	if errors.Is(err, user.ErrInvalidPassword) {
+		apierr.Write(c, apierr.Unauthenticated("invalid credentials").WithReason("invalid_credentials"))
+		return
+	}
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	if _, err := user.SetPassword(c.Request.Context(), db, userID, req.NewPassword); err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+
+	token, err := auth.SignJWT(jwtSecret, userID, c.GetString(auth.CtxUsernameKey), c.GetString(auth.CtxRoleKey), 24*time.Hour)
+	if err != nil {
+		apierr.Write(c, apierr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "token": token})
+}
+
+// POST /me/email/verification: gửi lại mail xác thực
+func handleResendVerification(c *gin.Context, db *sql.DB, mailer mail.Mailer) {
+	userID := c.GetString(auth.CtxUserIDKey)
+	p, err := user.GetProfile(c.Request.Context(), db, userID)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	if p.Email == "" {
+		apierr.Write(c, apierr.Invalid("no email on account").WithReason("no_email"))
+		return
+	}
+	if p.EmailVerified {
+		apierr.Write(c, apierr.Invalid("email already verified").WithReason("email_already_verified"))
+		return
+	}
+	if err := sendVerificationEmail(c.Request.Context(), db, mailer, userID, p.Username, p.Email); err != nil {
+		logger.WarnContext(c.Request.Context(), "send verification failed", slog.String("user_id", userID), logging.Err(err))
+		apierr.Write(c, apierr.New(apierr.CodeUnavailable, "send mail failed").WithReason("mail_failed"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// GET /auth/verify-email?token= (link trong mail) hoặc POST {token}
+func handleVerifyEmail(c *gin.Context, db *sql.DB) {
+	token := c.Query("token")
+	if token == "" {
+		var req struct {
+			Token string `json:"token"`
+		}
+		_ = c.ShouldBindJSON(&req)
+		token = req.Token
+	}
+	if token == "" {
+		apierr.Write(c, apierr.InvalidField("token", "required", "token required"))
+		return
+	}
+
+	userID, email, err := user.ConsumeToken(c.Request.Context(), db, token, user.PurposeVerifyEmail)
+	if err == nil {
+		err = user.MarkEmailVerified(c.Request.Context(), db, userID, email)
+	}
+	if errors.Is(err, user.ErrInvalidToken) {
+		apierr.Write(c, apierr.InvalidField("token", "invalid", err.Error()).WithReason("invalid_token"))
+		return
+	}
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "email": email})
+}
+
+// POST /auth/password/forgot {email}: luôn trả 200 để không lộ email nào đã đăng ký
+func handleForgotPassword(c *gin.Context, db *sql.DB, mailer mail.Mailer) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" {
+		apierr.Write(c, apierr.InvalidField("email", "required", "email required"))
+		return
+	}
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+
+	if u, err := user.GetByEmail(c.Request.Context(), db, email); err == nil {
+		token, err := user.CreateToken(c.Request.Context(), db, u.ID, user.PurposeResetPassword, email, resetPasswordTTL)
+		if err == nil {
+			err = mailer.Send(mail.Message{
+				To:      email,
+				Subject: "Reset your MangaHub password",
+				Body: fmt.Sprintf("Hi %s,\n\nUse this token to reset your password (valid for 1 hour):\n\n%s\n\n"+
+					"POST %s/api/v1/auth/password/reset {\"token\": \"...\", \"new_password\": \"...\"}\n\n"+
+					"If you did not request this, ignore this email.\n", u.Username, token, appBaseURL),
+			})
+		}
+		if err != nil {
+			logger.WarnContext(c.Request.Context(), "password reset mail failed", slog.String("user_id", u.ID), logging.Err(err))
+		}
+	} else if err != sql.ErrNoRows {
+		logger.WarnContext(c.Request.Context(), "password reset lookup failed", logging.Err(err))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// POST /auth/password/reset {token, new_password}: đặt password mới, thu hồi mọi session
+func handleResetPassword(c *gin.Context, db *sql.DB) {
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" || req.NewPassword == "" {
+		apierr.Write(c, apierr.Invalid("token/new_password required"))
+		return
+	}
+	if err := validatePassword("new_password", req.NewPassword); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+
+	userID, _, err := user.ConsumeToken(c.Request.Context(), db, req.Token, user.PurposeResetPassword)
+	if errors.Is(err, user.ErrInvalidToken) {
+		apierr.Write(c, apierr.InvalidField("token", "invalid", err.Error()).WithReason("invalid_token"))
+		return
+	}
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	if _, err := user.SetPassword(c.Request.Context(), db, userID, req.NewPassword); err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// DELETE /me {password}: xoá tài khoản (library/review/DM bị xoá, chat công khai được ẩn danh)
+func handleDeleteMe(c *gin.Context, db *sql.DB) {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Password == "" {
+		apierr.Write(c, apierr.InvalidField("password", "required", "password required"))
+		return
+	}
+	userID := c.GetString(auth.CtxUserIDKey)
+
+	err := user.CheckPassword(c.Request.Context(), db, userID, req.Password)
+	if errors.Is(err, user.ErrInvalidPassword) {
+		apierr.Write(c, apierr.Unauthenticated("invalid credentials").WithReason("invalid_credentials"))
+		return
+	}
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	if err := user.DeleteAccount(c.Request.Context(), db, userID); err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// sendVerificationEmail tạo token xác thực cho userID và gửi tới email (lời chào dùng username)
+func sendVerificationEmail(ctx context.Context, db *sql.DB, mailer mail.Mailer, userID, username, email string) error {
+	token, err := user.CreateToken(ctx, db, userID, user.PurposeVerifyEmail, email, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Verify your MangaHub email",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening:\n\n%s/api/v1/auth/verify-email?token=%s\n\n"+
+			"The link expires in 48 hours.\n", username, appBaseURL, url.QueryEscape(token)),
+	})
+}
+
+// checkSession thu hồi token của user đã xoá hoặc phát hành trước lần đổi/reset password gần nhất
+func checkSession(db *sql.DB, claims *auth.Claims) error {
+	after, err := user.TokensValidAfter(context.Background(), db, claims.UserID)
+	if err == sql.ErrNoRows {
+		return auth.ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if !after.IsZero() && (claims.IssuedAt == nil || claims.IssuedAt.Time.Before(after)) {
+		return auth.ErrSessionRevoked
+	}
+	return nil
+}
+
+// newMailer: MAILER=stdout (mặc định) | file (MAIL_DIR) | smtp (SMTP_ADDR, SMTP_FROM)
+func newMailer(kind string) (mail.Mailer, error) {
+	switch kind {
+	case "", "stdout":
+		return mail.Stdout{}, nil
+	case "file":
+		dir := envOr("MAIL_DIR", "./data/mail")
+		logger.Info("writing outgoing mail to dir", slog.String("dir", dir))
+		return &mail.File{Dir: dir}, nil
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			return nil, errors.New("SMTP_ADDR required for smtp mailer")
+		}
+		return mail.SMTP{Addr: addr, From: envOr("SMTP_FROM", "no-reply@mangahub.local")}, nil
+	}
+	return nil, fmt.Errorf("unknown mailer %q (stdout|file|smtp)", kind)
+}
+
+func validatePassword(field, password string) error {
+	if len(password) < 6 || len(password) > 100 {
+		return apierr.InvalidField(field, "invalid", field+" must be 6-100 characters")
+	}
+	return nil
+}
+
+// normalizeEmail: "" = xoá email
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", nil
+	}
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 254 {
+		return "", apierr.InvalidField("email", "invalid", "invalid email")
+	}
+	return email, nil
+}
+
+func validateAvatarURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(raw) > 500 {
+		return apierr.InvalidField("avatar_url", "invalid", "avatar_url must be an http(s) URL")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/apierr"
+	"mangahub/internal/auth"
+	"mangahub/internal/chat"
+	"mangahub/internal/library"
+	"mangahub/internal/social"
+	"mangahub/internal/websocket"
+)
+
+// GET /chat/:room/messages?before=<id>&limit= (mới -> cũ, next_before dùng cho trang kế)
+func handleChatHistory(c *gin.Context, store *chat.Store, hub *websocket.ChatHub) {
+	userID := c.GetString(auth.CtxUserIDKey)
+	room, err := websocket.NormalizeRoom(c.Param("room"), userID)
+	if err != nil {
+		apierr.Write(c, apierr.InvalidField("room", "invalid", err.Error()))
+		return
+	}
+	if !websocket.IsDMParticipant(room, userID) {
+		apierr.Write(c, apierr.Forbidden("forbidden").WithReason("not_dm_participant"))
+		return
+	}
+	if err := hub.CheckRoomAccess(room, userID); err != nil {
+		apierr.Write(c, apierr.Forbidden(err.Error()).WithReason("room_forbidden"))
+		return
+	}
+
+	var before int64
+	if v := c.Query("before"); v != "" {
+		before, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || before < 0 {
+			apierr.Write(c, apierr.InvalidField("before", "invalid", "invalid before cursor"))
+			return
+		}
+	}
+	limit := parseInt(c.Query("limit"), 50)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	msgs, err := store.Before(room, before, limit)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "chat"))
+		return
+	}
+
+	resp := gin.H{"room": room, "messages": msgs}
+	if len(msgs) == limit {
+		resp["next_before"] = msgs[len(msgs)-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func handleSetChatRetention(c *gin.Context, store *chat.Store) {
+	var req struct {
+		RetentionHours *int `json:"retention_hours"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.RetentionHours == nil || *req.RetentionHours < 0 {
+		apierr.Write(c, apierr.InvalidField("retention_hours", "required", "retention_hours (>= 0, 0 = keep forever) required"))
+		return
+	}
+	room, err := websocket.NormalizeRoomAdmin(c.Param("room"))
+	if err != nil {
+		apierr.Write(c, apierr.InvalidField("room", "invalid", err.Error()))
+		return
+	}
+
+	if err := store.SetRetention(room, time.Duration(*req.RetentionHours)*time.Hour); err != nil {
+		apierr.Write(c, apierr.DB(err, "chat"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "room": room, "retention_hours": *req.RetentionHours})
+}
+
+// listRoomAccess: room list:<owner>:<list_name> chỉ mở cho chủ list và follower của họ,
+// list phải tồn tại và không nằm trong list_privacy
+func listRoomAccess(db *sql.DB) websocket.ListAccessFunc {
+	return func(owner, listName, viewerID string) error {
+		entries, err := library.GetProgressByList(context.Background(), db, owner, listName)
+		if err != nil {
+			return errors.New("cannot check list room access")
+		}
+		if len(entries) == 0 {
+			return errors.New("reading list not found")
+		}
+		if owner == viewerID {
+			return nil
+		}
+		privacy, err := social.GetPrivacy(db, owner)
+		if err != nil {
+			return errors.New("cannot check list room access")
+		}
+		if slices.Contains(privacy.PrivateLists, listName) {
+			return errors.New("reading list is private")
+		}
+		following, err := social.IsFollowing(db, viewerID, owner)
+		if err != nil {
+			return errors.New("cannot check list room access")
+		}
+		if !following {
+			return errors.New("follow the list owner to join this room")
+		}
+		return nil
+	}
+}
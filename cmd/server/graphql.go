@@ -0,0 +1,734 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+
+	"mangahub/internal/apierr"
+	"mangahub/internal/auth"
+	"mangahub/internal/feed"
+	"mangahub/internal/library"
+	"mangahub/internal/logging"
+	"mangahub/internal/manga"
+	"mangahub/internal/review"
+	"mangahub/internal/social"
+	"mangahub/internal/user"
+	"mangahub/pkg/models"
+)
+
+// gqlSchema: manga + tiến độ của viewer + review + manga liên quan lấy trong 1 round trip.
+// Query/mutation qua POST /graphql, subscription qua WebSocket (GET /graphql, graphql-transport-ws).
+const gqlSchema = `
+scalar Time
+
+schema {
+	query: Query
+	mutation: Mutation
+	subscription: Subscription
+}
+
+type Query {
+	manga(id: ID!): Manga
+	searchManga(query: String, genre: String, status: String, sortBy: String, first: Int = 20, after: String): MangaConnection!
+	# null khi chưa login
+	me: User
+	user(username: String!): UserProfile
+}
+
+type Mutation {
+	addToLibrary(input: LibraryInput!): Progress!
+	updateProgress(input: ProgressInput!): Progress!
+}
+
+type Subscription {
+	# progress của viewer và người viewer follow (chưa login: mọi progress công khai)
+	progressUpdated(mangaId: ID): ProgressEvent!
+}
+
+type Manga {
+	id: ID!
+	title: String!
+	author: String!
+	genres: [String!]!
+	status: String!
+	totalChapters: Int!
+	description: String!
+	updatedAt: Time!
+	rating: Rating!
+	# sort: helpful | recent
+	reviews(first: Int = 5, sort: String = "helpful"): [Review!]!
+	related(first: Int = 5): [Manga!]!
+	# tiến độ của viewer; null khi chưa login hoặc manga chưa có trong thư viện
+	progress: Progress
+}
+
+type MangaConnection {
+	nodes: [Manga!]!
+	# truyền vào after để lấy trang tiếp; null khi hết
+	nextCursor: String
+}
+
+type Rating {
+	average: Float!
+	count: Int!
+	histogram: [Int!]!
+}
+
+type Review {
+	id: ID!
+	username: String!
+	score: Int!
+	body: String!
+	spoiler: Boolean!
+	helpfulCount: Int!
+	createdAt: Time!
+	updatedAt: Time!
+}
+
+type Progress {
+	mangaId: ID!
+	manga: Manga
+	currentChapter: Int!
+	status: String!
+	listName: String!
+}
+
+type User {
+	id: ID!
+	username: String!
+	displayName: String!
+	avatarUrl: String!
+	bio: String!
+	library(list: String): [Progress!]!
+}
+
+type UserProfile {
+	username: String!
+	joinedAt: Time!
+	followers: Int!
+	following: Int!
+	isFollowing: Boolean!
+	private: Boolean!
+	lists: [ReadingList!]!
+}
+
+type ReadingList {
+	name: String!
+	entries: [Progress!]!
+}
+
+type ProgressEvent {
+	userId: ID!
+	mangaId: ID!
+	manga: Manga
+	chapter: Int!
+	timestamp: Time!
+}
+
+input LibraryInput {
+	mangaId: ID!
+	status: String!
+	currentChapter: Int = 0
+	listName: String
+}
+
+input ProgressInput {
+	mangaId: ID!
+	currentChapter: Int!
+	status: String
+	listName: String
+}
+`
+
+// giới hạn để 1 request không quét cả DB
+const (
+	gqlMaxDepth       = 8
+	gqlMaxParallelism = 64
+	gqlMaxQueryLength = 8 << 10
+	gqlMaxBatch       = 10 // số operation tối đa trong 1 batch (POST mảng)
+	gqlMaxList        = 50 // first tối đa của reviews/related
+	gqlMaxBody        = 256 << 10
+)
+
+func newGraphQLSchema(db *sql.DB, bus *feed.Bus, progressCh chan<- models.ProgressUpdate) *gqlService {
+	return newGQLService(graphql.MustParseSchema(gqlSchema, &gqlRoot{db: db, bus: bus, progressCh: progressCh},
+		graphql.MaxDepth(gqlMaxDepth),
+		graphql.MaxParallelism(gqlMaxParallelism),
+		graphql.MaxQueryLength(gqlMaxQueryLength),
+		graphql.Logger(gqlPanicLogger{}),
+	))
+}
+
+// gqlPanicLogger ghi panic của resolver qua slog (mặc định graphql-go dùng log chuẩn)
+type gqlPanicLogger struct{}
+
+func (gqlPanicLogger) LogPanic(ctx context.Context, value any) {
+	logger.ErrorContext(ctx, "graphql resolver panic", slog.String("panic", fmt.Sprint(value)))
+}
+
+type gqlParams struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// POST /graphql: body là 1 operation {query, operationName, variables} hoặc mảng operation (batch).
+// Lỗi GraphQL vẫn trả 200 theo convention, chỉ body hỏng mới là 400 problem+json.
+// Mỗi operation được ước lượng cost trước khi chạy; cả batch dùng chung ngân sách gqlMaxCost.
+func handleGraphQL(c *gin.Context, db *sql.DB, schema *gqlService) {
+	raw, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, gqlMaxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apierr.Write(c, apierr.TooLarge(gqlMaxBody))
+			return
+		}
+		apierr.Write(c, apierr.Invalid("cannot read body"))
+		return
+	}
+	raw = bytes.TrimSpace(raw)
+	batch := len(raw) > 0 && raw[0] == '['
+
+	var ops []gqlParams
+	if batch {
+		err = json.Unmarshal(raw, &ops)
+	} else {
+		ops = make([]gqlParams, 1)
+		err = json.Unmarshal(raw, &ops[0])
+	}
+	if err != nil {
+		apierr.Write(c, apierr.Invalid("invalid json"))
+		return
+	}
+	if len(ops) == 0 || len(ops) > gqlMaxBatch {
+		apierr.Write(c, apierr.Invalid(fmt.Sprintf("batch must contain 1-%d operations", gqlMaxBatch)))
+		return
+	}
+	for _, op := range ops {
+		if strings.TrimSpace(op.Query) == "" {
+			apierr.Write(c, apierr.InvalidField("query", "required", "query required"))
+			return
+		}
+	}
+
+	// cả batch dùng chung loader: các operation chạy song song nên truy vấn được gom chung
+	ctx := withGQLRequest(c.Request.Context(), db, c.GetString(auth.CtxUserIDKey), true)
+	res := make([]*graphql.Response, len(ops))
+	run := make([]bool, len(ops))
+	var budget int64 = gqlMaxCost
+	sequential := false
+	for i, op := range ops {
+		p, err := schema.plan(op.Query, op.OperationName, op.Variables)
+		if err == nil && p.cost > budget {
+			err = tooComplex()
+		}
+		if err != nil {
+			res[i] = gqlErrorResponse(ctx, err)
+			continue
+		}
+		budget -= p.cost
+		run[i] = true
+		// mutation chạy theo đúng thứ tự trong batch (operation sau có thể đọc kết quả của operation trước)
+		sequential = sequential || p.mutation
+	}
+
+	if sequential {
+		for i, op := range ops {
+			if run[i] {
+				res[i] = schema.Exec(ctx, op.Query, op.OperationName, op.Variables)
+			}
+		}
+	} else {
+		var wg sync.WaitGroup
+		for i, op := range ops {
+			if !run[i] {
+				continue
+			}
+			wg.Go(func() { res[i] = schema.Exec(ctx, op.Query, op.OperationName, op.Variables) })
+		}
+		wg.Wait()
+	}
+
+	if batch {
+		c.JSON(http.StatusOK, res)
+		return
+	}
+	c.JSON(http.StatusOK, res[0])
+}
+
+type gqlRoot struct {
+	db         *sql.DB
+	bus        *feed.Bus
+	progressCh chan<- models.ProgressUpdate
+}
+
+func (r *gqlRoot) Manga(ctx context.Context, args struct{ ID graphql.ID }) (*mangaResolver, error) {
+	id, err := sanitizeMangaID(string(args.ID))
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, err)
+	}
+	return r.loadManga(ctx, id)
+}
+
+// loadManga qua dataloader; manga không tồn tại => null (không phải lỗi)
+func (r *gqlRoot) loadManga(ctx context.Context, id string) (*mangaResolver, error) {
+	m, err := gqlRequestFrom(ctx).loaders.manga.Load(ctx, id)()
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "manga"))
+	}
+	return &mangaResolver{root: r, m: m}, nil
+}
+
+type searchArgs struct {
+	Query  *string
+	Genre  *string
+	Status *string
+	SortBy *string
+	First  int32
+	After  *string
+}
+
+func (r *gqlRoot) SearchManga(ctx context.Context, args searchArgs) (*mangaConnResolver, error) {
+	p := manga.SearchParams{Limit: int(args.First)}
+	if args.Query != nil {
+		p.Query = sanitizeSearchQuery(*args.Query)
+	}
+	if args.Genre != nil {
+		p.Genre = sanitizeSearchQuery(*args.Genre)
+	}
+	if args.Status != nil {
+		p.Status = *args.Status
+	}
+	if args.SortBy != nil {
+		if !manga.ValidSort(*args.SortBy) {
+			return nil, apierr.GraphQL(ctx, apierr.InvalidField("sortBy", "invalid", "invalid sortBy, options: "+strings.Join(manga.SortOptions, ", ")))
+		}
+		p.SortBy = *args.SortBy
+	}
+	if args.After != nil {
+		p.Cursor = *args.After
+	}
+
+	page, err := manga.SearchPage(ctx, r.db, p)
+	if errors.Is(err, manga.ErrInvalidCursor) {
+		return nil, apierr.GraphQL(ctx, apierr.InvalidField("after", "invalid", "invalid cursor (expired or from another query)"))
+	}
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "manga"))
+	}
+	// trang kết quả đã có đủ dữ liệu manga: nạp sẵn vào loader cho các field con (related...)
+	loader := gqlRequestFrom(ctx).loaders.manga
+	for _, m := range page.Results {
+		loader.Prime(ctx, m.ID, m)
+	}
+	return &mangaConnResolver{root: r, page: page}, nil
+}
+
+func (r *gqlRoot) Me(ctx context.Context) (*userResolver, error) {
+	viewerID := gqlRequestFrom(ctx).viewerID
+	if viewerID == "" {
+		return nil, nil
+	}
+	p, err := user.GetProfile(ctx, r.db, viewerID)
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "user"))
+	}
+	return &userResolver{root: r, p: p}, nil
+}
+
+func (r *gqlRoot) User(ctx context.Context, args struct{ Username string }) (*profileResolver, error) {
+	username, err := sanitizeUsername(args.Username)
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, err)
+	}
+	u, err := user.GetByUsername(ctx, r.db, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "user"))
+	}
+	p, err := social.GetProfile(r.db, u.ID, u.Username, u.CreatedAt, gqlRequestFrom(ctx).viewerID)
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "social"))
+	}
+	return &profileResolver{root: r, p: p}, nil
+}
+
+// requireViewer: mutation cần login (token trong header Authorization)
+func requireViewer(ctx context.Context) (string, error) {
+	if id := gqlRequestFrom(ctx).viewerID; id != "" {
+		return id, nil
+	}
+	return "", apierr.GraphQL(ctx, apierr.Unauthenticated("login required").WithReason("missing_token"))
+}
+
+func (r *gqlRoot) AddToLibrary(ctx context.Context, args struct {
+	Input struct {
+		MangaID        graphql.ID
+		Status         string
+		CurrentChapter int32
+		ListName       *string
+	}
+}) (*progressResolver, error) {
+	userID, err := requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req := libraryInput{MangaID: string(args.Input.MangaID), Status: args.Input.Status, CurrentChapter: int(args.Input.CurrentChapter)}
+	if args.Input.ListName != nil {
+		req.ListName = *args.Input.ListName
+	}
+	p, err := addToLibrary(ctx, r.db, r.bus, userID, req)
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, err)
+	}
+	return r.mutated(ctx, p), nil
+}
+
+func (r *gqlRoot) UpdateProgress(ctx context.Context, args struct {
+	Input struct {
+		MangaID        graphql.ID
+		CurrentChapter int32
+		Status         *string
+		ListName       *string
+	}
+}) (*progressResolver, error) {
+	userID, err := requireViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req := progressInput{MangaID: string(args.Input.MangaID), CurrentChapter: int(args.Input.CurrentChapter)}
+	if args.Input.Status != nil {
+		req.Status = *args.Input.Status
+	}
+	if args.Input.ListName != nil {
+		req.ListName = *args.Input.ListName
+	}
+	p, err := updateProgress(ctx, r.db, r.progressCh, userID, req)
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, err)
+	}
+	return r.mutated(ctx, p), nil
+}
+
+// mutated: progress vừa ghi thay cho bản loader có thể đã đọc trước đó trong cùng request
+func (r *gqlRoot) mutated(ctx context.Context, p library.Progress) *progressResolver {
+	if l := gqlRequestFrom(ctx).loaders.progress; l != nil {
+		l.Clear(ctx, p.MangaID).Prime(ctx, p.MangaID, &p)
+	}
+	return &progressResolver{root: r, p: p}
+}
+
+// ProgressUpdated stream event progress theo feed.Audience của viewer (giống /ws/feed);
+// channel đóng khi client huỷ subscription hoặc khi subscriber bị bus đóng vì chậm
+func (r *gqlRoot) ProgressUpdated(ctx context.Context, args struct{ MangaID *graphql.ID }) (<-chan *progressEventResolver, error) {
+	aud := r.bus.Audience(gqlRequestFrom(ctx).viewerID)
+	var mangaID string
+	if args.MangaID != nil {
+		mangaID = string(*args.MangaID)
+	}
+	// lọc mangaId ngay trong match: event không khớp không chiếm buffer của subscription
+	sub, _, _ := r.bus.Subscribe(func(e feed.Event) bool {
+		if e.Type != feed.TypeProgress || !aud.Allows(e) {
+			return false
+		}
+		if mangaID == "" {
+			return true
+		}
+		u, err := progressPayload(e.Payload)
+		return err == nil && u.MangaID == mangaID
+	}, 0)
+
+	out := make(chan *progressEventResolver)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				u, err := progressPayload(e.Payload)
+				if err != nil {
+					logger.WarnContext(ctx, "bad progress event", slog.Uint64("seq", e.Seq), logging.Err(err))
+					continue
+				}
+				select {
+				case out <- &progressEventResolver{root: r, u: u}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// progressPayload: payload là models.ProgressUpdate, hoặc JSON đã decode khi đi qua broker Redis
+func progressPayload(payload any) (models.ProgressUpdate, error) {
+	if u, ok := payload.(models.ProgressUpdate); ok {
+		return u, nil
+	}
+	var u models.ProgressUpdate
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return u, err
+	}
+	err = json.Unmarshal(b, &u)
+	return u, err
+}
+
+type mangaResolver struct {
+	root *gqlRoot
+	m    manga.Manga
+}
+
+func (m *mangaResolver) ID() graphql.ID          { return graphql.ID(m.m.ID) }
+func (m *mangaResolver) Title() string           { return m.m.Title }
+func (m *mangaResolver) Author() string          { return m.m.Author }
+func (m *mangaResolver) Status() string          { return m.m.Status }
+func (m *mangaResolver) TotalChapters() int32    { return int32(m.m.TotalChapters) }
+func (m *mangaResolver) Description() string     { return m.m.Description }
+func (m *mangaResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: m.m.UpdatedAt} }
+
+// Genres: cột genres là JSON array dạng text
+func (m *mangaResolver) Genres() []string {
+	genres := []string{}
+	if err := json.Unmarshal([]byte(m.m.Genres), &genres); err != nil {
+		return []string{}
+	}
+	return genres
+}
+
+func (m *mangaResolver) Rating(ctx context.Context) (*ratingResolver, error) {
+	s, err := gqlRequestFrom(ctx).loaders.rating.Load(ctx, m.m.ID)()
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "review"))
+	}
+	return &ratingResolver{s}, nil
+}
+
+func (m *mangaResolver) Reviews(ctx context.Context, args struct {
+	First int32
+	Sort  string
+}) ([]*reviewResolver, error) {
+	if args.First < 0 || args.First > gqlMaxList {
+		return nil, apierr.GraphQL(ctx, apierr.InvalidField("first", "out_of_range", fmt.Sprintf("first must be 0-%d", gqlMaxList)))
+	}
+	if args.Sort != "helpful" && args.Sort != "recent" {
+		return nil, apierr.GraphQL(ctx, apierr.InvalidField("sort", "invalid", "sort must be helpful or recent"))
+	}
+	list, err := gqlRequestFrom(ctx).loaders.reviews.Load(ctx, reviewKey{mangaID: m.m.ID, sort: args.Sort, limit: int(args.First)})()
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "review"))
+	}
+	out := make([]*reviewResolver, len(list))
+	for i, rv := range list {
+		out[i] = &reviewResolver{rv}
+	}
+	return out, nil
+}
+
+func (m *mangaResolver) Related(ctx context.Context, args struct{ First int32 }) ([]*mangaResolver, error) {
+	if args.First < 0 || args.First > gqlMaxList {
+		return nil, apierr.GraphQL(ctx, apierr.InvalidField("first", "out_of_range", fmt.Sprintf("first must be 0-%d", gqlMaxList)))
+	}
+	loaders := gqlRequestFrom(ctx).loaders
+	ids, err := loaders.related.Load(ctx, relatedKey{mangaID: m.m.ID, limit: int(args.First)})()
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "recommendation"))
+	}
+	// gọi Load cho cả danh sách trước rồi mới chờ để các id vào chung 1 lô
+	thunks := loaders.manga.LoadMany(ctx, ids)
+	list, errs := thunks()
+	out := make([]*mangaResolver, 0, len(list))
+	for i, rm := range list {
+		if errs != nil && errs[i] != nil {
+			if errors.Is(errs[i], sql.ErrNoRows) {
+				continue // bảng similarity chưa tính lại sau khi manga bị xoá
+			}
+			return nil, apierr.GraphQL(ctx, apierr.DB(errs[i], "manga"))
+		}
+		out = append(out, &mangaResolver{root: m.root, m: rm})
+	}
+	return out, nil
+}
+
+func (m *mangaResolver) Progress(ctx context.Context) (*progressResolver, error) {
+	l := gqlRequestFrom(ctx).loaders.progress
+	if l == nil {
+		return nil, nil
+	}
+	p, err := l.Load(ctx, m.m.ID)()
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "progress"))
+	}
+	if p == nil {
+		return nil, nil
+	}
+	return &progressResolver{root: m.root, p: *p}, nil
+}
+
+type mangaConnResolver struct {
+	root *gqlRoot
+	page manga.Page
+}
+
+func (c *mangaConnResolver) Nodes() []*mangaResolver {
+	out := make([]*mangaResolver, len(c.page.Results))
+	for i, m := range c.page.Results {
+		out[i] = &mangaResolver{root: c.root, m: m}
+	}
+	return out
+}
+
+func (c *mangaConnResolver) NextCursor() *string {
+	if c.page.NextCursor == "" {
+		return nil
+	}
+	return &c.page.NextCursor
+}
+
+type ratingResolver struct{ s review.Summary }
+
+func (r *ratingResolver) Average() float64 { return r.s.Average }
+func (r *ratingResolver) Count() int32     { return int32(r.s.Count) }
+
+func (r *ratingResolver) Histogram() []int32 {
+	out := make([]int32, len(r.s.Histogram))
+	for i, n := range r.s.Histogram {
+		out[i] = int32(n)
+	}
+	return out
+}
+
+type reviewResolver struct{ r review.Review }
+
+func (r *reviewResolver) ID() graphql.ID          { return graphql.ID(fmt.Sprint(r.r.ID)) }
+func (r *reviewResolver) Username() string        { return r.r.Username }
+func (r *reviewResolver) Score() int32            { return int32(r.r.Score) }
+func (r *reviewResolver) Body() string            { return r.r.Body }
+func (r *reviewResolver) Spoiler() bool           { return r.r.Spoiler }
+func (r *reviewResolver) HelpfulCount() int32     { return int32(r.r.HelpfulCount) }
+func (r *reviewResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.r.CreatedAt} }
+func (r *reviewResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.r.UpdatedAt} }
+
+type progressResolver struct {
+	root *gqlRoot
+	p    library.Progress
+}
+
+func (p *progressResolver) MangaID() graphql.ID   { return graphql.ID(p.p.MangaID) }
+func (p *progressResolver) CurrentChapter() int32 { return int32(p.p.CurrentChapter) }
+func (p *progressResolver) Status() string        { return p.p.Status }
+func (p *progressResolver) ListName() string      { return p.p.ListName }
+
+func (p *progressResolver) Manga(ctx context.Context) (*mangaResolver, error) {
+	return p.root.loadManga(ctx, p.p.MangaID)
+}
+
+type userResolver struct {
+	root *gqlRoot
+	p    user.Profile
+}
+
+func (u *userResolver) ID() graphql.ID      { return graphql.ID(u.p.ID) }
+func (u *userResolver) Username() string    { return u.p.Username }
+func (u *userResolver) DisplayName() string { return u.p.DisplayName }
+func (u *userResolver) AvatarURL() string   { return u.p.AvatarURL }
+func (u *userResolver) Bio() string         { return u.p.Bio }
+
+func (u *userResolver) Library(ctx context.Context, args struct{ List *string }) ([]*progressResolver, error) {
+	var list []library.Progress
+	var err error
+	if args.List != nil {
+		list, err = library.GetProgressByList(ctx, u.root.db, u.p.ID, *args.List)
+	} else {
+		list, err = library.ListAll(ctx, u.root.db, u.p.ID)
+	}
+	if err != nil {
+		return nil, apierr.GraphQL(ctx, apierr.DB(err, "progress"))
+	}
+	out := make([]*progressResolver, len(list))
+	for i, p := range list {
+		out[i] = &progressResolver{root: u.root, p: p}
+	}
+	return out, nil
+}
+
+type profileResolver struct {
+	root *gqlRoot
+	p    social.Profile
+}
+
+func (p *profileResolver) Username() string       { return p.p.Username }
+func (p *profileResolver) JoinedAt() graphql.Time { return graphql.Time{Time: p.p.JoinedAt} }
+func (p *profileResolver) Followers() int32       { return int32(p.p.Followers) }
+func (p *profileResolver) Following() int32       { return int32(p.p.Following) }
+func (p *profileResolver) IsFollowing() bool      { return p.p.IsFollowing }
+func (p *profileResolver) Private() bool          { return p.p.Private }
+
+// Lists theo tên list (thứ tự ổn định); rỗng khi profile private
+func (p *profileResolver) Lists() []*readingListResolver {
+	names := make([]string, 0, len(p.p.Lists))
+	for name := range p.p.Lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]*readingListResolver, len(names))
+	for i, name := range names {
+		entries := make([]*progressResolver, len(p.p.Lists[name]))
+		for j, e := range p.p.Lists[name] {
+			entries[j] = &progressResolver{root: p.root, p: library.Progress{
+				MangaID: e.MangaID, CurrentChapter: e.CurrentChapter, Status: e.Status, ListName: name,
+			}}
+		}
+		out[i] = &readingListResolver{name: name, entries: entries}
+	}
+	return out
+}
+
+type readingListResolver struct {
+	name    string
+	entries []*progressResolver
+}
+
+func (l *readingListResolver) Name() string                 { return l.name }
+func (l *readingListResolver) Entries() []*progressResolver { return l.entries }
+
+type progressEventResolver struct {
+	root *gqlRoot
+	u    models.ProgressUpdate
+}
+
+func (e *progressEventResolver) UserID() graphql.ID  { return graphql.ID(e.u.UserID) }
+func (e *progressEventResolver) MangaID() graphql.ID { return graphql.ID(e.u.MangaID) }
+func (e *progressEventResolver) Chapter() int32      { return int32(e.u.Chapter) }
+func (e *progressEventResolver) Timestamp() graphql.Time {
+	return graphql.Time{Time: time.Unix(e.u.Timestamp, 0)}
+}
+
+func (e *progressEventResolver) Manga(ctx context.Context) (*mangaResolver, error) {
+	return e.root.loadManga(ctx, e.u.MangaID)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+
+	"mangahub/internal/apierr"
+)
+
+// ngân sách của 1 request: số object mà resolver có thể sinh ra, ước lượng trước khi chạy
+// (list nhân với first). MaxDepth không đủ: related(first: 50) lồng 6 tầng đã là 50^6 resolver.
+const (
+	gqlMaxCost      = 5000
+	gqlListEstimate = gqlMaxList // list không có first (library, lists, entries)
+)
+
+// gqlService là schema graphql-go kèm bộ ước lượng cost. graphql-go không public parser
+// nên query được parse thêm 1 lần bằng gqlparser trên cùng SDL.
+type gqlService struct {
+	*graphql.Schema
+	types *ast.Schema
+}
+
+func newGQLService(schema *graphql.Schema) *gqlService {
+	types, err := validator.LoadSchema(validator.Prelude, &ast.Source{Name: "schema.graphql", Input: gqlSchema})
+	if err != nil {
+		panic(fmt.Sprintf("graphql cost schema: %v", err))
+	}
+	return &gqlService{Schema: schema, types: types}
+}
+
+// gqlPlan: kết quả phân tích 1 operation trước khi chạy
+type gqlPlan struct {
+	mutation bool
+	cost     int64
+}
+
+// plan ước lượng cost của operation sẽ chạy. Query không parse được bị từ chối luôn (không để
+// graphql-go chạy thứ mình không đo được); operationName sai/thiếu thì cost 0 vì graphql-go
+// sẽ trả lỗi mà không chạy resolver nào.
+func (s *gqlService) plan(query, operationName string, vars map[string]any) (gqlPlan, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return gqlPlan{}, apierr.Invalid(err.Error()).WithReason("invalid_query")
+	}
+	var op *ast.OperationDefinition
+	switch {
+	case operationName != "":
+		op = doc.Operations.ForName(operationName)
+	case len(doc.Operations) == 1:
+		op = doc.Operations[0]
+	}
+	if op == nil {
+		return gqlPlan{}, nil
+	}
+
+	var root *ast.Definition
+	switch op.Operation {
+	case ast.Mutation:
+		root = s.types.Mutation
+	case ast.Subscription:
+		root = s.types.Subscription
+	default:
+		root = s.types.Query
+	}
+	e := &costEstimator{types: s.types, doc: doc, op: op, vars: vars, active: map[string]bool{}}
+	cost := e.selections(op.SelectionSet, root, 1, 0)
+	if cost > gqlMaxCost {
+		return gqlPlan{}, tooComplex()
+	}
+	return gqlPlan{mutation: op.Operation == ast.Mutation, cost: cost}, nil
+}
+
+func tooComplex() *apierr.Error {
+	return apierr.Invalid(fmt.Sprintf("query too complex (max cost %d)", gqlMaxCost)).
+		WithReason("query_too_complex").
+		WithMeta("max_cost", gqlMaxCost)
+}
+
+// Subscribe (WebSocket) cũng qua bước ước lượng: subscription chạy lại selection cho mỗi event
+func (s *gqlService) Subscribe(ctx context.Context, query, operationName string, variables map[string]any) (<-chan any, error) {
+	if _, err := s.plan(query, operationName, variables); err != nil {
+		out := make(chan any, 1)
+		out <- gqlErrorResponse(ctx, err)
+		close(out)
+		return out, nil
+	}
+	return s.Schema.Subscribe(ctx, query, operationName, variables)
+}
+
+// gqlErrorResponse: lỗi trước khi chạy, cùng dạng errors[].extensions với lỗi của resolver
+func gqlErrorResponse(ctx context.Context, err error) *graphql.Response {
+	qe := &gqlerrors.QueryError{Message: err.Error()}
+	if ext, ok := apierr.GraphQL(ctx, err).(interface{ Extensions() map[string]any }); ok {
+		qe.Extensions = ext.Extensions()
+	}
+	return &graphql.Response{Errors: []*gqlerrors.QueryError{qe}}
+}
+
+type costEstimator struct {
+	types  *ast.Schema
+	doc    *ast.QueryDocument
+	op     *ast.OperationDefinition
+	vars   map[string]any
+	active map[string]bool // fragment đang mở rộng (chặn fragment vòng)
+}
+
+// selections cộng số object sinh ra trong selection set; mult là số lần parent được resolve,
+// size là first của field connection phía trên (searchManga(first) -> nodes).
+// Dừng sớm khi đã vượt ngân sách để phép nhân không tràn.
+func (e *costEstimator) selections(set ast.SelectionSet, parent *ast.Definition, mult, size int64) int64 {
+	var cost int64
+	for _, sel := range set {
+		if cost > gqlMaxCost {
+			break
+		}
+		switch sel := sel.(type) {
+		case *ast.Field:
+			cost += e.field(sel, parent, mult, size)
+		case *ast.InlineFragment:
+			cost += e.selections(sel.SelectionSet, e.typeOr(sel.TypeCondition, parent), mult, size)
+		case *ast.FragmentSpread:
+			frag := e.doc.Fragments.ForName(sel.Name)
+			if frag == nil || e.active[sel.Name] {
+				continue
+			}
+			e.active[sel.Name] = true
+			cost += e.selections(frag.SelectionSet, e.typeOr(frag.TypeCondition, parent), mult, size)
+			delete(e.active, sel.Name)
+		}
+	}
+	return cost
+}
+
+func (e *costEstimator) field(f *ast.Field, parent *ast.Definition, mult, size int64) int64 {
+	// introspection bị chặn bởi kích thước schema; field lạ để graphql-go báo lỗi
+	if parent == nil || strings.HasPrefix(f.Name, "__") || len(f.SelectionSet) == 0 {
+		return 0
+	}
+	def := parent.Fields.ForName(f.Name)
+	if def == nil {
+		return 0
+	}
+	first, hasFirst := e.first(f, def)
+
+	n, childSize := mult, int64(0)
+	switch {
+	case def.Type.Elem != nil && hasFirst:
+		n = mult * first
+	case def.Type.Elem != nil && size > 0:
+		n = mult * size
+	case def.Type.Elem != nil:
+		n = mult * gqlListEstimate
+	case hasFirst:
+		childSize = first
+	}
+	if n > gqlMaxCost {
+		return n
+	}
+	return n + e.selections(f.SelectionSet, e.types.Types[def.Type.Name()], n, childSize)
+}
+
+// first lấy từ query (literal/biến), không có thì default trong schema; giá trị lớn bị kẹp
+// ở gqlMaxCost+1 (đủ để vượt ngân sách, resolver sẽ báo out_of_range)
+func (e *costEstimator) first(f *ast.Field, def *ast.FieldDefinition) (int64, bool) {
+	argDef := def.Arguments.ForName("first")
+	if argDef == nil {
+		return 0, false
+	}
+	var v any
+	if arg := f.Arguments.ForName("first"); arg != nil {
+		v = e.value(arg.Value)
+	}
+	if v == nil && argDef.DefaultValue != nil {
+		v, _ = argDef.DefaultValue.Value(nil)
+	}
+	var n int64
+	switch v := v.(type) {
+	case int64:
+		n = v
+	case float64:
+		n = int64(min(v, gqlMaxCost+1))
+	case json.Number:
+		n, _ = v.Int64()
+	}
+	return max(0, min(n, gqlMaxCost+1)), true
+}
+
+// value: biến lấy từ variables, thiếu thì default khai báo trong operation
+func (e *costEstimator) value(v *ast.Value) any {
+	if v.Kind == ast.Variable {
+		if val, ok := e.vars[v.Raw]; ok {
+			return val
+		}
+		if def := e.op.VariableDefinitions.ForName(v.Raw); def != nil && def.DefaultValue != nil {
+			val, _ := def.DefaultValue.Value(nil)
+			return val
+		}
+		return nil
+	}
+	val, _ := v.Value(e.vars)
+	return val
+}
+
+func (e *costEstimator) typeOr(name string, fallback *ast.Definition) *ast.Definition {
+	if t := e.types.Types[name]; t != nil {
+		return t
+	}
+	return fallback
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"mangahub/internal/apierr"
+)
+
+func nested(field string, levels int) string {
+	q := "title"
+	for range levels {
+		q = field + " { id " + q + " }"
+	}
+	return `{ manga(id: "x") { ` + q + ` } }`
+}
+
+func TestGQLPlan(t *testing.T) {
+	s := newGQLService(nil)
+	tests := []struct {
+		name     string
+		query    string
+		opName   string
+		vars     map[string]any
+		cost     int64
+		mutation bool
+		tooBig   bool
+	}{
+		{name: "scalar only", query: `{ manga(id: "x") { id title } }`, cost: 1},
+		{name: "default first", query: `{ manga(id: "x") { related { id } reviews { id } } }`, cost: 1 + 5 + 5},
+		{name: "literal first", query: `{ manga(id: "x") { related(first: 10) { related(first: 10) { id } } } }`, cost: 1 + 10 + 100},
+		{name: "connection passes first to nodes", query: `{ searchManga(first: 30) { nodes { id } } }`, cost: 1 + 30},
+		{name: "variable first", query: `query Q($n: Int) { manga(id: "x") { related(first: $n) { id } } }`, vars: map[string]any{"n": float64(40)}, cost: 1 + 40},
+		{name: "variable default", query: `query Q($n: Int = 7) { manga(id: "x") { related(first: $n) { id } } }`, cost: 1 + 7},
+		{name: "unbounded list estimate", query: `{ me { library { manga { id } } } }`, cost: 1 + gqlListEstimate + gqlListEstimate},
+		{name: "fragments counted", query: `{ manga(id: "x") { ...R ... on Manga { reviews(first: 3) { id } } } } fragment R on Manga { related(first: 4) { id } }`, cost: 1 + 4 + 3},
+		{name: "fragment cycle terminates", query: `{ manga(id: "x") { ...A } } fragment A on Manga { related { ...A } }`, cost: 1 + 5},
+		{name: "introspection free", query: `{ __schema { types { name fields { name args { name } } } } }`, cost: 0},
+		{name: "selected operation", query: `query A { manga(id: "x") { id } } query B { manga(id: "x") { related { id } } }`, opName: "B", cost: 1 + 5},
+		{name: "mutation", query: `mutation { updateProgress(input: {mangaId: "x", currentChapter: 1}) { currentChapter } }`, cost: 1, mutation: true},
+		{name: "nested related", query: nested("related(first: 50)", 6), tooBig: true},
+		{name: "nested related default first", query: nested("related", 6), tooBig: true},
+		{name: "huge variable", query: `query Q($n: Int) { searchManga(first: $n) { nodes { id } } }`, vars: map[string]any{"n": float64(1 << 40)}, tooBig: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := s.plan(tt.query, tt.opName, tt.vars)
+			if tt.tooBig {
+				var e *apierr.Error
+				if !errors.As(err, &e) || e.Reason != "query_too_complex" {
+					t.Fatalf("err = %v, want query_too_complex", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("plan: %v", err)
+			}
+			if p.cost != tt.cost || p.mutation != tt.mutation {
+				t.Errorf("plan = {cost %d, mutation %v}, want {cost %d, mutation %v}", p.cost, p.mutation, tt.cost, tt.mutation)
+			}
+		})
+	}
+}
+
+func TestGQLPlanSyntaxError(t *testing.T) {
+	_, err := newGQLService(nil).plan(`{ manga(id: "x") { id `, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "Expected") {
+		t.Fatalf("err = %v, want syntax error", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+
+	"mangahub/internal/library"
+	"mangahub/internal/manga"
+	"mangahub/internal/recommend"
+	"mangahub/internal/review"
+)
+
+// loaderWait: resolver của các field anh em chạy song song, chờ ngắn là đủ gom thành 1 truy vấn
+const (
+	loaderWait     = 5 * time.Millisecond
+	loaderCapacity = 200 // số key tối đa trong 1 câu IN (...)
+)
+
+type reviewKey struct {
+	mangaID string
+	sort    string
+	limit   int
+}
+
+type relatedKey struct {
+	mangaID string
+	limit   int
+}
+
+// gqlLoaders gom các lần đọc của 1 request GraphQL thành truy vấn theo lô (tránh N+1 với SQLite).
+// Mỗi request HTTP có bộ loader riêng; subscription dùng bản không cache để event sau không
+// nhận dữ liệu cũ.
+type gqlLoaders struct {
+	manga    *dataloader.Loader[string, manga.Manga]
+	rating   *dataloader.Loader[string, review.Summary]
+	reviews  *dataloader.Loader[reviewKey, []review.Review]
+	related  *dataloader.Loader[relatedKey, []string]
+	progress *dataloader.Loader[string, *library.Progress] // của viewer; nil nếu chưa login
+}
+
+func newLoaders(db *sql.DB, viewerID string, cache bool) *gqlLoaders {
+	l := &gqlLoaders{
+		manga: dataloader.NewBatchedLoader(func(ctx context.Context, ids []string) []*dataloader.Result[manga.Manga] {
+			found, err := manga.GetByIDs(ctx, db, ids)
+			return results(ids, err, func(id string) (manga.Manga, error) {
+				m, ok := found[id]
+				if !ok {
+					return m, sql.ErrNoRows
+				}
+				return m, nil
+			})
+		}, loaderOpts[string, manga.Manga](cache)...),
+
+		rating: dataloader.NewBatchedLoader(func(ctx context.Context, ids []string) []*dataloader.Result[review.Summary] {
+			sums, err := review.Summaries(db, ids)
+			return results(ids, err, func(id string) (review.Summary, error) { return sums[id], nil })
+		}, loaderOpts[string, review.Summary](cache)...),
+
+		reviews: dataloader.NewBatchedLoader(func(ctx context.Context, keys []reviewKey) []*dataloader.Result[[]review.Review] {
+			// 1 truy vấn cho mỗi cặp (sort, limit) khác nhau trong lô
+			type group struct {
+				sort  string
+				limit int
+			}
+			ids := map[group][]string{}
+			for _, k := range keys {
+				g := group{k.sort, k.limit}
+				ids[g] = append(ids[g], k.mangaID)
+			}
+			byGroup := map[group]map[string][]review.Review{}
+			var err error
+			for g, list := range ids {
+				if byGroup[g], err = review.ListTop(db, list, g.sort, g.limit); err != nil {
+					break
+				}
+			}
+			return results(keys, err, func(k reviewKey) ([]review.Review, error) {
+				return byGroup[group{k.sort, k.limit}][k.mangaID], nil
+			})
+		}, loaderOpts[reviewKey, []review.Review](cache)...),
+
+		related: dataloader.NewBatchedLoader(func(ctx context.Context, keys []relatedKey) []*dataloader.Result[[]string] {
+			ids := map[int][]string{}
+			for _, k := range keys {
+				ids[k.limit] = append(ids[k.limit], k.mangaID)
+			}
+			byLimit := map[int]map[string][]string{}
+			var err error
+			for limit, list := range ids {
+				if byLimit[limit], err = recommend.Similar(db, list, limit); err != nil {
+					break
+				}
+			}
+			return results(keys, err, func(k relatedKey) ([]string, error) { return byLimit[k.limit][k.mangaID], nil })
+		}, loaderOpts[relatedKey, []string](cache)...),
+	}
+
+	if viewerID != "" {
+		l.progress = dataloader.NewBatchedLoader(func(ctx context.Context, ids []string) []*dataloader.Result[*library.Progress] {
+			found, err := library.GetProgressMany(ctx, db, viewerID, ids)
+			return results(ids, err, func(id string) (*library.Progress, error) {
+				if p, ok := found[id]; ok {
+					return &p, nil
+				}
+				return nil, nil
+			})
+		}, loaderOpts[string, *library.Progress](cache)...)
+	}
+	return l
+}
+
+func loaderOpts[K comparable, V any](cache bool) []dataloader.Option[K, V] {
+	opts := []dataloader.Option[K, V]{
+		dataloader.WithWait[K, V](loaderWait),
+		dataloader.WithBatchCapacity[K, V](loaderCapacity),
+	}
+	if !cache {
+		opts = append(opts, dataloader.WithCache[K, V](&dataloader.NoCache[K, V]{}))
+	}
+	return opts
+}
+
+// results dựng kết quả theo đúng thứ tự keys (yêu cầu của dataloader); err != nil => lỗi cho mọi key
+func results[K comparable, V any](keys []K, err error, get func(K) (V, error)) []*dataloader.Result[V] {
+	out := make([]*dataloader.Result[V], len(keys))
+	for i, k := range keys {
+		if err != nil {
+			out[i] = &dataloader.Result[V]{Error: err}
+			continue
+		}
+		v, e := get(k)
+		out[i] = &dataloader.Result[V]{Data: v, Error: e}
+	}
+	return out
+}
+
+type gqlCtxKey struct{}
+
+// gqlRequest là phần context riêng của 1 request GraphQL (HTTP) hoặc 1 subscription (WebSocket)
+type gqlRequest struct {
+	viewerID string
+	loaders  *gqlLoaders
+}
+
+func withGQLRequest(ctx context.Context, db *sql.DB, viewerID string, cache bool) context.Context {
+	return context.WithValue(ctx, gqlCtxKey{}, &gqlRequest{viewerID: viewerID, loaders: newLoaders(db, viewerID, cache)})
+}
+
+func gqlRequestFrom(ctx context.Context) *gqlRequest {
+	r, _ := ctx.Value(gqlCtxKey{}).(*gqlRequest)
+	return r
+}
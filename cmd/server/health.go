@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"mangahub/internal/health"
+)
+
+// tên service trong grpc.health.v1 (package.Service trong proto/manga.proto)
+const grpcServiceName = "mangahub.MangaService"
+
+// GET /livez, /readyz: 200 khi mọi thành phần up, ngược lại 503
+func handleHealth(c *gin.Context, check func(context.Context) health.Result) {
+	res := check(c.Request.Context())
+	code := http.StatusOK
+	if res.Status != health.StatusUp {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, res)
+}
+
+func checkDatabase(ctx context.Context, db *sql.DB) health.Report {
+	start := time.Now()
+	var one int
+	if err := db.QueryRowContext(ctx, `SELECT 1`).Scan(&one); err != nil {
+		return health.Down(err.Error(), nil)
+	}
+	stats := db.Stats()
+	return health.Up(map[string]any{
+		"latency_ms":       time.Since(start).Milliseconds(),
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+	})
+}
+
+// syncGRPCHealth cập nhật grpc.health.v1 theo readiness để client/LB gRPC dùng chung 1 nguồn
+func syncGRPCHealth(checks *health.Registry, hs *grpchealth.Server, every time.Duration) {
+	for {
+		st := healthpb.HealthCheckResponse_SERVING
+		if checks.Ready(context.Background()).Status != health.StatusUp {
+			st = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		hs.SetServingStatus("", st)
+		hs.SetServingStatus(grpcServiceName, st)
+		time.Sleep(every)
+	}
+}
@@ -1,25 +1,52 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip" // client gửi request nén gzip thì response cũng được nén gzip
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
+	"mangahub/internal/apierr"
 	"mangahub/internal/auth"
+	"mangahub/internal/broker"
+	"mangahub/internal/chat"
+	"mangahub/internal/compress"
+	"mangahub/internal/feed"
 	grpcserver "mangahub/internal/grpc"
+	"mangahub/internal/health"
+	"mangahub/internal/httpcache"
 	"mangahub/internal/library"
+	"mangahub/internal/logging"
+	"mangahub/internal/mail"
 	"mangahub/internal/manga"
+	"mangahub/internal/metrics"
+	"mangahub/internal/notify"
+	"mangahub/internal/popularity"
+	"mangahub/internal/ratelimit"
+	"mangahub/internal/recommend"
+	"mangahub/internal/review"
+	"mangahub/internal/social"
+	"mangahub/internal/stats"
 	"mangahub/internal/tcpsync"
+	"mangahub/internal/tracing"
 	"mangahub/internal/udpnotify"
 	"mangahub/internal/user"
 	"mangahub/internal/websocket"
@@ -28,232 +55,652 @@ import (
 	"mangahub/proto"
 )
 
+var logger = logging.For("server")
+
 var jwtSecret = []byte("dev-secret-change-me")
 
+// địa chỉ listen, override bằng env để chạy nhiều instance trên cùng 1 máy
+var (
+	httpAddr = envOr("HTTP_ADDR", ":8080")
+	tcpAddr  = envOr("TCP_ADDR", ":9090")
+	udpAddr  = envOr("UDP_ADDR", ":7070")
+	grpcAddr = envOr("GRPC_ADDR", ":50051")
+)
+
+// URL public của server, dùng cho link trong email
+var appBaseURL = strings.TrimSuffix(envOr("APP_BASE_URL", "http://localhost:8080"), "/")
+
 func main() {
+	// LOG_FORMAT=json|text, LOG_LEVEL, LOG_LEVELS=tcpsync=debug,... (xem internal/logging)
+	logging.Setup(os.Stderr, logging.ConfigFromEnv())
+
+	// OTEL_TRACES_EXPORTER=none|otlp|stdout (xem internal/tracing)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.ConfigFromEnv(), os.Stdout)
+	if err != nil {
+		fatal("tracing", err)
+	}
+	// server chỉ dừng bằng signal: flush span còn trong batch trước khi thoát
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = shutdownTracing(ctx)
+		os.Exit(0)
+	}()
+
 	// Dùng 1 DB cố định trong /data để tránh lệch working directory
-	dbPath := "./data/mangahub.db"
+	// (DB_PATH cho phép nhiều instance dùng chung 1 file)
+	dbPath := envOr("DB_PATH", "./data/mangahub.db")
 
 	// Ensure data folder exists
-	if err := os.MkdirAll("./data", 0755); err != nil {
-		log.Fatal(err)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		fatal("create data dir", err)
 	}
 
+	// đo thời gian mọi câu lệnh SQL cho /metrics, và span con cho câu lệnh chạy trong 1 trace
+	database.SetQueryHook(metrics.ObserveDB)
+	database.SetQueryTracer(tracing.DBQuery)
+
 	db, err := database.Open(dbPath)
 	if err != nil {
-		log.Fatal(err)
+		fatal("open database", err)
 	}
 	defer db.Close()
 
 	// ✅ FIX: tạo schema trước khi chạy API
 	if err := database.Migrate(db); err != nil {
-		log.Fatal(err)
+		fatal("migrate", err)
 	}
 
 	// ✅ Seed manga nếu có file JSON (Day 1)
 	if _, err := os.Stat("./data/manga.json"); err == nil {
 		mangaList, err := database.LoadMangaFromJSON("./data/manga.json")
 		if err != nil {
-			log.Fatal(err)
+			fatal("load manga.json", err)
 		}
 		n, err := database.SeedManga(db, mangaList)
 		if err != nil {
-			log.Fatal(err)
+			fatal("seed manga", err)
 		}
-		log.Printf("Seeded %d manga into %s", n, dbPath)
+		logger.Info("seeded manga", slog.Int("count", n), slog.String("db", dbPath))
 	} else {
-		log.Printf("warn: data/manga.json not found; skip seeding (%v)", err)
+		logger.Warn("data/manga.json not found; skip seeding", logging.Err(err))
 	}
 
-	r := gin.Default()
-	//web
+	// DB cũ chưa có bộ đếm popularity thì dựng lại 1 lần từ user_progress
+	if n, err := popularity.Backfill(db); err != nil {
+		fatal("backfill popularity", err)
+	} else if n > 0 {
+		logger.Info("backfilled popularity counters", slog.Int("manga", n))
+	}
 
-	// Serve UI entry
-	r.GET("/ui", func(c *gin.Context) {
-		c.File("./web/index.html")
-	})
+	// JWT của user đã xoá / trước lần đổi password bị từ chối ở mọi transport
+	auth.UseSessionCheck(func(claims *auth.Claims) error { return checkSession(db, claims) })
 
-	// Serve static files
-	r.Static("/ui", "./web")
+	// Mailer cho email xác thực / reset password: MAILER=stdout|file|smtp
+	mailer, err := newMailer(os.Getenv("MAILER"))
+	if err != nil {
+		fatal("mailer", err)
+	}
+
+	// Tính lại bảng similarity cho recommendation mỗi giờ
+	go recommend.RunPeriodic(db, time.Hour)
 
 	progressCh := make(chan models.ProgressUpdate, 100)
 
+	// nguồn event chung cho TCP sync và /ws/feed (giữ 1024 event gần nhất để resume).
+	// feed.Audience gồm chính user + người user follow đang để activity public.
+	feedBus := feed.NewBus(1024)
+	feedBus.SetFollowing(func(userID string) ([]string, error) { return social.VisibleFollowing(db, userID) })
+	feedBus.SetPrivacy(func(userID, listName string) (bool, error) { return social.ActivityHidden(db, userID, listName) })
+	go feedBus.PumpProgress(progressCh)
+
+	// Broker nối các instance: BROKER=redis (REDIS_URL) hoặc memory (mặc định, 1 instance)
+	msgBroker, err := newBroker(os.Getenv("BROKER"), envOr("REDIS_URL", "redis://localhost:6379/0"))
+	if err != nil {
+		fatal("broker", err)
+	}
+	defer msgBroker.Close()
+	if err := manga.UseBroker(msgBroker); err != nil {
+		fatal("broker", err)
+	}
+	if err := feedBus.UseBroker(msgBroker); err != nil {
+		fatal("broker", err)
+	}
+
 	// TCP server
-	tcpServer := tcpsync.New(":9090", progressCh)
+	tcpServer := tcpsync.New(tcpAddr, feedBus)
+	tcpServer.SetAuth(func(token string) (string, error) {
+		claims, err := auth.ParseJWT(jwtSecret, token)
+		if err != nil {
+			return "", err
+		}
+		return claims.UserID, nil
+	})
+	if err := tcpServer.UseBroker(msgBroker); err != nil {
+		fatal("broker", err)
+	}
 	go func() {
 		if err := tcpServer.Start(); err != nil {
-			log.Fatal(err)
+			fatal("tcp sync", err)
 		}
 	}()
 
 	// UDP server
-	udpServer := udpnotify.New(":7070")
+	udpServer := udpnotify.New(udpAddr)
+	if err := udpServer.UseBroker(msgBroker); err != nil {
+		fatal("broker", err)
+	}
 	go func() {
 		if err := udpServer.Start(); err != nil {
-			log.Fatal(err)
+			fatal("udp notify", err)
 		}
 	}()
-	log.Println("UDP server listening on :7070")
 
 	// gRPC server
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(tracing.GRPCServerHandler()),
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(logging.For("grpc")), metrics.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor(logging.For("grpc")), metrics.StreamServerInterceptor()),
+	)
 	grpcService := grpcserver.NewServer(db)
+	grpcService.SetAuth(func(token string) (string, error) {
+		claims, err := auth.ParseJWT(jwtSecret, token)
+		if err != nil {
+			return "", err
+		}
+		return claims.UserID, nil
+	})
 	proto.RegisterMangaServiceServer(grpcServer, grpcService)
+	// grpc.health.v1.Health: NOT_SERVING cho tới khi /readyz ok (đồng bộ bởi syncGRPCHealth)
+	grpcHealth := grpchealth.NewServer()
+	grpcHealth.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	grpcHealth.SetServingStatus(grpcServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, grpcHealth)
 	reflection.Register(grpcServer)
 	go func() {
-		lis, err := net.Listen("tcp", ":50051")
+		lis, err := net.Listen("tcp", grpcAddr)
 		if err != nil {
-			log.Fatal("Failed to listen for gRPC:", err)
+			grpcService.SetServing(grpcAddr, false, err)
+			fatal("grpc listen", err)
 		}
-		log.Println("gRPC server listening on :50051")
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatal("Failed to serve gRPC:", err)
+		logger.Info("gRPC server listening", slog.String("addr", grpcAddr))
+		grpcService.SetServing(lis.Addr().String(), true, nil)
+		err = grpcServer.Serve(lis)
+		grpcService.SetServing(lis.Addr().String(), false, err)
+		if err != nil {
+			fatal("grpc serve", err)
 		}
 	}()
 
 	// Chat hub
+	chatStore := chat.NewStore(db)
 	chatHub := websocket.NewHub()
+	chatHub.UseStore(chatStore, 50)
+	chatHub.UseListAccess(listRoomAccess(db))
+	if err := chatHub.UseSanctions(chatStore); err != nil {
+		fatal("chat sanctions", err)
+	}
+	if err := chatHub.UseBroker(msgBroker); err != nil {
+		fatal("broker", err)
+	}
 	go chatHub.Run()
-	log.Println("Chat hub started")
+	go chatStore.RunPruner(time.Hour)
 
-	//ROUTES
-	r.GET("/health", func(c *gin.Context) { handleHealthCheck(c, db, tcpServer, udpServer, grpcServer, chatHub) })
-
-	// AUTH
-	r.POST("/auth/register", func(c *gin.Context) { handleRegister(c, db) })
-	r.POST("/auth/login", func(c *gin.Context) { handleLogin(c, db) })
-
-	// PUBLIC MANGA
-	r.GET("/manga", func(c *gin.Context) { handleSearchManga(c, db) })
-	r.GET("/manga/:id", func(c *gin.Context) { handleMangaDetail(c, db) })
-
-	// WEBSOCKET CHAT
-	r.GET("/ws", websocket.HandleWebSocket(chatHub))
-
-	// PROTECTED
-	authed := r.Group("/")
-	authed.Use(auth.RequireJWT(jwtSecret))
-	authed.POST("/library", func(c *gin.Context) { handleAddLibrary(c, db) })
-	authed.PATCH("/progress", func(c *gin.Context) { handleUpdateProgress(c, db, progressCh) })
-	authed.POST("/admin/notify", func(c *gin.Context) {
-		var req struct {
-			Message string `json:"message"`
-		}
-		if err := c.ShouldBindJSON(&req); err != nil || req.Message == "" {
-			c.JSON(400, gin.H{"error": "message required"})
-			return
-		}
-		udpServer.Broadcast(req.Message)
-		c.JSON(200, gin.H{"ok": true})
+	// Fan-out notification chapter mới qua mọi transport realtime
+	dispatcher := notify.NewDispatcher(db)
+	dispatcher.Register(notify.ChannelWebSocket, notify.Senders{chatHub, feedBus})
+	dispatcher.Register(notify.ChannelTCP, tcpServer)
+	dispatcher.Register(notify.ChannelGRPC, grpcService)
+	dispatcher.RegisterUDP(udpServer)
+
+	// Rate limit + chống brute-force login: RATE_LIMIT_STORE=memory (mặc định) | redis (REDIS_URL, chia sẻ giữa instance)
+	rlStore, err := newRateLimitStore(os.Getenv("RATE_LIMIT_STORE"), envOr("REDIS_URL", "redis://localhost:6379/0"))
+	if err != nil {
+		fatal("rate limit store", err)
+	}
+	loginGuard := ratelimit.DefaultLoginGuard(rlStore)
+
+	// Health: mỗi server tự báo trạng thái; chat hub + feed bus là event loop nội bộ nên tính cả cho /livez
+	checks := health.NewRegistry(2 * time.Second)
+	checks.Add("database", health.CheckerFunc(func(ctx context.Context) health.Report { return checkDatabase(ctx, db) }))
+	checks.Add("tcpsync", tcpServer)
+	checks.Add("udpnotify", udpServer)
+	checks.Add("grpc", grpcService)
+	checks.AddLiveness("chat", chatHub)
+	checks.AddLiveness("feed", feedBus)
+	if hc, ok := msgBroker.(health.HealthChecker); ok {
+		checks.Add("broker", hc)
+	}
+	if hc, ok := rlStore.(health.HealthChecker); ok {
+		checks.Add("ratelimit", hc)
+	}
+	go syncGRPCHealth(checks, grpcHealth, 5*time.Second)
+
+	// số client đang kết nối, đọc lúc scrape
+	metrics.ClientGauge("tcpsync", tcpServer.ClientCount)
+	metrics.ClientGauge("udpnotify", udpServer.ClientCount)
+	metrics.ClientGauge("chat", chatHub.ClientCount)
+	metrics.ClientGauge("feed", feedBus.SubscriberCount)
+
+	r := newRouter(httpDeps{
+		db: db, mailer: mailer, rlStore: rlStore, loginGuard: loginGuard, checks: checks,
+		feedBus: feedBus, progressCh: progressCh, chatHub: chatHub, chatStore: chatStore,
+		udpServer: udpServer, dispatcher: dispatcher,
+	})
+	// TRUSTED_PROXIES=10.0.0.0/8,... : chỉ tin X-Forwarded-For từ các proxy này (ClientIP dùng cho
+	// rate limit và khoá login). Mặc định không tin proxy nào => ClientIP là địa chỉ kết nối.
+	if err := r.SetTrustedProxies(splitList(os.Getenv("TRUSTED_PROXIES"))); err != nil {
+		fatal("trusted proxies", err)
+	}
+
+	logger.Info("HTTP API listening", slog.String("addr", httpAddr))
+	fatal("http serve", r.Run(httpAddr))
+}
+
+// httpDeps là các thành phần route HTTP dùng tới
+type httpDeps struct {
+	db         *sql.DB
+	mailer     mail.Mailer
+	rlStore    ratelimit.Store
+	loginGuard *ratelimit.LoginGuard
+	checks     *health.Registry
+	feedBus    *feed.Bus
+	progressCh chan<- models.ProgressUpdate
+	chatHub    *websocket.ChatHub
+	chatStore  *chat.Store
+	udpServer  *udpnotify.Server
+	dispatcher *notify.Dispatcher
+}
+
+// newRouter đăng ký middleware và mọi route HTTP; route mới phải có trong apiSpec
+// (openapi_test.go kiểm tra bằng spec.Missing)
+func newRouter(d httpDeps) *gin.Engine {
+	db, mailer, rlStore, loginGuard, checks := d.db, d.mailer, d.rlStore, d.loginGuard, d.checks
+	feedBus, progressCh, chatHub, chatStore := d.feedBus, d.progressCh, d.chatHub, d.chatStore
+	udpServer, dispatcher := d.udpServer, d.dispatcher
+
+	// request ID trước để access log, metrics và handler đều thấy
+	r := gin.New()
+	r.Use(gin.Recovery(), logging.RequestIDMiddleware(), tracing.Gin("mangahub-http"), logging.AccessLog(logging.For("http"), auth.CtxUserIDKey))
+	r.Use(metrics.GinMiddleware())
+	// nén br/gzip theo Accept-Encoding (response >= 1KB, content type dạng text/JSON)
+	r.Use(compress.Middleware())
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	//web
+
+	// Serve UI entry
+	r.GET("/ui", func(c *gin.Context) {
+		c.File("./web/index.html")
 	})
 
-	log.Println("HTTP API listening on :8080")
-	log.Fatal(r.Run(":8080"))
+	// Serve static files
+	r.Static("/ui", "./web")
+
+	//ROUTES
+	r.GET("/livez", func(c *gin.Context) { handleHealth(c, checks.Live) })
+	r.GET("/readyz", func(c *gin.Context) { handleHealth(c, checks.Ready) })
+	r.GET("/health", func(c *gin.Context) { handleHealth(c, checks.Ready) }) // giữ cho client cũ, giống /readyz
+
+	graphqlSchema := newGraphQLSchema(db, feedBus, progressCh)
+
+	// OPENAPI: spec phục vụ tại /openapi.json, validate request sau rate limit / xác thực
+	spec := apiSpec()
+	validate := spec.Validate()
+	r.GET("/openapi.json", spec.Handler())
+
+	// API: mount dưới /api/v1; đường dẫn cũ (không prefix) vẫn chạy nhưng trả header Deprecation
+	routes := func(g *gin.RouterGroup) {
+		// AUTH
+		authGroup := g.Group("/auth", ratelimit.Middleware(rlStore, "auth", authLimit, auth.CtxUserIDKey), validate)
+		authGroup.POST("/register", func(c *gin.Context) { handleRegister(c, db, mailer) })
+		authGroup.POST("/login", func(c *gin.Context) { handleLogin(c, db, loginGuard) })
+		authGroup.GET("/verify-email", func(c *gin.Context) { handleVerifyEmail(c, db) })
+		authGroup.POST("/verify-email", func(c *gin.Context) { handleVerifyEmail(c, db) })
+		authGroup.POST("/password/forgot", func(c *gin.Context) { handleForgotPassword(c, db, mailer) })
+		authGroup.POST("/password/reset", func(c *gin.Context) { handleResetPassword(c, db) })
+
+		// PUBLIC MANGA
+		searchRL := ratelimit.Middleware(rlStore, "search", searchLimit, auth.CtxUserIDKey)
+		g.GET("/manga", searchRL, validate, func(c *gin.Context) { handleSearchManga(c, db) })
+		g.GET("/manga/trending", searchRL, validate, func(c *gin.Context) { handleTrendingManga(c, db) })
+		g.GET("/manga/:id", auth.OptionalJWT(jwtSecret), validate, func(c *gin.Context) { handleMangaDetail(c, db) })
+		g.GET("/manga/:id/reviews", validate, func(c *gin.Context) { handleListReviews(c, db) })
+		g.GET("/users/:username", auth.OptionalJWT(jwtSecret), validate, func(c *gin.Context) { handleUserProfile(c, db) })
+
+		// WEBSOCKET CHAT
+		// Cần JWT (query ?token=, subprotocol "bearer" hoặc cookie); anonymous read-only bật bằng WS_ALLOW_ANONYMOUS=true
+		g.GET("/ws", validate, websocket.HandleWebSocket(chatHub, websocket.Config{
+			Secret:         jwtSecret,
+			AllowAnonymous: os.Getenv("WS_ALLOW_ANONYMOUS") == "true",
+			AllowedOrigins: splitList(os.Getenv("WS_ALLOWED_ORIGINS")),
+		}))
+		// LIVE FEED: progress/library/notification của user (resume bằng ?since=<seq>)
+		g.GET("/ws/feed", validate, websocket.HandleFeed(feedBus, websocket.Config{
+			Secret:         jwtSecret,
+			AllowedOrigins: splitList(os.Getenv("WS_ALLOWED_ORIGINS")),
+		}))
+
+		// GRAPHQL: query/mutation qua POST (có thể batch), subscription qua WebSocket trên GET
+		gqlRL := ratelimit.Middleware(rlStore, "graphql", graphqlLimit, auth.CtxUserIDKey)
+		g.POST("/graphql", auth.OptionalJWT(jwtSecret), gqlRL, validate, func(c *gin.Context) { handleGraphQL(c, db, graphqlSchema) })
+		g.GET("/graphql", validate, websocket.HandleGraphQL(graphqlSchema, websocket.Config{
+			Secret:         jwtSecret,
+			AllowedOrigins: splitList(os.Getenv("WS_ALLOWED_ORIGINS")),
+		}, func(ctx context.Context, userID string) context.Context {
+			return withGQLRequest(ctx, db, userID, false)
+		}))
+
+		// PROTECTED
+		authed := g.Group("")
+		authed.Use(auth.RequireJWT(jwtSecret), ratelimit.WritesOnly(ratelimit.Middleware(rlStore, "write", writeLimit, auth.CtxUserIDKey)), validate)
+		authed.POST("/library", func(c *gin.Context) { handleAddLibrary(c, db, feedBus) })
+		authed.PATCH("/progress", func(c *gin.Context) { handleUpdateProgress(c, db, progressCh) })
+		authed.GET("/chat/:room/messages", func(c *gin.Context) { handleChatHistory(c, chatStore, chatHub) })
+		authed.GET("/me", func(c *gin.Context) { handleGetMe(c, db) })
+		authed.PATCH("/me", func(c *gin.Context) { handleUpdateMe(c, db, mailer) })
+		authed.DELETE("/me", func(c *gin.Context) { handleDeleteMe(c, db) })
+		authed.POST("/me/password", func(c *gin.Context) { handleChangePassword(c, db) })
+		authed.POST("/me/email/verification", func(c *gin.Context) { handleResendVerification(c, db, mailer) })
+		authed.GET("/me/stats", func(c *gin.Context) { handleMyStats(c, db) })
+		authed.GET("/me/recommendations", func(c *gin.Context) { handleMyRecommendations(c, db) })
+		authed.POST("/users/:username/follow", func(c *gin.Context) { handleFollowUser(c, db, true) })
+		authed.DELETE("/users/:username/follow", func(c *gin.Context) { handleFollowUser(c, db, false) })
+		authed.GET("/feed", func(c *gin.Context) { handleActivityFeed(c, db) })
+		authed.GET("/me/privacy", func(c *gin.Context) { handleGetPrivacy(c, db) })
+		authed.PUT("/me/privacy", func(c *gin.Context) { handleSetPrivacy(c, db) })
+		authed.POST("/manga/:id/follow", func(c *gin.Context) { handleFollowManga(c, db, true) })
+		authed.DELETE("/manga/:id/follow", func(c *gin.Context) { handleFollowManga(c, db, false) })
+		authed.GET("/me/notifications", func(c *gin.Context) { handleListNotifications(c, db) })
+		authed.POST("/me/notifications/read-all", func(c *gin.Context) { handleMarkAllNotificationsRead(c, db) })
+		authed.POST("/me/notifications/:id/read", func(c *gin.Context) { handleMarkNotificationRead(c, db) })
+		authed.GET("/me/notification-preferences", func(c *gin.Context) { handleGetNotificationPrefs(c, db) })
+		authed.PUT("/me/notification-preferences", func(c *gin.Context) { handleSetNotificationPrefs(c, db) })
+		authed.PUT("/manga/:id/review", func(c *gin.Context) { handleUpsertReview(c, db) })
+		authed.DELETE("/manga/:id/review", func(c *gin.Context) { handleDeleteReview(c, db) })
+		authed.POST("/reviews/:id/helpful", func(c *gin.Context) { handleVoteReview(c, db, true) })
+		authed.DELETE("/reviews/:id/helpful", func(c *gin.Context) { handleVoteReview(c, db, false) })
+		authed.POST("/admin/notify", func(c *gin.Context) {
+			var req struct {
+				Message string `json:"message"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil || req.Message == "" {
+				apierr.Write(c, apierr.InvalidField("message", "required", "message required"))
+				return
+			}
+			udpServer.Broadcast(req.Message)
+			c.JSON(200, gin.H{"ok": true})
+		})
+
+		// ADMIN (moderation)
+		admin := authed.Group("/admin")
+		admin.Use(auth.RequireRole(auth.RoleAdmin))
+		admin.POST("/manga/:id/chapters", func(c *gin.Context) { handleReleaseChapter(c, db, dispatcher) })
+		admin.PUT("/chat/:room/retention", func(c *gin.Context) { handleSetChatRetention(c, chatStore) })
+		admin.POST("/reviews/:id/hide", func(c *gin.Context) { handleModerateReview(c, db, true) })
+		admin.POST("/reviews/:id/unhide", func(c *gin.Context) { handleModerateReview(c, db, false) })
+	}
+	routes(r.Group(apiPrefix))
+	routes(r.Group("", deprecatedAlias(apiPrefix)))
+
+	return r
+}
+
+// apiPrefix là prefix của API hiện hành
+const apiPrefix = "/api/v1"
+
+// deprecatedAlias đánh dấu route cũ không có prefix: client nên chuyển sang successor-version
+func deprecatedAlias(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+prefix+c.Request.URL.Path+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+// fatal thay log.Fatal: ghi lỗi qua slog rồi thoát
+func fatal(msg string, err error) {
+	logger.Error(msg, logging.Err(err))
+	os.Exit(1)
+}
+
+func newBroker(kind, redisURL string) (broker.Broker, error) {
+	switch kind {
+	case "", "memory":
+		return broker.NewMemory(), nil
+	case "redis":
+		logger.Info("using Redis broker", slog.String("host", redisHost(redisURL)))
+		return broker.NewRedis(redisURL)
+	}
+	return nil, fmt.Errorf("unknown broker %q (memory|redis)", kind)
+}
+
+// redisHost: chỉ host của REDIS_URL để ghi log (redis://[:password@]host... có thể chứa password)
+func redisHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(invalid url)"
+	}
+	return u.Host
 }
 
-func handleRegister(c *gin.Context, db *sql.DB) {
+// budget theo IP và theo user
+var (
+	authLimit    = ratelimit.PerMinute(20, 10)         // /auth/*
+	searchLimit  = ratelimit.Limit{Rate: 5, Burst: 20} // GET /manga, /manga/trending
+	writeLimit   = ratelimit.Limit{Rate: 2, Burst: 20} // POST/PUT/PATCH/DELETE đã login
+	graphqlLimit = ratelimit.Limit{Rate: 2, Burst: 20} // POST /graphql (1 request có thể là batch, có mutation)
+)
+
+// Cache-Control cho catalog: dùng lại tối đa max-age rồi revalidate bằng ETag (304 nếu không đổi).
+// Chi tiết có rating/popularity đổi thường xuyên hơn nên max-age ngắn hơn.
+const (
+	searchCacheControl   = "public, max-age=60"
+	detailCacheControl   = "public, max-age=15"
+	personalCacheControl = "private, no-cache" // response kèm progress của user
+)
+
+func newRateLimitStore(kind, redisURL string) (ratelimit.Store, error) {
+	switch kind {
+	case "", "memory":
+		return ratelimit.NewMemory(), nil
+	case "redis":
+		logger.Info("using Redis rate limit store", slog.String("host", redisHost(redisURL)))
+		return ratelimit.NewRedis(redisURL)
+	}
+	return nil, fmt.Errorf("unknown rate limit store %q (memory|redis)", kind)
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func handleRegister(c *gin.Context, db *sql.DB, mailer mail.Mailer) {
 	var req struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
+		Email    string `json:"email"` // tuỳ chọn, cần xác thực qua mail
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || req.Username == "" || req.Password == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "username/password required"})
+		apierr.Write(c, apierr.Invalid("username/password required"))
 		return
 	}
 
 	// Bonus: Sanitize username input
 	sanitizedUsername, err := sanitizeUsername(req.Username)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.Write(c, err)
 		return
 	}
 
 	// Bonus: Validate password length
-	if len(req.Password) < 6 || len(req.Password) > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "password must be 6-100 characters"})
+	if err := validatePassword("password", req.Password); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		apierr.Write(c, err)
 		return
 	}
+	if email != "" {
+		if taken, err := user.EmailTaken(c.Request.Context(), db, email, ""); err != nil {
+			apierr.Write(c, apierr.DB(err, "user"))
+			return
+		} else if taken {
+			apierr.Write(c, apierr.Conflict(user.ErrEmailTaken.Error()).WithReason("email_taken"))
+			return
+		}
+	}
 
 	// id đơn giản demo: dùng username làm id (sau này có thể đổi sang uuid)
-	if err := user.CreateUser(db, sanitizedUsername, sanitizedUsername, req.Password); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := user.CreateUser(c.Request.Context(), db, sanitizedUsername, sanitizedUsername, req.Password); errors.Is(err, user.ErrUsernameTaken) {
+		apierr.Write(c, apierr.Conflict(err.Error()).WithReason("username_taken"))
+		return
+	} else if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
 		return
 	}
+	if email != "" {
+		if err := user.SetEmail(c.Request.Context(), db, sanitizedUsername, email); err != nil {
+			logger.WarnContext(c.Request.Context(), "set email failed", slog.String("user_id", sanitizedUsername), logging.Err(err))
+		} else if err := sendVerificationEmail(c.Request.Context(), db, mailer, sanitizedUsername, sanitizedUsername, email); err != nil {
+			logger.WarnContext(c.Request.Context(), "send verification failed", slog.String("user_id", sanitizedUsername), logging.Err(err))
+		}
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"ok": true})
 }
 
-func handleLogin(c *gin.Context, db *sql.DB) {
+func handleLogin(c *gin.Context, db *sql.DB, guard *ratelimit.LoginGuard) {
 	var req struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+		apierr.Write(c, apierr.Invalid("invalid json"))
 		return
 	}
 
 	// Bonus: Sanitize username input
 	sanitizedUsername, err := sanitizeUsername(req.Username)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.Write(c, err)
+		return
+	}
+
+	// lần thử được đếm trước bcrypt; đang bị khoá vì sai nhiều lần thì không kiểm tra password
+	if wait := guard.Attempt(sanitizedUsername, c.ClientIP()); wait > 0 {
+		ratelimit.TooManyRequests(c, wait, "too many failed login attempts")
 		return
 	}
 
-	u, err := user.VerifyLogin(db, sanitizedUsername, req.Password)
+	u, err := user.VerifyLogin(c.Request.Context(), db, sanitizedUsername, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		apierr.Write(c, apierr.Unauthenticated("invalid credentials").WithReason("invalid_credentials"))
 		return
 	}
+	guard.Success(sanitizedUsername, c.ClientIP())
 
-	token, err := auth.SignJWT(jwtSecret, u.ID, u.Username, 24*time.Hour)
+	token, err := auth.SignJWT(jwtSecret, u.ID, u.Username, u.Role, 24*time.Hour)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "sign token failed"})
+		apierr.Write(c, apierr.Internal(err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"token": token})
 }
 
+// GET /manga: phân trang bằng cursor (next_cursor của trang trước); offset chỉ giữ cho client cũ.
+// include_total=true => trả thêm total (số manga khớp bộ lọc).
 func handleSearchManga(c *gin.Context, db *sql.DB) {
-	q := c.Query("q")
-	genre := c.Query("genre")
-	status := c.Query("status")
-	sortBy := c.Query("sort_by") // Bonus: Add sort_by parameter
-	limit := parseInt(c.Query("limit"), 20)
-	offset := parseInt(c.Query("offset"), 0)
+	p := manga.SearchParams{
+		Query:     c.Query("q"),
+		Genre:     c.Query("genre"),
+		Status:    c.Query("status"),
+		SortBy:    c.Query("sort_by"), // Bonus: Add sort_by parameter
+		Limit:     parseInt(c.Query("limit"), manga.DefaultPageSize),
+		Offset:    parseInt(c.Query("offset"), 0),
+		Cursor:    c.Query("cursor"),
+		WithTotal: c.Query("include_total") == "true",
+	}
 
 	// Bonus: Sanitize search query
-	if q != "" {
-		q = sanitizeSearchQuery(q)
+	if p.Query != "" {
+		p.Query = sanitizeSearchQuery(p.Query)
 	}
 	// Bonus: Sanitize genre
-	if genre != "" {
-		genre = sanitizeSearchQuery(genre)
+	if p.Genre != "" {
+		p.Genre = sanitizeSearchQuery(p.Genre)
 	}
 
 	// Bonus: Validate and sanitize sortBy
-	validSortOptions := []string{"title_asc", "title_desc", "author_asc", "author_desc", "chapters_asc", "chapters_desc"}
-	sortByValid := false
-	for _, valid := range validSortOptions {
-		if sortBy == valid {
-			sortByValid = true
-			break
-		}
+	if p.SortBy != "" && !manga.ValidSort(p.SortBy) {
+		apierr.Write(c, apierr.InvalidField("sort_by", "invalid", "invalid sort_by, options: "+strings.Join(manga.SortOptions, ", ")))
+		return
 	}
-	if sortBy != "" && !sortByValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort_by, options: title_asc, title_desc, author_asc, author_desc, chapters_asc, chapters_desc"})
+	fields, err := manga.ParseFields(c.Query("fields"))
+	if err != nil {
+		apierr.Write(c, apierr.InvalidField("fields", "invalid", err.Error()))
 		return
 	}
 
-	// Bonus: Use advanced search with sorting
-	res, err := manga.AdvancedSearch(db, q, genre, status, sortBy, limit, offset)
+	page, err := manga.SearchPage(c.Request.Context(), db, p)
+	if errors.Is(err, manga.ErrInvalidCursor) {
+		apierr.Write(c, apierr.InvalidField("cursor", "invalid", "invalid cursor (expired or from another query)"))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		apierr.Write(c, apierr.DB(err, "manga"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"results": res, "limit": limit, "offset": offset, "sort_by": sortBy})
+	modified, err := manga.LastModified(c.Request.Context(), db)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "manga"))
+		return
+	}
+
+	resp := gin.H{"results": fields.ApplyAll(page.Results), "limit": page.Limit, "offset": p.Offset, "sort_by": p.SortBy}
+	if page.NextCursor != "" {
+		resp["next_cursor"] = page.NextCursor
+	}
+	if page.Total != nil {
+		resp["total"] = *page.Total
+	}
+	// sort theo rating/popularity đổi mà catalog không đổi => chỉ revalidate bằng ETag
+	httpcache.JSON(c, resp, httpcache.Options{
+		CacheControl:  searchCacheControl,
+		LastModified:  modified,
+		ModifiedExact: manga.CatalogOnly(p.SortBy),
+	})
+}
+
+// window dạng "7d" / "30d" (1-90 ngày), mặc định 7d
+func handleTrendingManga(c *gin.Context, db *sql.DB) {
+	window := c.DefaultQuery("window", "7d")
+	days, err := strconv.Atoi(strings.TrimSuffix(window, "d"))
+	if err != nil || days < 1 || days > 90 {
+		apierr.Write(c, apierr.InvalidField("window", "invalid", "invalid window, use e.g. 7d or 30d (max 90d)"))
+		return
+	}
+	limit := parseInt(c.Query("limit"), 20)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	res, err := popularity.TopTrending(db, days, limit)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "manga"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": res, "window": window})
 }
 
 func handleMangaDetail(c *gin.Context, db *sql.DB) {
@@ -261,63 +708,90 @@ func handleMangaDetail(c *gin.Context, db *sql.DB) {
 	// Bonus: Sanitize manga ID
 	sanitizedID, err := sanitizeMangaID(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierr.Write(c, err)
 		return
 	}
-	m, err := manga.GetByID(db, sanitizedID)
+	fields, err := manga.ParseFields(c.Query("fields"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "manga not found"})
+		apierr.Write(c, apierr.InvalidField("fields", "invalid", err.Error()))
+		return
+	}
+	m, err := manga.GetByID(c.Request.Context(), db, sanitizedID)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "manga"))
+		return
+	}
+
+	rating, err := review.GetSummary(db, sanitizedID)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "manga"))
+		return
+	}
+	pop, err := popularity.GetCounters(db, sanitizedID)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "manga"))
 		return
 	}
 
-	// Nếu có JWT thì trả kèm progress (không bắt buộc, nhưng đúng hướng use-case)
-	userIDAny, ok := c.Get(auth.CtxUserIDKey)
-	if ok {
-		if p, err := library.GetProgress(db, userIDAny.(string), id); err == nil {
-			c.JSON(http.StatusOK, gin.H{"manga": m, "progress": p})
+	// rating/popularity không có mốc Last-Modified => chỉ revalidate bằng ETag
+	opts := httpcache.Options{CacheControl: detailCacheControl, LastModified: m.UpdatedAt}
+
+	// Nếu có JWT thì trả kèm progress (không bắt buộc, nhưng đúng hướng use-case).
+	// Response khác nhau theo token => cache dùng chung phải tách theo Authorization.
+	c.Writer.Header().Add("Vary", "Authorization")
+	if userID := c.GetString(auth.CtxUserIDKey); userID != "" {
+		if p, err := library.GetProgress(c.Request.Context(), db, userID, sanitizedID); err == nil {
+			opts.CacheControl = personalCacheControl
+			httpcache.JSON(c, gin.H{"manga": fields.Apply(m), "rating": rating, "popularity": pop, "progress": p}, opts)
 			return
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"manga": m})
+	httpcache.JSON(c, gin.H{"manga": fields.Apply(m), "rating": rating, "popularity": pop}, opts)
 }
 
-func handleAddLibrary(c *gin.Context, db *sql.DB) {
-	var req struct {
-		MangaID        string `json:"manga_id"`
-		Status         string `json:"status"`
-		CurrentChapter int    `json:"current_chapter"`
-		ListName       string `json:"list_name"` // Bonus: Multiple reading lists
-	}
+// libraryInput là body của POST /library và input của mutation addToLibrary (GraphQL)
+type libraryInput struct {
+	MangaID        string `json:"manga_id"`
+	Status         string `json:"status"`
+	CurrentChapter int    `json:"current_chapter"`
+	ListName       string `json:"list_name"` // Bonus: Multiple reading lists
+}
+
+func handleAddLibrary(c *gin.Context, db *sql.DB, bus *feed.Bus) {
+	var req libraryInput
 	if err := c.ShouldBindJSON(&req); err != nil || req.MangaID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "manga_id required"})
+		apierr.Write(c, apierr.InvalidField("manga_id", "required", "manga_id required"))
 		return
 	}
-	userID := c.GetString(auth.CtxUserIDKey)
+	if _, err := addToLibrary(c.Request.Context(), db, bus, c.GetString(auth.CtxUserIDKey), req); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
 
+// addToLibrary dùng chung cho POST /library và GraphQL
+func addToLibrary(ctx context.Context, db *sql.DB, bus *feed.Bus, userID string, req libraryInput) (library.Progress, error) {
 	// Bonus: Sanitize manga ID
 	sanitizedMangaID, err := sanitizeMangaID(req.MangaID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return library.Progress{}, err
 	}
 
 	// Bonus: Validate status
 	validatedStatus, err := validateStatus(req.Status)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return library.Progress{}, err
 	}
 
 	// Bonus: Validate chapter number
 	if req.CurrentChapter < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "chapter number cannot be negative"})
-		return
+		return library.Progress{}, apierr.InvalidField("current_chapter", "out_of_range", "chapter number cannot be negative")
 	}
 
-	if _, err := manga.GetByID(db, sanitizedMangaID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "manga not found"})
-		return
+	if _, err := manga.GetByID(ctx, db, sanitizedMangaID); err != nil {
+		return library.Progress{}, apierr.DB(err, "manga")
 	}
 
 	// Bonus: Use list_name from request, default to "default" if empty
@@ -326,34 +800,53 @@ func handleAddLibrary(c *gin.Context, db *sql.DB) {
 		listName = "default"
 	}
 
-	if err := library.UpsertProgress(db, library.Progress{
+	p := library.Progress{
 		UserID: userID, MangaID: sanitizedMangaID, CurrentChapter: req.CurrentChapter, Status: validatedStatus, ListName: listName,
-	}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
-		return
+	}
+	if err := library.UpsertProgress(ctx, db, p); err != nil {
+		return library.Progress{}, apierr.DB(err, "progress")
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	// Đang đọc => tự động follow để nhận thông báo chapter mới
+	if validatedStatus == "reading" {
+		if err := notify.Follow(db, userID, sanitizedMangaID); err != nil {
+			logger.WarnContext(ctx, "auto-follow failed", slog.String("user_id", userID), slog.String("manga_id", sanitizedMangaID), logging.Err(err))
+		}
+	}
+
+	bus.PublishContext(ctx, feed.TypeLibrary, userID, gin.H{
+		"manga_id": sanitizedMangaID, "status": validatedStatus, "current_chapter": req.CurrentChapter, "list_name": listName,
+	})
+	return p, nil
+}
+
+// progressInput là body của PATCH /progress và input của mutation updateProgress (GraphQL)
+type progressInput struct {
+	MangaID        string `json:"manga_id"`
+	CurrentChapter int    `json:"current_chapter"`
+	Status         string `json:"status"`
+	ListName       string `json:"list_name"` // Bonus: Multiple reading lists
 }
 
 func handleUpdateProgress(c *gin.Context, db *sql.DB, progressCh chan<- models.ProgressUpdate) {
-	var req struct {
-		MangaID        string `json:"manga_id"`
-		CurrentChapter int    `json:"current_chapter"`
-		Status         string `json:"status"`
-		ListName       string `json:"list_name"` // Bonus: Multiple reading lists
-	}
+	var req progressInput
 	if err := c.ShouldBindJSON(&req); err != nil || req.MangaID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "manga_id required"})
+		apierr.Write(c, apierr.InvalidField("manga_id", "required", "manga_id required"))
 		return
 	}
-	userID := c.GetString(auth.CtxUserIDKey)
+	if _, err := updateProgress(c.Request.Context(), db, progressCh, c.GetString(auth.CtxUserIDKey), req); err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
 
+// updateProgress dùng chung cho PATCH /progress và GraphQL
+func updateProgress(ctx context.Context, db *sql.DB, progressCh chan<- models.ProgressUpdate, userID string, req progressInput) (library.Progress, error) {
 	// Bonus: Sanitize manga ID
 	sanitizedMangaID, err := sanitizeMangaID(req.MangaID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return library.Progress{}, err
 	}
 
 	// Bonus: Validate status if provided
@@ -361,21 +854,18 @@ func handleUpdateProgress(c *gin.Context, db *sql.DB, progressCh chan<- models.P
 	if req.Status != "" {
 		validatedStatus, err = validateStatus(req.Status)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+			return library.Progress{}, err
 		}
 	}
 
-	m, err := manga.GetByID(db, sanitizedMangaID)
+	m, err := manga.GetByID(ctx, db, sanitizedMangaID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "manga not found"})
-		return
+		return library.Progress{}, apierr.DB(err, "manga")
 	}
 
 	// Validate chapter number
 	if req.CurrentChapter < 0 || (m.TotalChapters > 0 && req.CurrentChapter > m.TotalChapters) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chapter number"})
-		return
+		return library.Progress{}, apierr.InvalidField("current_chapter", "out_of_range", "invalid chapter number")
 	}
 
 	// Bonus: Use list_name from request, default to "default" if empty
@@ -385,96 +875,59 @@ func handleUpdateProgress(c *gin.Context, db *sql.DB, progressCh chan<- models.P
 	}
 
 	// Save progress to database
-	if err := library.UpsertProgress(db, library.Progress{
+	p := library.Progress{
 		UserID: userID, MangaID: sanitizedMangaID, CurrentChapter: req.CurrentChapter, Status: validatedStatus, ListName: listName,
-	}); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
-		return
+	}
+	if err := library.UpsertProgress(ctx, db, p); err != nil {
+		return library.Progress{}, apierr.DB(err, "progress")
 	}
 
 	evt := models.ProgressUpdate{
 		UserID:    userID,
 		MangaID:   sanitizedMangaID,
 		Chapter:   req.CurrentChapter,
+		ListName:  listName,
 		Timestamp: time.Now().Unix(),
+		RequestID: logging.RequestID(ctx),
+		Trace:     tracing.Inject(ctx),
 	}
 
 	// tránh block nếu channel đầy
 	select {
 	case progressCh <- evt:
 	default:
-		log.Println("warn: progress channel full, drop event")
+		logger.WarnContext(ctx, "progress channel full, drop event")
+		metrics.Dropped(metrics.DropProgressChannel)
 	}
-
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	return p, nil
 }
 
-// Bonus: Health Check endpoint - checks all service statuses
-func handleHealthCheck(c *gin.Context, db *sql.DB, tcpServer *tcpsync.Server, udpServer *udpnotify.Server, grpcServer *grpc.Server, chatHub *websocket.ChatHub) {
-	status := gin.H{
-		"status":    "healthy",
-		"timestamp": time.Now().Unix(),
-		"services":  gin.H{},
-	}
-
-	allHealthy := true
-	services := status["services"].(gin.H)
-
-	// Check database
-	if err := db.Ping(); err != nil {
-		services["database"] = gin.H{"status": "unhealthy", "error": err.Error()}
-		allHealthy = false
-	} else {
-		services["database"] = gin.H{"status": "healthy"}
-	}
-
-	// Check TCP server - try to connect to port
-	tcpHealthy := checkTCPHealth(":9090")
-	services["tcp"] = gin.H{"status": map[bool]string{true: "healthy", false: "unhealthy"}[tcpHealthy]}
-	if !tcpHealthy {
-		allHealthy = false
-	}
+func handleMyStats(c *gin.Context, db *sql.DB) {
+	userID := c.GetString(auth.CtxUserIDKey)
 
-	// Check UDP server - check if server is initialized
-	if udpServer != nil {
-		services["udp"] = gin.H{"status": "healthy"}
-	} else {
-		services["udp"] = gin.H{"status": "unhealthy"}
-		allHealthy = false
+	st, err := stats.ForUser(db, userID, time.Now())
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "stats"))
+		return
 	}
 
-	// Check gRPC server - try to connect to port
-	grpcHealthy := checkTCPHealth(":50051")
-	services["grpc"] = gin.H{"status": map[bool]string{true: "healthy", false: "unhealthy"}[grpcHealthy]}
-	if !grpcHealthy {
-		allHealthy = false
-	}
+	c.JSON(http.StatusOK, st)
+}
 
-	// Check WebSocket hub - check if hub is initialized
-	if chatHub != nil {
-		services["websocket"] = gin.H{"status": "healthy"}
-	} else {
-		services["websocket"] = gin.H{"status": "unhealthy"}
-		allHealthy = false
+func handleMyRecommendations(c *gin.Context, db *sql.DB) {
+	userID := c.GetString(auth.CtxUserIDKey)
+	limit := parseInt(c.Query("limit"), 10)
+	if limit <= 0 || limit > 50 {
+		limit = 10
 	}
 
-	if !allHealthy {
-		status["status"] = "degraded"
-		c.JSON(http.StatusOK, status)
+	recs, err := recommend.ForUser(db, userID, limit)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "recommendations"))
 		return
 	}
 
-	c.JSON(http.StatusOK, status)
-}
-
-// Bonus: Helper function to check TCP port health
-func checkTCPHealth(addr string) bool {
-	conn, err := net.DialTimeout("tcp", addr, 1*time.Second)
-	if err != nil {
-		return false
-	}
-	conn.Close()
-	return true
+	c.JSON(http.StatusOK, gin.H{"results": recs})
 }
 
 // Bonus: Input sanitization functions
@@ -483,14 +936,18 @@ func sanitizeUsername(username string) (string, error) {
 	username = strings.TrimSpace(username)
 	// Validate length
 	if len(username) < 3 || len(username) > 20 {
-		return "", fmt.Errorf("username must be 3-20 characters")
+		return "", apierr.InvalidField("username", "invalid", "username must be 3-20 characters")
 	}
 	// Only allow alphanumeric and underscore
 	for _, r := range username {
 		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_') {
-			return "", fmt.Errorf("username can only contain letters, numbers, and underscores")
+			return "", apierr.InvalidField("username", "invalid", "username can only contain letters, numbers, and underscores")
 		}
 	}
+	// chat của tài khoản đã xoá được gắn với user_id này
+	if strings.EqualFold(username, user.DeletedUserID) {
+		return "", apierr.InvalidField("username", "reserved", "username is reserved")
+	}
 	return username, nil
 }
 
@@ -516,14 +973,14 @@ func sanitizeSearchQuery(query string) string {
 func sanitizeMangaID(id string) (string, error) {
 	id = strings.TrimSpace(id)
 	if id == "" {
-		return "", fmt.Errorf("manga ID cannot be empty")
+		return "", apierr.InvalidField("manga_id", "required", "manga ID cannot be empty")
 	}
 	if len(id) > 50 {
-		return "", fmt.Errorf("manga ID too long")
+		return "", apierr.InvalidField("manga_id", "too_long", "manga ID too long")
 	}
 	for _, r := range id {
 		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_') {
-			return "", fmt.Errorf("invalid manga ID format")
+			return "", apierr.InvalidField("manga_id", "invalid", "invalid manga ID format")
 		}
 	}
 	return id, nil
@@ -538,7 +995,18 @@ func validateStatus(status string) (string, error) {
 			return status, nil
 		}
 	}
-	return "", fmt.Errorf("invalid status, must be one of: %v", validStatuses)
+	return "", apierr.InvalidField("status", "invalid", fmt.Sprintf("invalid status, must be one of: %v", validStatuses))
+}
+
+// "a, b,c" -> [a b c], bỏ phần tử rỗng
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
 }
 
 func parseInt(s string, def int) int {
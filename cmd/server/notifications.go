@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/apierr"
+	"mangahub/internal/auth"
+	"mangahub/internal/manga"
+	"mangahub/internal/notify"
+)
+
+func handleFollowManga(c *gin.Context, db *sql.DB, follow bool) {
+	mangaID, err := sanitizeMangaID(c.Param("id"))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	userID := c.GetString(auth.CtxUserIDKey)
+
+	if !follow {
+		if err := notify.Unfollow(db, userID, mangaID); err != nil {
+			apierr.Write(c, apierr.DB(err, "notification"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "following": false})
+		return
+	}
+
+	if _, err := manga.GetByID(c.Request.Context(), db, mangaID); err != nil {
+		apierr.Write(c, apierr.DB(err, "manga"))
+		return
+	}
+	if err := notify.Follow(db, userID, mangaID); err != nil {
+		apierr.Write(c, apierr.DB(err, "notification"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "following": true})
+}
+
+func handleListNotifications(c *gin.Context, db *sql.DB) {
+	userID := c.GetString(auth.CtxUserIDKey)
+	unreadOnly := c.Query("unread") == "true"
+	limit := parseInt(c.Query("limit"), 20)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset := parseInt(c.Query("offset"), 0)
+
+	res, err := notify.List(db, userID, unreadOnly, limit, offset)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "notification"))
+		return
+	}
+	unread, err := notify.UnreadCount(db, userID)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "notification"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": res, "unread": unread, "limit": limit, "offset": offset})
+}
+
+func handleMarkNotificationRead(c *gin.Context, db *sql.DB) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierr.Write(c, apierr.InvalidField("id", "invalid", "invalid notification id"))
+		return
+	}
+
+	if err := notify.MarkRead(db, c.GetString(auth.CtxUserIDKey), id); err != nil {
+		apierr.Write(c, apierr.DB(err, "notification"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func handleMarkAllNotificationsRead(c *gin.Context, db *sql.DB) {
+	n, err := notify.MarkAllRead(db, c.GetString(auth.CtxUserIDKey))
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "notification"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "updated": n})
+}
+
+func handleGetNotificationPrefs(c *gin.Context, db *sql.DB) {
+	p, err := notify.GetPrefs(db, c.GetString(auth.CtxUserIDKey))
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "notification"))
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func handleSetNotificationPrefs(c *gin.Context, db *sql.DB) {
+	userID := c.GetString(auth.CtxUserIDKey)
+
+	// bắt đầu từ pref hiện tại để client chỉ cần gửi field muốn đổi
+	p, err := notify.GetPrefs(db, userID)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "notification"))
+		return
+	}
+	if err := c.ShouldBindJSON(&p); err != nil {
+		apierr.Write(c, apierr.Invalid("invalid json"))
+		return
+	}
+	if err := notify.SetPrefs(db, userID, p); err != nil {
+		apierr.Write(c, apierr.DB(err, "notification"))
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func handleReleaseChapter(c *gin.Context, db *sql.DB, dispatcher *notify.Dispatcher) {
+	var req struct {
+		Chapter int    `json:"chapter"`
+		Title   string `json:"title"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Chapter <= 0 {
+		apierr.Write(c, apierr.InvalidField("chapter", "required", "chapter (> 0) required"))
+		return
+	}
+	mangaID, err := sanitizeMangaID(c.Param("id"))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	m, err := manga.GetByID(c.Request.Context(), db, mangaID)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "manga"))
+		return
+	}
+
+	notified, err := dispatcher.ReleaseChapter(m.ID, m.Title, req.Chapter, req.Title)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "notification"))
+		return
+	}
+	// catalog: total_chapters theo chapter mới nhất (xoá cache + đổi ETag của manga)
+	if _, err := manga.RaiseChapters(c.Request.Context(), db, m.ID, req.Chapter); err != nil {
+		apierr.Write(c, apierr.DB(err, "manga"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "notified": notified})
+}
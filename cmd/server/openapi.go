@@ -0,0 +1,406 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mangahub/internal/apierr"
+	mangapkg "mangahub/internal/manga"
+	"mangahub/internal/openapi"
+)
+
+// apiSpec mô tả mọi route HTTP đăng ký trong main; route mới phải thêm ở đây,
+// nếu không TestAPISpecCoversRoutes (openapi_test.go) báo lỗi.
+func apiSpec() *openapi.Document {
+	d := openapi.New("MangaHub API", "1.0.0",
+		"REST API của MangaHub. Lỗi trả về dạng application/problem+json (RFC 7807). "+
+			"Route không có prefix "+apiPrefix+" là alias cũ (header Deprecation: true).")
+	d.Servers = []openapi.Server{{URL: "/"}}
+	d.AliasPrefix = apiPrefix
+	d.Tags = []openapi.Tag{
+		{Name: "auth"}, {Name: "manga"}, {Name: "library"}, {Name: "account"}, {Name: "social"},
+		{Name: "notifications"}, {Name: "reviews"}, {Name: "chat"}, {Name: "graphql"}, {Name: "admin"}, {Name: "health"},
+	}
+
+	// schema dùng chung
+	problem := d.Define("Problem", openapi.Object(map[string]*openapi.Schema{
+		"type":       openapi.String(),
+		"title":      openapi.String(),
+		"status":     openapi.Integer(),
+		"detail":     openapi.String(),
+		"instance":   openapi.String(),
+		"code":       openapi.String().OneOf(codes()...),
+		"reason":     openapi.String(),
+		"request_id": openapi.String(),
+		"error":      openapi.String().Desc("giống detail, cho client cũ"),
+		"errors": openapi.Array(openapi.Object(map[string]*openapi.Schema{
+			"field": openapi.String(), "reason": openapi.String(), "message": openapi.String(),
+		})),
+	}, "type", "title", "status", "code"))
+	ok := d.Define("OK", openapi.Object(map[string]*openapi.Schema{"ok": openapi.Boolean()}, "ok"))
+	mangaID := openapi.String().Len(1, 50).Match(`^[A-Za-z0-9_-]+$`)
+	username := openapi.String().Len(3, 20).Match(`^[A-Za-z0-9_]+$`)
+	status := openapi.String().OneOf("plan-to-read", "reading", "completed", "on-hold", "dropped")
+	manga := d.Define("Manga", openapi.Object(map[string]*openapi.Schema{
+		"id":             openapi.String(),
+		"title":          openapi.String(),
+		"author":         openapi.String(),
+		"genres":         openapi.String().Desc("JSON array dạng text"),
+		"status":         openapi.String(),
+		"total_chapters": openapi.Integer(),
+		"description":    openapi.String(),
+		"updated_at":     openapi.String().Fmt("date-time"),
+	}))
+	progress := d.Define("Progress", openapi.Object(map[string]*openapi.Schema{
+		"user_id":         openapi.String(),
+		"manga_id":        openapi.String(),
+		"current_chapter": openapi.Integer(),
+		"status":          openapi.String(),
+		"list_name":       openapi.String(),
+	}))
+	rating := d.Define("RatingSummary", openapi.Object(map[string]*openapi.Schema{
+		"average": openapi.Number(), "count": openapi.Integer(), "histogram": openapi.Array(openapi.Integer()),
+	}))
+	review := d.Define("Review", openapi.Object(map[string]*openapi.Schema{
+		"id":            openapi.Integer(),
+		"user_id":       openapi.String(),
+		"username":      openapi.String(),
+		"manga_id":      openapi.String(),
+		"score":         openapi.Integer(),
+		"body":          openapi.String(),
+		"spoiler":       openapi.Boolean(),
+		"helpful_count": openapi.Integer(),
+		"created_at":    openapi.String().Fmt("date-time"),
+		"updated_at":    openapi.String().Fmt("date-time"),
+	}))
+	notification := d.Define("Notification", openapi.Object(map[string]*openapi.Schema{
+		"id":         openapi.Integer(),
+		"user_id":    openapi.String(),
+		"type":       openapi.String(),
+		"manga_id":   openapi.String(),
+		"chapter":    openapi.Integer(),
+		"message":    openapi.String(),
+		"read":       openapi.Boolean(),
+		"created_at": openapi.String().Fmt("date-time"),
+	}))
+	prefs := d.Define("NotificationPrefs", openapi.Object(map[string]*openapi.Schema{
+		"websocket": openapi.Boolean(), "tcp": openapi.Boolean(), "udp": openapi.Boolean(), "grpc": openapi.Boolean(),
+	}))
+	privacy := d.Define("Privacy", openapi.Object(map[string]*openapi.Schema{
+		"profile_public":  openapi.Boolean(),
+		"activity_public": openapi.Boolean(),
+		"private_lists":   openapi.Array(openapi.String().Len(1, 50)),
+	}))
+	profile := d.Define("Profile", openapi.Object(map[string]*openapi.Schema{
+		"id":             openapi.String(),
+		"username":       openapi.String(),
+		"display_name":   openapi.String(),
+		"avatar_url":     openapi.String(),
+		"bio":            openapi.String(),
+		"email":          openapi.String().Fmt("email"),
+		"email_verified": openapi.Boolean(),
+		"role":           openapi.String(),
+		"created_at":     openapi.String().Fmt("date-time"),
+	}))
+	health := d.Define("Health", openapi.Object(map[string]*openapi.Schema{
+		"status":     openapi.String().OneOf("up", "down"),
+		"timestamp":  openapi.Integer(),
+		"components": openapi.Map(openapi.Object(map[string]*openapi.Schema{"status": openapi.String(), "details": openapi.Map(nil), "last_error": openapi.String()})),
+	}))
+
+	idParam := openapi.PathParam("id", "manga id", mangaID)
+	limit := func(def, max int) openapi.Parameter {
+		return openapi.QueryParam("limit", "số phần tử (1-"+strconv.Itoa(max)+")", openapi.Integer().Min(1).Max(float64(max)).Def(def))
+	}
+	offset := openapi.QueryParam("offset", "", openapi.Integer().Min(0).Def(0))
+	before := openapi.QueryParam("before", "cursor: id của phần tử cuối trang trước", openapi.Integer().Min(0))
+
+	resp := func(code int, r openapi.Response) map[string]openapi.Response {
+		return map[string]openapi.Response{strconv.Itoa(code): r}
+	}
+	// op gắn response lỗi chung (problem+json) theo errCodes
+	op := func(tag, summary string, o openapi.Operation, okCode int, okResp openapi.Response, errCodes ...int) *openapi.Operation {
+		o.Tags = []string{tag}
+		o.Summary = summary
+		o.Responses = resp(okCode, okResp)
+		for _, c := range errCodes {
+			o.Responses[strconv.Itoa(c)] = openapi.Response{
+				Description: http.StatusText(c),
+				Content:     map[string]openapi.MediaType{apierr.ContentType: {Schema: problem}},
+			}
+		}
+		if o.Security != nil {
+			o.Responses["401"] = openapi.Response{Description: "missing/invalid token", Content: map[string]openapi.MediaType{apierr.ContentType: {Schema: problem}}}
+		}
+		return &o
+	}
+	okResp := openapi.OK("ok", ok)
+	api := func(method, path string, o *openapi.Operation) { d.Add(method, apiPrefix+path, o) }
+	// conditional: GET có ETag / Last-Modified / Cache-Control, trả 304 khi validator khớp
+	conditional := func(o *openapi.Operation) *openapi.Operation {
+		o.Parameters = append(o.Parameters,
+			openapi.HeaderParam("If-None-Match", "ETag đã lưu (ưu tiên hơn If-Modified-Since)", openapi.String()),
+			openapi.HeaderParam("If-Modified-Since", "chỉ dùng khi response chỉ phụ thuộc catalog", openapi.String()),
+		)
+		headers := map[string]openapi.Header{
+			"ETag":          {Description: "validator yếu (W/) theo nội dung", Schema: openapi.String()},
+			"Last-Modified": {Description: "updated_at mới nhất của manga liên quan", Schema: openapi.String()},
+			"Cache-Control": {Schema: openapi.String()},
+		}
+		ok := o.Responses["200"]
+		ok.Headers = headers
+		o.Responses["200"] = ok
+		o.Responses["304"] = openapi.Response{Description: "không đổi so với bản client đang giữ", Headers: headers}
+		return o
+	}
+
+	// HEALTH & META
+	d.Add(http.MethodGet, "/livez", op("health", "Liveness", openapi.Operation{}, 200, openapi.OK("process còn chạy", health), 503))
+	d.Add(http.MethodGet, "/readyz", op("health", "Readiness (DB, broker, TCP/UDP/gRPC, chat, feed)", openapi.Operation{}, 200, openapi.OK("sẵn sàng", health), 503))
+	d.Add(http.MethodGet, "/health", op("health", "Như /readyz (giữ cho client cũ)", openapi.Operation{Deprecated: true}, 200, openapi.OK("sẵn sàng", health), 503))
+	d.Add(http.MethodGet, "/metrics", op("health", "Prometheus metrics", openapi.Operation{}, 200,
+		openapi.Response{Description: "text exposition format", Content: map[string]openapi.MediaType{"text/plain": {Schema: openapi.String()}}}))
+	d.Add(http.MethodGet, "/openapi.json", op("health", "Tài liệu OpenAPI này", openapi.Operation{}, 200, openapi.OK("OpenAPI 3 document", openapi.Map(nil))))
+
+	// AUTH
+	api(http.MethodPost, "/auth/register", op("auth", "Đăng ký", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{
+			"username": username,
+			"password": openapi.String().Len(6, 100),
+			"email":    openapi.String().Fmt("email").Len(0, 254).Desc("tuỳ chọn, cần xác thực qua mail"),
+		}, "username", "password")),
+	}, 201, okResp, 400, 409, 429))
+	api(http.MethodPost, "/auth/login", op("auth", "Đăng nhập, trả JWT (24h)", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{"username": username, "password": openapi.String().Len(1, 100)}, "username", "password")),
+	}, 200, openapi.OK("token", openapi.Object(map[string]*openapi.Schema{"token": openapi.String()}, "token")), 400, 401, 429))
+	verify := openapi.Object(map[string]*openapi.Schema{"token": openapi.String().Len(1, 200)})
+	api(http.MethodGet, "/auth/verify-email", op("auth", "Xác thực email (link trong mail)", openapi.Operation{
+		Parameters: []openapi.Parameter{openapi.QueryParam("token", "", openapi.String().Len(1, 200))},
+	}, 200, openapi.OK("email đã xác thực", openapi.Object(map[string]*openapi.Schema{"ok": openapi.Boolean(), "email": openapi.String()})), 400, 429))
+	api(http.MethodPost, "/auth/verify-email", op("auth", "Xác thực email", openapi.Operation{
+		Parameters:  []openapi.Parameter{openapi.QueryParam("token", "", openapi.String().Len(1, 200))},
+		RequestBody: &openapi.RequestBody{Content: openapi.JSON(verify)},
+	}, 200, openapi.OK("email đã xác thực", openapi.Object(map[string]*openapi.Schema{"ok": openapi.Boolean(), "email": openapi.String()})), 400, 429))
+	api(http.MethodPost, "/auth/password/forgot", op("auth", "Gửi mail đặt lại password (luôn 200)", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{"email": openapi.String().Len(1, 254)}, "email")),
+	}, 200, okResp, 400, 429))
+	api(http.MethodPost, "/auth/password/reset", op("auth", "Đặt password mới bằng token trong mail", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{"token": openapi.String().Len(1, 200), "new_password": openapi.String().Len(6, 100)}, "token", "new_password")),
+	}, 200, okResp, 400, 429))
+
+	// MANGA
+	fields := openapi.QueryParam("fields", "sparse fieldset của manga, vd id,title (id luôn có): "+strings.Join(mangapkg.Fields, ", "),
+		openapi.String().Len(0, 200).Match(`^[a-z_]+(,[a-z_]+)*$`))
+	api(http.MethodGet, "/manga", conditional(op("manga", "Tìm manga", openapi.Operation{
+		Parameters: []openapi.Parameter{
+			openapi.QueryParam("q", "tìm theo title/author", openapi.String().Len(0, 200)),
+			openapi.QueryParam("genre", "", openapi.String().Len(0, 100)),
+			openapi.QueryParam("status", "trạng thái phát hành", openapi.String().Len(0, 50)),
+			openapi.QueryParam("sort_by", "", openapi.String().OneOf(mangapkg.SortOptions...)),
+			limit(mangapkg.DefaultPageSize, mangapkg.MaxPageSize),
+			openapi.QueryParam("cursor", "next_cursor của trang trước (cùng q/genre/status/sort_by)", openapi.String().Len(1, 1000)),
+			openapi.QueryParam("include_total", "trả thêm total", openapi.Boolean()),
+			openapi.QueryParam("offset", "chỉ cho client cũ, bị bỏ qua khi có cursor", openapi.Integer().Min(0).Def(0)),
+			fields,
+		},
+	}, 200, openapi.OK("kết quả", openapi.Object(map[string]*openapi.Schema{
+		"results":     openapi.Array(manga),
+		"limit":       openapi.Integer(),
+		"offset":      openapi.Integer(),
+		"sort_by":     openapi.String(),
+		"next_cursor": openapi.String().Desc("không có khi đã hết kết quả"),
+		"total":       openapi.Integer().Desc("chỉ có khi include_total=true"),
+	})), 400, 429)))
+	api(http.MethodGet, "/manga/trending", op("manga", "Manga trending", openapi.Operation{
+		Parameters: []openapi.Parameter{
+			openapi.QueryParam("window", "vd 7d, 30d (tối đa 90d)", openapi.String().Match(`^[0-9]+d$`).Def("7d")),
+			limit(20, 100),
+		},
+	}, 200, openapi.OK("kết quả", openapi.Object(map[string]*openapi.Schema{
+		"results": openapi.Array(openapi.Object(map[string]*openapi.Schema{"manga": manga, "adds": openapi.Integer(), "completions": openapi.Integer(), "score": openapi.Integer()})),
+		"window":  openapi.String(),
+	})), 400, 429))
+	api(http.MethodGet, "/manga/{id}", conditional(op("manga", "Chi tiết manga (có token thì kèm progress)", openapi.Operation{
+		Parameters: []openapi.Parameter{idParam, fields},
+	}, 200, openapi.OK("manga", openapi.Object(map[string]*openapi.Schema{
+		"manga": manga, "rating": rating, "popularity": openapi.Map(openapi.Integer()), "progress": progress,
+	})), 400, 404)))
+	api(http.MethodGet, "/manga/{id}/reviews", op("reviews", "Review của manga", openapi.Operation{
+		Parameters: []openapi.Parameter{idParam, openapi.QueryParam("sort", "", openapi.String().OneOf("helpful", "recent").Def("helpful")), limit(20, 100), offset},
+	}, 200, openapi.OK("review", openapi.Object(map[string]*openapi.Schema{
+		"results": openapi.Array(review), "limit": openapi.Integer(), "offset": openapi.Integer(), "sort": openapi.String(),
+	})), 400))
+
+	// WEBSOCKET
+	api(http.MethodGet, "/ws", op("chat", "WebSocket chat (JWT qua ?token=, subprotocol bearer hoặc cookie)", openapi.Operation{
+		Parameters: []openapi.Parameter{openapi.QueryParam("token", "", openapi.String())},
+	}, 101, openapi.Response{Description: "Switching Protocols"}, 401, 403))
+	api(http.MethodGet, "/ws/feed", op("library", "WebSocket live feed (progress/library/notification)", openapi.Operation{
+		Parameters: []openapi.Parameter{openapi.QueryParam("token", "", openapi.String()), openapi.QueryParam("since", "seq cuối đã nhận, để resume", openapi.Integer().Min(0))},
+	}, 101, openapi.Response{Description: "Switching Protocols"}, 400, 401))
+
+	// GRAPHQL
+	api(http.MethodPost, "/graphql", op("graphql", "GraphQL query/mutation (manga, library, user, progress)", openapi.Operation{
+		Description: "Body là 1 operation hoặc mảng operation (batch, tối đa 10). Token tuỳ chọn; mutation cần login. " +
+			"Lỗi GraphQL trả 200 trong errors[] với extensions.code như problem+json. " +
+			"Cost (số object ước lượng, list nhân với first) tối đa 5000 cho cả request, vượt => extensions.reason=query_too_complex. " +
+			"Batch có mutation thì các operation chạy tuần tự theo thứ tự gửi.",
+		RequestBody: openapi.Body(&openapi.Schema{Description: "operation {query, operationName, variables} hoặc mảng operation"}),
+	}, 200, openapi.OK("GraphQL response (object, hoặc mảng khi batch)", openapi.Map(nil)), 400, 413, 429))
+	api(http.MethodGet, "/graphql", op("graphql", "GraphQL subscription qua WebSocket (subprotocol graphql-transport-ws)", openapi.Operation{
+		Description: "JWT qua header, ?token=, cookie hoặc payload connection_init {authorization}. Không có token => chỉ progress công khai.",
+		Parameters:  []openapi.Parameter{openapi.QueryParam("token", "", openapi.String())},
+	}, 101, openapi.Response{Description: "Switching Protocols"}, 400, 401))
+
+	// PROFILE (public)
+	api(http.MethodGet, "/users/{username}", op("social", "Profile công khai", openapi.Operation{
+		Parameters: []openapi.Parameter{openapi.PathParam("username", "", username)},
+	}, 200, openapi.OK("profile", openapi.Map(nil)), 400, 404))
+
+	// các route dưới đây cần JWT
+	authed := func(tag, summary string, o openapi.Operation, okCode int, okResp openapi.Response, errCodes ...int) *openapi.Operation {
+		o.Security = openapi.Bearer
+		return op(tag, summary, o, okCode, okResp, append(errCodes, 429)...)
+	}
+
+	// LIBRARY & PROGRESS
+	api(http.MethodPost, "/library", authed("library", "Thêm manga vào library", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{
+			"manga_id":        mangaID,
+			"status":          status,
+			"current_chapter": openapi.Integer().Min(0),
+			"list_name":       openapi.String().Len(0, 50).Def("default"),
+		}, "manga_id", "status")),
+	}, 200, okResp, 400, 404))
+	api(http.MethodPatch, "/progress", authed("library", "Cập nhật chapter đang đọc", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{
+			"manga_id":        mangaID,
+			"current_chapter": openapi.Integer().Min(0),
+			"status":          status,
+			"list_name":       openapi.String().Len(0, 50).Def("default"),
+		}, "manga_id", "current_chapter")),
+	}, 200, okResp, 400, 404))
+	api(http.MethodGet, "/me/stats", authed("library", "Thống kê đọc của user", openapi.Operation{}, 200, openapi.OK("stats", openapi.Map(nil)), 500))
+	api(http.MethodGet, "/me/recommendations", authed("library", "Gợi ý manga", openapi.Operation{
+		Parameters: []openapi.Parameter{limit(10, 50)},
+	}, 200, openapi.OK("gợi ý", openapi.Object(map[string]*openapi.Schema{"results": openapi.Array(openapi.Object(map[string]*openapi.Schema{
+		"manga": manga, "score": openapi.Number(), "reason": openapi.String().OneOf("similar", "popular"),
+	}))})), 500))
+
+	// ACCOUNT
+	api(http.MethodGet, "/me", authed("account", "Profile của user", openapi.Operation{}, 200, openapi.OK("profile", profile), 404))
+	api(http.MethodPatch, "/me", authed("account", "Sửa profile (chỉ field có trong body)", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{
+			"display_name": openapi.String().Len(0, 50).Null(),
+			"avatar_url":   openapi.String().Len(0, 500).Null(),
+			"bio":          openapi.String().Len(0, 500).Null(),
+			"email":        openapi.String().Len(0, 254).Null().Desc("đổi email => gửi lại mail xác thực; \"\" = xoá"),
+		})),
+	}, 200, openapi.OK("profile", profile), 400, 409))
+	api(http.MethodDelete, "/me", authed("account", "Xoá tài khoản", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{"password": openapi.String().Len(1, 100)}, "password")),
+	}, 200, okResp, 400))
+	api(http.MethodPost, "/me/password", authed("account", "Đổi password (thu hồi token cũ, trả token mới)", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{"current_password": openapi.String().Len(1, 100), "new_password": openapi.String().Len(6, 100)},
+			"current_password", "new_password")),
+	}, 200, openapi.OK("token mới", openapi.Object(map[string]*openapi.Schema{"ok": openapi.Boolean(), "token": openapi.String()})), 400))
+	api(http.MethodPost, "/me/email/verification", authed("account", "Gửi lại mail xác thực", openapi.Operation{}, 200, okResp, 400, 503))
+	api(http.MethodGet, "/me/privacy", authed("social", "Cài đặt riêng tư", openapi.Operation{}, 200, openapi.OK("privacy", privacy)))
+	api(http.MethodPut, "/me/privacy", authed("social", "Đổi cài đặt riêng tư", openapi.Operation{RequestBody: openapi.Body(privacy)}, 200, openapi.OK("privacy", privacy), 400))
+
+	// SOCIAL
+	follow := openapi.Object(map[string]*openapi.Schema{"ok": openapi.Boolean(), "following": openapi.Boolean(), "followers": openapi.Integer()})
+	api(http.MethodPost, "/users/{username}/follow", authed("social", "Follow user", openapi.Operation{
+		Parameters: []openapi.Parameter{openapi.PathParam("username", "", username)},
+	}, 200, openapi.OK("ok", follow), 400, 404))
+	api(http.MethodDelete, "/users/{username}/follow", authed("social", "Bỏ follow user", openapi.Operation{
+		Parameters: []openapi.Parameter{openapi.PathParam("username", "", username)},
+	}, 200, openapi.OK("ok", follow), 400, 404))
+	api(http.MethodGet, "/feed", authed("social", "Hoạt động của người đang follow", openapi.Operation{
+		Parameters: []openapi.Parameter{before, limit(20, 100)},
+	}, 200, openapi.OK("feed", openapi.Object(map[string]*openapi.Schema{"results": openapi.Array(openapi.Map(nil)), "next_before": openapi.Integer()})), 400))
+
+	// NOTIFICATIONS
+	api(http.MethodPost, "/manga/{id}/follow", authed("notifications", "Follow manga (nhận thông báo chapter mới)", openapi.Operation{
+		Parameters: []openapi.Parameter{idParam},
+	}, 200, openapi.OK("ok", openapi.Object(map[string]*openapi.Schema{"ok": openapi.Boolean(), "following": openapi.Boolean()})), 400, 404))
+	api(http.MethodDelete, "/manga/{id}/follow", authed("notifications", "Bỏ follow manga", openapi.Operation{
+		Parameters: []openapi.Parameter{idParam},
+	}, 200, openapi.OK("ok", openapi.Object(map[string]*openapi.Schema{"ok": openapi.Boolean(), "following": openapi.Boolean()})), 400))
+	api(http.MethodGet, "/me/notifications", authed("notifications", "Danh sách notification", openapi.Operation{
+		Parameters: []openapi.Parameter{openapi.QueryParam("unread", "chỉ lấy chưa đọc", openapi.Boolean()), limit(20, 100), offset},
+	}, 200, openapi.OK("notification", openapi.Object(map[string]*openapi.Schema{
+		"results": openapi.Array(notification), "unread": openapi.Integer(), "limit": openapi.Integer(), "offset": openapi.Integer(),
+	}))))
+	api(http.MethodPost, "/me/notifications/read-all", authed("notifications", "Đánh dấu đã đọc tất cả", openapi.Operation{}, 200,
+		openapi.OK("ok", openapi.Object(map[string]*openapi.Schema{"ok": openapi.Boolean(), "updated": openapi.Integer()}))))
+	api(http.MethodPost, "/me/notifications/{id}/read", authed("notifications", "Đánh dấu đã đọc", openapi.Operation{
+		Parameters: []openapi.Parameter{openapi.PathParam("id", "notification id", openapi.Integer().Min(1))},
+	}, 200, okResp, 400, 404))
+	api(http.MethodGet, "/me/notification-preferences", authed("notifications", "Kênh nhận notification", openapi.Operation{}, 200, openapi.OK("prefs", prefs)))
+	api(http.MethodPut, "/me/notification-preferences", authed("notifications", "Đổi kênh nhận notification (gửi field muốn đổi)", openapi.Operation{
+		RequestBody: openapi.Body(prefs),
+	}, 200, openapi.OK("prefs", prefs), 400))
+
+	// REVIEWS
+	reviewID := openapi.PathParam("id", "review id", openapi.Integer().Min(1))
+	api(http.MethodPut, "/manga/{id}/review", authed("reviews", "Viết/sửa review", openapi.Operation{
+		Parameters: []openapi.Parameter{idParam},
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{
+			"score":   openapi.Integer().Min(1).Max(10),
+			"body":    openapi.String().Len(0, 5000),
+			"spoiler": openapi.Boolean(),
+		}, "score")),
+	}, 200, okResp, 400, 404))
+	api(http.MethodDelete, "/manga/{id}/review", authed("reviews", "Xoá review của mình", openapi.Operation{
+		Parameters: []openapi.Parameter{idParam},
+	}, 200, okResp, 400, 404))
+	api(http.MethodPost, "/reviews/{id}/helpful", authed("reviews", "Vote review hữu ích", openapi.Operation{
+		Parameters: []openapi.Parameter{reviewID},
+	}, 200, okResp, 400, 404))
+	api(http.MethodDelete, "/reviews/{id}/helpful", authed("reviews", "Bỏ vote", openapi.Operation{
+		Parameters: []openapi.Parameter{reviewID},
+	}, 200, okResp, 400))
+
+	// CHAT
+	room := openapi.PathParam("room", "lobby, manga:<id>, list:<owner>:<name>, dm:<user>", openapi.String().Len(1, 120))
+	api(http.MethodGet, "/chat/{room}/messages", authed("chat", "Lịch sử chat (mới -> cũ)", openapi.Operation{
+		Parameters: []openapi.Parameter{room, before, limit(50, 200)},
+	}, 200, openapi.OK("tin nhắn", openapi.Object(map[string]*openapi.Schema{
+		"room": openapi.String(), "messages": openapi.Array(openapi.Map(nil)), "next_before": openapi.Integer(),
+	})), 400, 403))
+
+	// ADMIN
+	admin := func(summary string, o openapi.Operation, errCodes ...int) *openapi.Operation {
+		return authed("admin", summary, o, 200, okResp, append(errCodes, 403)...)
+	}
+	api(http.MethodPost, "/admin/notify", admin("Broadcast UDP", openapi.Operation{
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{"message": openapi.String().Len(1, 1000)}, "message")),
+	}, 400))
+	api(http.MethodPost, "/admin/manga/{id}/chapters", admin("Phát hành chapter (gửi notification cho follower)", openapi.Operation{
+		Parameters:  []openapi.Parameter{idParam},
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{"chapter": openapi.Integer().Min(1), "title": openapi.String().Len(0, 200)}, "chapter")),
+	}, 400, 404))
+	api(http.MethodPut, "/admin/chat/{room}/retention", admin("Thời gian giữ tin nhắn của room", openapi.Operation{
+		Parameters:  []openapi.Parameter{room},
+		RequestBody: openapi.Body(openapi.Object(map[string]*openapi.Schema{"retention_hours": openapi.Integer().Min(0).Desc("0 = giữ mãi")}, "retention_hours")),
+	}, 400))
+	moderate := openapi.Object(map[string]*openapi.Schema{"reason": openapi.String().Len(0, 500)})
+	api(http.MethodPost, "/admin/reviews/{id}/hide", admin("Ẩn review vi phạm", openapi.Operation{
+		Parameters: []openapi.Parameter{reviewID}, RequestBody: &openapi.RequestBody{Content: openapi.JSON(moderate)},
+	}, 400, 404))
+	api(http.MethodPost, "/admin/reviews/{id}/unhide", admin("Hiện lại review", openapi.Operation{
+		Parameters: []openapi.Parameter{reviewID}, RequestBody: &openapi.RequestBody{Content: openapi.JSON(moderate)},
+	}, 400, 404))
+
+	return d
+}
+
+func codes() []string {
+	return []string{
+		string(apierr.CodeInvalidArgument), string(apierr.CodeValidation), string(apierr.CodeUnauthenticated),
+		string(apierr.CodeForbidden), string(apierr.CodeNotFound), string(apierr.CodeConflict),
+		string(apierr.CodeRateLimited), string(apierr.CodeInternal), string(apierr.CodeUnavailable),
+	}
+}
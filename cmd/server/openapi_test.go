@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mọi route đăng ký trong newRouter phải có operation trong apiSpec
+func TestAPISpecCoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := newRouter(httpDeps{})
+	if missing := apiSpec().Missing(r.Routes(), "/ui"); len(missing) > 0 {
+		t.Errorf("routes missing from openapi spec:")
+		for _, m := range missing {
+			t.Errorf("  %s", m)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/apierr"
+	"mangahub/internal/auth"
+	"mangahub/internal/manga"
+	"mangahub/internal/review"
+)
+
+func handleListReviews(c *gin.Context, db *sql.DB) {
+	mangaID, err := sanitizeMangaID(c.Param("id"))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	sortBy := c.DefaultQuery("sort", "helpful")
+	if sortBy != "helpful" && sortBy != "recent" {
+		apierr.Write(c, apierr.InvalidField("sort", "invalid", "invalid sort, options: helpful, recent"))
+		return
+	}
+	limit := parseInt(c.Query("limit"), 20)
+	offset := parseInt(c.Query("offset"), 0)
+
+	res, err := review.List(db, mangaID, sortBy, limit, offset)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "review"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": res, "limit": limit, "offset": offset, "sort": sortBy})
+}
+
+func handleUpsertReview(c *gin.Context, db *sql.DB) {
+	var req struct {
+		Score   int    `json:"score"`
+		Body    string `json:"body"`
+		Spoiler bool   `json:"spoiler"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Write(c, apierr.Invalid("invalid json"))
+		return
+	}
+	if req.Score < review.MinScore || req.Score > review.MaxScore {
+		apierr.Write(c, apierr.InvalidField("score", "out_of_range", review.ErrInvalidScore.Error()))
+		return
+	}
+	if len(req.Body) > 5000 {
+		apierr.Write(c, apierr.InvalidField("body", "too_long", "review body too long (max 5000)"))
+		return
+	}
+
+	mangaID, err := sanitizeMangaID(c.Param("id"))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	if _, err := manga.GetByID(c.Request.Context(), db, mangaID); err != nil {
+		apierr.Write(c, apierr.DB(err, "manga"))
+		return
+	}
+
+	if err := review.Upsert(db, review.Review{
+		UserID: c.GetString(auth.CtxUserIDKey), MangaID: mangaID, Score: req.Score, Body: req.Body, Spoiler: req.Spoiler,
+	}); err != nil {
+		apierr.Write(c, apierr.DB(err, "review"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func handleDeleteReview(c *gin.Context, db *sql.DB) {
+	mangaID, err := sanitizeMangaID(c.Param("id"))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+
+	if err := review.Delete(db, c.GetString(auth.CtxUserIDKey), mangaID); err != nil {
+		apierr.Write(c, apierr.DB(err, "review"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func handleVoteReview(c *gin.Context, db *sql.DB, helpful bool) {
+	reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierr.Write(c, apierr.InvalidField("id", "invalid", "invalid review id"))
+		return
+	}
+	userID := c.GetString(auth.CtxUserIDKey)
+
+	if !helpful {
+		if err := review.Unvote(db, reviewID, userID); err != nil {
+			apierr.Write(c, apierr.DB(err, "review"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+		return
+	}
+
+	if err := review.Vote(db, reviewID, userID); err != nil {
+		if errors.Is(err, review.ErrOwnReview) {
+			apierr.Write(c, apierr.Invalid(err.Error()).WithReason("own_review"))
+			return
+		}
+		apierr.Write(c, apierr.DB(err, "review"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func handleModerateReview(c *gin.Context, db *sql.DB, hidden bool) {
+	reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		apierr.Write(c, apierr.InvalidField("id", "invalid", "invalid review id"))
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req) // reason không bắt buộc
+
+	if err := review.SetHidden(db, reviewID, hidden, req.Reason, c.GetString(auth.CtxUserIDKey)); err != nil {
+		apierr.Write(c, apierr.DB(err, "review"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "hidden": hidden})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/apierr"
+	"mangahub/internal/auth"
+	"mangahub/internal/social"
+	"mangahub/internal/user"
+)
+
+// POST/DELETE /users/:username/follow
+func handleFollowUser(c *gin.Context, db *sql.DB, follow bool) {
+	username, err := sanitizeUsername(c.Param("username"))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	target, err := user.GetByUsername(c.Request.Context(), db, username)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+	userID := c.GetString(auth.CtxUserIDKey)
+
+	if follow {
+		err = social.Follow(db, userID, target.ID)
+	} else {
+		err = social.Unfollow(db, userID, target.ID)
+	}
+	if errors.Is(err, social.ErrSelfFollow) {
+		apierr.Write(c, apierr.Invalid(err.Error()).WithReason("self_follow"))
+		return
+	}
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "social"))
+		return
+	}
+
+	followers, _, err := social.Counts(db, target.ID)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "social"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "following": follow, "followers": followers})
+}
+
+// GET /users/:username (public; có token thì trả thêm is_following)
+func handleUserProfile(c *gin.Context, db *sql.DB) {
+	username, err := sanitizeUsername(c.Param("username"))
+	if err != nil {
+		apierr.Write(c, err)
+		return
+	}
+	u, err := user.GetByUsername(c.Request.Context(), db, username)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "user"))
+		return
+	}
+
+	p, err := social.GetProfile(db, u.ID, u.Username, u.CreatedAt, c.GetString(auth.CtxUserIDKey))
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "social"))
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// GET /feed?before=<id>&limit= activity của người mình follow (mới -> cũ)
+func handleActivityFeed(c *gin.Context, db *sql.DB) {
+	var before int64
+	if v := c.Query("before"); v != "" {
+		var err error
+		before, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || before < 0 {
+			apierr.Write(c, apierr.InvalidField("before", "invalid", "invalid before cursor"))
+			return
+		}
+	}
+	limit := parseInt(c.Query("limit"), 20)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	items, err := social.Feed(db, c.GetString(auth.CtxUserIDKey), before, limit)
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "social"))
+		return
+	}
+
+	resp := gin.H{"results": items}
+	if len(items) == limit {
+		resp["next_before"] = items[len(items)-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func handleGetPrivacy(c *gin.Context, db *sql.DB) {
+	p, err := social.GetPrivacy(db, c.GetString(auth.CtxUserIDKey))
+	if err != nil {
+		apierr.Write(c, apierr.DB(err, "social"))
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func handleSetPrivacy(c *gin.Context, db *sql.DB) {
+	p := social.DefaultPrivacy()
+	if err := c.ShouldBindJSON(&p); err != nil {
+		apierr.Write(c, apierr.Invalid("invalid body"))
+		return
+	}
+	userID := c.GetString(auth.CtxUserIDKey)
+	if err := social.SetPrivacy(db, userID, p); err != nil {
+		apierr.Write(c, apierr.DB(err, "social"))
+		return
+	}
+	handleGetPrivacy(c, db)
+}
@@ -20,6 +20,12 @@ func main() {
 	defer conn.Close()
 
 	fmt.Println("Connected to TCP sync:", addr)
+
+	// tuỳ chọn: JWT ở tham số thứ 2 để nhận notification riêng của user;
+	// khi đã AUTH chỉ nhận event của chính user và người user follow (feed.Audience)
+	if len(os.Args) > 2 {
+		fmt.Fprintf(conn, "AUTH %s\n", os.Args[2])
+	}
 	fmt.Println("Waiting for progress updates...")
 
 	sc := bufio.NewScanner(conn)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
 )
 
 func main() {
@@ -24,8 +25,9 @@ func main() {
 	}
 	defer conn.Close()
 
-	// Subscribe
-	if _, err := conn.WriteToUDP([]byte("SUBSCRIBE"), serverAddr); err != nil {
+	// Subscribe (các tham số sau server là topic, vd: manga:one-piece)
+	subscribe := strings.Join(append([]string{"SUBSCRIBE"}, os.Args[min(len(os.Args), 2):]...), " ")
+	if _, err := conn.WriteToUDP([]byte(subscribe), serverAddr); err != nil {
 		panic(err)
 	}
 
@@ -0,0 +1,190 @@
+// Package apierr là mô hình lỗi chung cho HTTP và gRPC: mỗi lỗi có Code (nhóm lỗi,
+// quyết định HTTP status / gRPC code), Reason (mã máy đọc được, vd "manga_not_found")
+// và danh sách lỗi theo field khi validate.
+package apierr
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Code là nhóm lỗi; client nên switch theo Code, còn Reason để phân biệt chi tiết
+type Code string
+
+const (
+	CodeInvalidArgument Code = "invalid_argument"  // request sai định dạng / tham số không hợp lệ
+	CodeValidation      Code = "validation_failed" // body đúng định dạng nhưng field không hợp lệ (xem Fields)
+	CodeUnauthenticated Code = "unauthenticated"
+	CodeForbidden       Code = "forbidden"
+	CodeNotFound        Code = "not_found"
+	CodeConflict        Code = "conflict"
+	CodeRateLimited     Code = "rate_limited"
+	CodeTooLarge        Code = "payload_too_large"
+	CodeInternal        Code = "internal"
+	CodeUnavailable     Code = "unavailable"
+)
+
+var httpStatus = map[Code]int{
+	CodeInvalidArgument: http.StatusBadRequest,
+	CodeValidation:      http.StatusBadRequest, // giữ 400 như trước để client cũ không vỡ
+	CodeUnauthenticated: http.StatusUnauthorized,
+	CodeForbidden:       http.StatusForbidden,
+	CodeNotFound:        http.StatusNotFound,
+	CodeConflict:        http.StatusConflict,
+	CodeRateLimited:     http.StatusTooManyRequests,
+	CodeTooLarge:        http.StatusRequestEntityTooLarge,
+	CodeInternal:        http.StatusInternalServerError,
+	CodeUnavailable:     http.StatusServiceUnavailable,
+}
+
+var grpcCode = map[Code]codes.Code{
+	CodeInvalidArgument: codes.InvalidArgument,
+	CodeValidation:      codes.InvalidArgument,
+	CodeUnauthenticated: codes.Unauthenticated,
+	CodeForbidden:       codes.PermissionDenied,
+	CodeNotFound:        codes.NotFound,
+	CodeConflict:        codes.AlreadyExists,
+	CodeRateLimited:     codes.ResourceExhausted,
+	CodeTooLarge:        codes.ResourceExhausted,
+	CodeInternal:        codes.Internal,
+	CodeUnavailable:     codes.Unavailable,
+}
+
+// HTTPStatus của nhóm lỗi (Code lạ => 500)
+func (c Code) HTTPStatus() int {
+	if s, ok := httpStatus[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode của nhóm lỗi (Code lạ => Internal)
+func (c Code) GRPCCode() codes.Code {
+	if s, ok := grpcCode[c]; ok {
+		return s
+	}
+	return codes.Internal
+}
+
+// FieldError là lỗi của 1 field trong body/query (Field theo tên JSON, vd "current_chapter")
+type FieldError struct {
+	Field   string `json:"field"`
+	Reason  string `json:"reason"` // vd "required", "too_long", "out_of_range", "invalid"
+	Message string `json:"message"`
+}
+
+// Error là lỗi trả về client. Err là nguyên nhân nội bộ: chỉ ghi log, không gửi ra ngoài.
+type Error struct {
+	Code    Code
+	Reason  string
+	Message string
+	Fields  []FieldError
+	Meta    map[string]any // thêm vào response (vd retry_after)
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// WithReason gán mã chi tiết, trả về chính e để viết 1 dòng
+func (e *Error) WithReason(reason string) *Error {
+	e.Reason = reason
+	return e
+}
+
+// WithMeta thêm 1 field vào response
+func (e *Error) WithMeta(key string, v any) *Error {
+	if e.Meta == nil {
+		e.Meta = map[string]any{}
+	}
+	e.Meta[key] = v
+	return e
+}
+
+func New(code Code, msg string) *Error {
+	return &Error{Code: code, Message: msg}
+}
+
+// Invalid: tham số/body không hợp lệ (400)
+func Invalid(msg string) *Error {
+	return New(CodeInvalidArgument, msg)
+}
+
+// InvalidField: lỗi validate của đúng 1 field
+func InvalidField(field, reason, msg string) *Error {
+	return Validation(Field(field, reason, msg))
+}
+
+// Validation gộp nhiều lỗi field
+func Validation(fields ...FieldError) *Error {
+	msg := "validation failed"
+	if len(fields) == 1 {
+		msg = fields[0].Message
+	}
+	return &Error{Code: CodeValidation, Message: msg, Fields: fields}
+}
+
+func Field(field, reason, msg string) FieldError {
+	return FieldError{Field: field, Reason: reason, Message: msg}
+}
+
+func Unauthenticated(msg string) *Error {
+	return New(CodeUnauthenticated, msg)
+}
+
+func Forbidden(msg string) *Error {
+	return New(CodeForbidden, msg)
+}
+
+// NotFound("manga") => "manga not found", reason "manga_not_found"
+func NotFound(resource string) *Error {
+	return &Error{Code: CodeNotFound, Reason: resource + "_not_found", Message: resource + " not found"}
+}
+
+func Conflict(msg string) *Error {
+	return New(CodeConflict, msg)
+}
+
+func RateLimited(msg string) *Error {
+	return New(CodeRateLimited, msg)
+}
+
+// TooLarge: body vượt giới hạn (413)
+func TooLarge(limit int64) *Error {
+	return New(CodeTooLarge, "request body too large (max "+strconv.FormatInt(limit, 10)+" bytes)").WithMeta("max_bytes", limit)
+}
+
+// Internal che nguyên nhân (chỉ log) và trả message chung
+func Internal(err error) *Error {
+	return &Error{Code: CodeInternal, Message: "internal error", Err: err}
+}
+
+// DB: lỗi từ database/sql; sql.ErrNoRows => NotFound(resource), còn lại => Internal
+// (trước đây mọi lỗi GetByID đều thành 404 "manga not found").
+func DB(err error, resource string) *Error {
+	if errors.Is(err, sql.ErrNoRows) {
+		e := NotFound(resource)
+		e.Err = err
+		return e
+	}
+	return &Error{Code: CodeInternal, Reason: "db_error", Message: "db error", Err: err}
+}
+
+// From chuyển error bất kỳ thành *Error (lỗi không phân loại => Internal)
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal(err)
+}
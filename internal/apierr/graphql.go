@@ -0,0 +1,41 @@
+package apierr
+
+import (
+	"context"
+	"log/slog"
+
+	"mangahub/internal/logging"
+)
+
+// GraphQL bọc err cho resolver GraphQL: message không kèm nguyên nhân nội bộ, extensions mang
+// code/reason/errors như problem+json (graphql-go đọc qua method Extensions). Lỗi 5xx được log.
+func GraphQL(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	e := From(err)
+	if e.Code.HTTPStatus() >= 500 {
+		logger.ErrorContext(ctx, "graphql resolver failed", slog.String("code", string(e.Code)), logging.Err(err))
+	}
+	return &graphQLError{e}
+}
+
+type graphQLError struct{ e *Error }
+
+func (g *graphQLError) Error() string { return g.e.Message }
+
+func (g *graphQLError) Unwrap() error { return g.e }
+
+func (g *graphQLError) Extensions() map[string]any {
+	ext := map[string]any{"code": g.e.Code, "status": g.e.Code.HTTPStatus()}
+	if g.e.Reason != "" {
+		ext["reason"] = g.e.Reason
+	}
+	if len(g.e.Fields) > 0 {
+		ext["errors"] = g.e.Fields
+	}
+	for k, v := range g.e.Meta {
+		ext[k] = v
+	}
+	return ext
+}
@@ -0,0 +1,35 @@
+package apierr
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain cho errdetails.ErrorInfo
+const errorDomain = "mangahub"
+
+// GRPCStatus cho phép handler gRPC trả thẳng *Error: grpc-go gọi method này để lấy status.
+// Code map như HTTP; Code/Reason nằm trong ErrorInfo, lỗi field nằm trong BadRequest.
+// Nguyên nhân nội bộ (Err) không gửi cho client.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.Code.GRPCCode(), e.Message)
+	info := &errdetails.ErrorInfo{Reason: string(e.Code), Domain: errorDomain}
+	if e.Reason != "" {
+		info.Metadata = map[string]string{"reason": e.Reason}
+	}
+	if withInfo, err := st.WithDetails(info); err == nil {
+		st = withInfo
+	}
+	if len(e.Fields) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, f := range e.Fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field: f.Field, Reason: f.Reason, Description: f.Message,
+			})
+		}
+		if withFields, err := st.WithDetails(br); err == nil {
+			st = withFields
+		}
+	}
+	return st
+}
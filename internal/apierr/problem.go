@@ -0,0 +1,87 @@
+package apierr
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/logging"
+)
+
+var logger = logging.For("http")
+
+// ContentType của RFC 7807
+const ContentType = "application/problem+json"
+
+// TypePrefix + Code là URI "type" của problem (URN, không cần trang tài liệu)
+const TypePrefix = "urn:mangahub:problem:"
+
+// Problem là body RFC 7807 (problem+json) kèm các member mở rộng của MangaHub
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	Reason    string       `json:"reason,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	// Error lặp lại Detail cho client cũ đọc {"error": "..."}
+	Error string         `json:"error"`
+	Meta  map[string]any `json:"-"`
+}
+
+// MarshalJSON đưa Meta lên cùng cấp (RFC 7807 extension members)
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	b, err := json.Marshal(plain(p))
+	if err != nil || len(p.Meta) == 0 {
+		return b, err
+	}
+	m := map[string]any{}
+	for k, v := range p.Meta {
+		m[k] = v
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		m[k] = v
+	}
+	return json.Marshal(m)
+}
+
+// ProblemFor dựng Problem từ error (instance = path của request)
+func ProblemFor(err error, instance, requestID string) Problem {
+	e := From(err)
+	status := e.Code.HTTPStatus()
+	return Problem{
+		Type:      TypePrefix + string(e.Code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      e.Code,
+		Reason:    e.Reason,
+		Errors:    e.Fields,
+		RequestID: requestID,
+		Error:     e.Message,
+		Meta:      e.Meta,
+	}
+}
+
+// Write trả lỗi dạng problem+json và abort chain; lỗi 5xx được log kèm nguyên nhân.
+func Write(c *gin.Context, err error) {
+	ctx := c.Request.Context()
+	p := ProblemFor(err, c.Request.URL.Path, logging.RequestID(ctx))
+	if p.Status >= 500 {
+		logger.ErrorContext(ctx, "request failed", slog.String("code", string(p.Code)), logging.Err(err))
+	}
+	_ = c.Error(err)
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
@@ -9,13 +9,15 @@ import (
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func SignJWT(secret []byte, userID, username string, ttl time.Duration) (string, error) {
+func SignJWT(secret []byte, userID, username, role string, ttl time.Duration) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -36,5 +38,10 @@ func ParseJWT(secret []byte, tokenStr string) (*Claims, error) {
 	if !ok || !tok.Valid {
 		return nil, jwt.ErrTokenInvalidClaims
 	}
+	if sessionCheck != nil {
+		if err := sessionCheck(claims); err != nil {
+			return nil, err
+		}
+	}
 	return claims, nil
 }
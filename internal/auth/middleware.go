@@ -1,30 +1,68 @@
 package auth
 
 import (
-	"net/http"
+	"errors"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/apierr"
 )
 
 const CtxUserIDKey = "user_id"
 const CtxUsernameKey = "username"
+const CtxRoleKey = "role"
+
+const RoleAdmin = "admin"
+const RoleModerator = "moderator"
 
 func RequireJWT(secret []byte) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
 		if h == "" || !strings.HasPrefix(h, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			apierr.Write(c, apierr.Unauthenticated("missing bearer token").WithReason("missing_token"))
 			return
 		}
 		tokenStr := strings.TrimPrefix(h, "Bearer ")
 		claims, err := ParseJWT(secret, tokenStr)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			reason := "invalid_token"
+			if errors.Is(err, ErrSessionRevoked) {
+				reason = "session_revoked"
+			}
+			apierr.Write(c, apierr.Unauthenticated("invalid token").WithReason(reason))
 			return
 		}
 		c.Set(CtxUserIDKey, claims.UserID)
 		c.Set(CtxUsernameKey, claims.Username)
+		c.Set(CtxRoleKey, claims.Role)
+		c.Next()
+	}
+}
+
+// OptionalJWT gắn user vào context nếu có token hợp lệ, không có token vẫn cho qua
+// (dùng cho route public nhưng trả thêm thông tin khi đã login)
+func OptionalJWT(secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.GetHeader("Authorization")
+		if strings.HasPrefix(h, "Bearer ") {
+			if claims, err := ParseJWT(secret, strings.TrimPrefix(h, "Bearer ")); err == nil {
+				c.Set(CtxUserIDKey, claims.UserID)
+				c.Set(CtxUsernameKey, claims.Username)
+				c.Set(CtxRoleKey, claims.Role)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireRole phải đặt sau RequireJWT
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(CtxRoleKey) != role {
+			apierr.Write(c, apierr.Forbidden("forbidden").WithReason("role_required"))
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,14 @@
+package auth
+
+import "errors"
+
+var ErrSessionRevoked = errors.New("session revoked")
+
+// sessionCheck chạy sau khi chữ ký/expiry hợp lệ, dùng để thu hồi token
+// (user bị xoá, đổi/reset password). nil = chỉ kiểm tra chữ ký.
+var sessionCheck func(*Claims) error
+
+// UseSessionCheck đăng ký hàm kiểm tra session cho mọi nơi gọi ParseJWT (HTTP, WS, TCP)
+func UseSessionCheck(fn func(*Claims) error) {
+	sessionCheck = fn
+}
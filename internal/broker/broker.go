@@ -0,0 +1,95 @@
+package broker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+
+	"mangahub/internal/logging"
+	"mangahub/internal/metrics"
+)
+
+var logger = logging.For("broker")
+
+// Broker là pub/sub giữa các instance server (chat, TCP sync, UDP, feed).
+// Handler được gọi tuần tự theo thứ tự publish trên từng subscription.
+type Broker interface {
+	Publish(subject string, data []byte) error
+	Subscribe(subject string, handler func(data []byte)) (unsubscribe func(), err error)
+	Close() error
+}
+
+// message bọc payload kèm node gửi để node đó bỏ qua bản sao của chính mình
+type message struct {
+	Node string          `json:"node"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Fanout nối 1 subject với hàm deliver local của 1 server:
+// server tự deliver cho client local rồi gọi Publish để các node khác deliver tiếp.
+type Fanout struct {
+	b       Broker
+	subject string
+	node    string
+	out     chan []byte
+}
+
+// NewFanout subscribe `subject`; deliver được gọi với payload do node khác publish
+func NewFanout(b Broker, subject string, deliver func(data []byte)) (*Fanout, error) {
+	f := &Fanout{b: b, subject: subject, node: newNodeID(), out: make(chan []byte, 1024)}
+	_, err := b.Subscribe(subject, func(raw []byte) {
+		var m message
+		if err := json.Unmarshal(raw, &m); err != nil {
+			logger.Warn("bad message", slog.String("subject", subject), logging.Err(err))
+			return
+		}
+		if m.Node == f.node {
+			return
+		}
+		deliver(m.Data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	go f.loop()
+	return f, nil
+}
+
+// Publish không block caller (có thể đang giữ lock); buffer đầy thì bỏ message.
+// Fanout nil (server chưa gắn broker) => không làm gì.
+func (f *Fanout) Publish(v any) {
+	if f == nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		logger.Error("marshal failed", slog.String("subject", f.subject), logging.Err(err))
+		return
+	}
+	raw, err := json.Marshal(message{Node: f.node, Data: data})
+	if err != nil {
+		logger.Error("marshal failed", slog.String("subject", f.subject), logging.Err(err))
+		return
+	}
+	select {
+	case f.out <- raw:
+	default:
+		logger.Warn("outbound queue full, drop message", slog.String("subject", f.subject))
+		metrics.Dropped(metrics.DropBrokerOutbound)
+	}
+}
+
+func (f *Fanout) loop() {
+	for raw := range f.out {
+		if err := f.b.Publish(f.subject, raw); err != nil {
+			logger.Warn("publish failed", slog.String("subject", f.subject), logging.Err(err))
+		}
+	}
+}
+
+func newNodeID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
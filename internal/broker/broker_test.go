@@ -0,0 +1,136 @@
+package broker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// mỗi backend trả về 2 broker đóng vai 2 instance server nối chung 1 hạ tầng
+var backends = map[string]func(t *testing.T) (Broker, Broker){
+	"memory": func(t *testing.T) (Broker, Broker) {
+		m := NewMemory()
+		t.Cleanup(func() { m.Close() })
+		return m, m
+	},
+	"redis": func(t *testing.T) (Broker, Broker) {
+		srv := miniredis.RunT(t)
+		a, err := NewRedis("redis://" + srv.Addr())
+		if err != nil {
+			t.Fatalf("NewRedis: %v", err)
+		}
+		b, err := NewRedis("redis://" + srv.Addr())
+		if err != nil {
+			t.Fatalf("NewRedis: %v", err)
+		}
+		t.Cleanup(func() { a.Close(); b.Close() })
+		return a, b
+	},
+}
+
+type event struct {
+	Seq  int    `json:"seq"`
+	Room string `json:"room"`
+}
+
+func collect(ch chan event) func(data []byte) {
+	return func(data []byte) {
+		var e event
+		if err := json.Unmarshal(data, &e); err != nil {
+			panic(err)
+		}
+		ch <- e
+	}
+}
+
+func TestFanout(t *testing.T) {
+	for name, newPair := range backends {
+		t.Run(name, func(t *testing.T) {
+			ba, bb := newPair(t)
+			gotA, gotB := make(chan event, 16), make(chan event, 16)
+			fa, err := NewFanout(ba, "chat", collect(gotA))
+			if err != nil {
+				t.Fatalf("NewFanout: %v", err)
+			}
+			fb, err := NewFanout(bb, "chat", collect(gotB))
+			if err != nil {
+				t.Fatalf("NewFanout: %v", err)
+			}
+
+			// node khác nhận đủ và đúng thứ tự publish
+			for i := 1; i <= 5; i++ {
+				fa.Publish(event{Seq: i, Room: "lobby"})
+			}
+			for i := 1; i <= 5; i++ {
+				select {
+				case e := <-gotB:
+					if e.Seq != i || e.Room != "lobby" {
+						t.Fatalf("message %d: got %+v", i, e)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("timeout waiting for message %d", i)
+				}
+			}
+
+			// chiều ngược lại
+			fb.Publish(event{Seq: 99})
+			select {
+			case e := <-gotA:
+				if e.Seq != 99 {
+					t.Fatalf("got %+v", e)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for reverse message")
+			}
+
+			// node gửi không nhận lại bản sao của chính mình
+			select {
+			case e := <-gotA:
+				t.Fatalf("publisher received its own message %+v", e)
+			case e := <-gotB:
+				t.Fatalf("publisher received its own message %+v", e)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestSubscribeIsolation(t *testing.T) {
+	for name, newPair := range backends {
+		t.Run(name, func(t *testing.T) {
+			ba, bb := newPair(t)
+			chat, feed := make(chan []byte, 4), make(chan []byte, 4)
+			unsub, err := bb.Subscribe("chat", func(d []byte) { chat <- d })
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			if _, err := bb.Subscribe("feed", func(d []byte) { feed <- d }); err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+
+			if err := ba.Publish("feed", []byte("f1")); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			select {
+			case d := <-feed:
+				if string(d) != "f1" {
+					t.Fatalf("got %q", d)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for feed message")
+			}
+
+			unsub()
+			if err := ba.Publish("chat", []byte("c1")); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			select {
+			case d := <-chat:
+				t.Fatalf("unsubscribed handler received %q", d)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
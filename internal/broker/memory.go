@@ -0,0 +1,99 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"mangahub/internal/health"
+	"mangahub/internal/metrics"
+)
+
+var ErrClosed = errors.New("broker closed")
+
+// Memory là broker trong process: mặc định khi chạy 1 instance, hoặc để chạy
+// nhiều hub/server trong cùng process khi thử nghiệm
+type Memory struct {
+	mu     sync.Mutex
+	subs   map[string]map[*memorySub]struct{}
+	closed bool
+}
+
+type memorySub struct {
+	ch chan []byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{subs: make(map[string]map[*memorySub]struct{})}
+}
+
+func (m *Memory) Publish(subject string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	for s := range m.subs[subject] {
+		select {
+		case s.ch <- data:
+		default:
+			logger.Warn("memory subscriber queue full, drop message", slog.String("subject", subject))
+			metrics.Dropped(metrics.DropBrokerMemory)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(subject string, handler func(data []byte)) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	s := &memorySub{ch: make(chan []byte, 256)}
+	if m.subs[subject] == nil {
+		m.subs[subject] = make(map[*memorySub]struct{})
+	}
+	m.subs[subject][s] = struct{}{}
+
+	go func() {
+		for data := range s.ch {
+			handler(data)
+		}
+	}()
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.subs[subject][s]; ok {
+			delete(m.subs[subject], s)
+			close(s.ch)
+		}
+	}, nil
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	for _, subs := range m.subs {
+		for s := range subs {
+			close(s.ch)
+		}
+	}
+	m.subs = nil
+	return nil
+}
+
+func (m *Memory) Health(ctx context.Context) health.Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return health.Down(ErrClosed.Error(), map[string]any{"kind": "memory"})
+	}
+	return health.Up(map[string]any{"kind": "memory"})
+}
@@ -0,0 +1,63 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+
+	"mangahub/internal/health"
+)
+
+// Redis dùng Redis pub/sub làm broker giữa các instance.
+// Khi thử nghiệm có thể trỏ tới redis-server local hoặc miniredis.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis nhận URL dạng redis://[:password@]host:port/db; subject được gắn prefix "mangahub."
+func NewRedis(url string) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &Redis{client: client, prefix: "mangahub."}, nil
+}
+
+func (r *Redis) Publish(subject string, data []byte) error {
+	return r.client.Publish(context.Background(), r.prefix+subject, data).Err()
+}
+
+func (r *Redis) Subscribe(subject string, handler func(data []byte)) (func(), error) {
+	ctx := context.Background()
+	ps := r.client.Subscribe(ctx, r.prefix+subject)
+	// chờ Redis xác nhận subscribe để không mất message publish ngay sau đó
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	go func() {
+		for msg := range ps.Channel() {
+			handler([]byte(msg.Payload))
+		}
+	}()
+	return func() { ps.Close() }, nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+// Health ping Redis; mất kết nối => các instance không còn đồng bộ chat/feed
+func (r *Redis) Health(ctx context.Context) health.Report {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return health.Down(err.Error(), map[string]any{"kind": "redis"})
+	}
+	return health.Up(map[string]any{"kind": "redis"})
+}
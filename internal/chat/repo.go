@@ -0,0 +1,259 @@
+package chat
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	"mangahub/internal/logging"
+	"mangahub/pkg/models"
+)
+
+var logger = logging.For("chat")
+
+// Retention mặc định theo loại room (phần trước dấu ':'), 0 = giữ vĩnh viễn.
+// Admin có thể override cho từng room (bảng chat_room_settings).
+var DefaultRetention = map[string]time.Duration{
+	"lobby": 7 * 24 * time.Hour,
+	"manga": 90 * 24 * time.Hour,
+	"list":  90 * 24 * time.Hour,
+	"dm":    0,
+}
+
+// Store lưu lịch sử chat vào SQLite (implement websocket.MessageStore)
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Save ghi message, gán ID và timestamp phía server
+func (s *Store) Save(m *models.ChatMessage) error {
+	now := time.Now().UTC()
+	res, err := s.db.Exec(`INSERT INTO chat_messages(room, user_id, username, type, message, spoiler, created_at) VALUES(?,?,?,?,?,?,?)`,
+		m.Room, m.UserID, m.Username, m.Type, m.Message, m.Spoiler, now.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return err
+	}
+	m.ID, err = res.LastInsertId()
+	m.Timestamp = now.Unix()
+	return err
+}
+
+// Recent trả về `limit` message mới nhất của room, theo thứ tự cũ -> mới
+func (s *Store) Recent(room string, limit int) ([]models.ChatMessage, error) {
+	msgs, err := s.Before(room, 0, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, nil
+}
+
+// Before phân trang theo cursor: message có id < beforeID (0 = mới nhất), mới -> cũ
+func (s *Store) Before(room string, beforeID int64, limit int) ([]models.ChatMessage, error) {
+	q := `SELECT id, room, user_id, username, type, message, COALESCE(spoiler,0), created_at FROM chat_messages WHERE room=? AND deleted_at IS NULL`
+	args := []any{room}
+	if beforeID > 0 {
+		q += " AND id < ?"
+		args = append(args, beforeID)
+	}
+	q += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []models.ChatMessage{}
+	for rows.Next() {
+		var m models.ChatMessage
+		var createdAt time.Time
+		if err := rows.Scan(&m.ID, &m.Room, &m.UserID, &m.Username, &m.Type, &m.Message, &m.Spoiler, &createdAt); err != nil {
+			return nil, err
+		}
+		m.Timestamp = createdAt.Unix()
+		res = append(res, m)
+	}
+	return res, rows.Err()
+}
+
+// Delete đánh dấu message đã bị xoá, trả về room của message
+func (s *Store) Delete(id int64) (string, error) {
+	var room string
+	err := s.db.QueryRow(`SELECT room FROM chat_messages WHERE id=? AND deleted_at IS NULL`, id).Scan(&room)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.db.Exec(`UPDATE chat_messages SET deleted_at=? WHERE id=?`,
+		time.Now().UTC().Format("2006-01-02 15:04:05"), id)
+	return room, err
+}
+
+// HasMessage: id là message chưa bị xoá của room
+func (s *Store) HasMessage(room string, id int64) (bool, error) {
+	var ok bool
+	err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM chat_messages WHERE id=? AND room=? AND deleted_at IS NULL)`, id, room).Scan(&ok)
+	return ok, err
+}
+
+// MarkRead ghi read receipt; chỉ tiến lên, không lùi về message cũ hơn
+func (s *Store) MarkRead(room, userID string, messageID int64) error {
+	_, err := s.db.Exec(`
+	INSERT INTO chat_read_receipts(room, user_id, last_read_id, updated_at) VALUES(?,?,?,CURRENT_TIMESTAMP)
+	ON CONFLICT(room, user_id) DO UPDATE SET
+		last_read_id=MAX(last_read_id, excluded.last_read_id), updated_at=excluded.updated_at`, room, userID, messageID)
+	return err
+}
+
+// Receipts trả về user_id -> id message cuối đã đọc trong room
+func (s *Store) Receipts(room string) (map[string]int64, error) {
+	rows, err := s.db.Query(`SELECT user_id, last_read_id FROM chat_read_receipts WHERE room=?`, room)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[string]int64{}
+	for rows.Next() {
+		var userID string
+		var id int64
+		if err := rows.Scan(&userID, &id); err != nil {
+			return nil, err
+		}
+		res[userID] = id
+	}
+	return res, rows.Err()
+}
+
+// SetSanction ghi mute/ban (kind) của user; until zero = vĩnh viễn
+func (s *Store) SetSanction(kind, userID string, until time.Time, by, reason string) error {
+	var u any
+	if !until.IsZero() {
+		u = until.UTC().Format("2006-01-02 15:04:05")
+	}
+	_, err := s.db.Exec(`
+	INSERT INTO chat_sanctions(user_id, kind, until, created_by, reason) VALUES(?,?,?,?,?)
+	ON CONFLICT(user_id, kind) DO UPDATE SET
+		until=excluded.until, created_by=excluded.created_by, reason=excluded.reason, created_at=CURRENT_TIMESTAMP`,
+		userID, kind, u, by, reason)
+	return err
+}
+
+// ClearSanction gỡ mute/ban (kind) của user
+func (s *Store) ClearSanction(kind, userID string) error {
+	_, err := s.db.Exec(`DELETE FROM chat_sanctions WHERE user_id=? AND kind=?`, userID, kind)
+	return err
+}
+
+// ActiveSanctions trả về user_id -> hết hạn (zero = vĩnh viễn) của các mute/ban còn hiệu lực
+func (s *Store) ActiveSanctions(kind string) (map[string]time.Time, error) {
+	rows, err := s.db.Query(`SELECT user_id, until FROM chat_sanctions WHERE kind=? AND (until IS NULL OR until > ?)`,
+		kind, time.Now().UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[string]time.Time{}
+	for rows.Next() {
+		var userID string
+		var until sql.NullTime
+		if err := rows.Scan(&userID, &until); err != nil {
+			return nil, err
+		}
+		res[userID] = until.Time
+	}
+	return res, rows.Err()
+}
+
+// Retention của room: override trong DB, nếu không có thì theo loại room
+func (s *Store) Retention(room string) (time.Duration, error) {
+	var hours int
+	err := s.db.QueryRow(`SELECT retention_hours FROM chat_room_settings WHERE room=?`, room).Scan(&hours)
+	if err == nil {
+		return time.Duration(hours) * time.Hour, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	kind, _, _ := strings.Cut(room, ":")
+	return DefaultRetention[kind], nil
+}
+
+// SetRetention override retention cho 1 room (0 = giữ vĩnh viễn)
+func (s *Store) SetRetention(room string, d time.Duration) error {
+	_, err := s.db.Exec(`
+	INSERT INTO chat_room_settings(room, retention_hours) VALUES(?,?)
+	ON CONFLICT(room) DO UPDATE SET retention_hours=excluded.retention_hours`, room, int(d/time.Hour))
+	return err
+}
+
+// Prune xoá message quá hạn retention của từng room
+func (s *Store) Prune() (int64, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT room FROM chat_messages`)
+	if err != nil {
+		return 0, err
+	}
+	var rooms []string
+	for rows.Next() {
+		var r string
+		if err := rows.Scan(&r); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		rooms = append(rooms, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, room := range rooms {
+		ret, err := s.Retention(room)
+		if err != nil {
+			return total, err
+		}
+		if ret <= 0 {
+			continue
+		}
+		cutoff := time.Now().UTC().Add(-ret).Format("2006-01-02 15:04:05")
+		res, err := s.db.Exec(`DELETE FROM chat_messages WHERE room=? AND created_at < ?`, room, cutoff)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+
+	// mute/ban đã hết hạn không cần giữ
+	if _, err := s.db.Exec(`DELETE FROM chat_sanctions WHERE until IS NOT NULL AND until <= ?`,
+		time.Now().UTC().Format("2006-01-02 15:04:05")); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// RunPruner gọi Prune định kỳ (chạy trong goroutine)
+func (s *Store) RunPruner(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		n, err := s.Prune()
+		if err != nil {
+			logger.Error("prune failed", logging.Err(err))
+			continue
+		}
+		if n > 0 {
+			logger.Info("pruned expired messages", slog.Int64("count", n))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package chat
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mangahub/pkg/database"
+	"mangahub/pkg/models"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return NewStore(db)
+}
+
+// mute/ban còn hiệu lực được nạp lại (vĩnh viễn => until zero), hết hạn hoặc đã gỡ thì không
+func TestSanctions(t *testing.T) {
+	s := newTestStore(t)
+	future := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	for _, st := range []struct {
+		kind, user string
+		until      time.Time
+	}{
+		{"ban", "forever", time.Time{}},
+		{"ban", "later", future},
+		{"ban", "expired", time.Now().Add(-time.Minute)},
+		{"ban", "lifted", time.Time{}},
+		{"mute", "muted", future},
+	} {
+		if err := s.SetSanction(st.kind, st.user, st.until, "mod", "spam"); err != nil {
+			t.Fatalf("SetSanction(%s, %s): %v", st.kind, st.user, err)
+		}
+	}
+	if err := s.ClearSanction("ban", "lifted"); err != nil {
+		t.Fatalf("ClearSanction: %v", err)
+	}
+
+	bans, err := s.ActiveSanctions("ban")
+	if err != nil {
+		t.Fatalf("ActiveSanctions: %v", err)
+	}
+	if len(bans) != 2 {
+		t.Fatalf("bans = %v, want forever + later", bans)
+	}
+	if until, ok := bans["forever"]; !ok || !until.IsZero() {
+		t.Errorf("forever = %v, %v; want zero time", until, ok)
+	}
+	if until := bans["later"]; !until.Equal(future) {
+		t.Errorf("later = %v, want %v", until, future)
+	}
+
+	mutes, err := s.ActiveSanctions("mute")
+	if err != nil {
+		t.Fatalf("ActiveSanctions: %v", err)
+	}
+	if len(mutes) != 1 || !mutes["muted"].Equal(future) {
+		t.Errorf("mutes = %v", mutes)
+	}
+
+	// ban lại thay thế hạn cũ
+	if err := s.SetSanction("ban", "later", time.Time{}, "mod", ""); err != nil {
+		t.Fatalf("SetSanction: %v", err)
+	}
+	bans, _ = s.ActiveSanctions("ban")
+	if !bans["later"].IsZero() {
+		t.Errorf("re-ban: later = %v, want permanent", bans["later"])
+	}
+
+	if _, err := s.Prune(); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	var n int
+	s.db.QueryRow(`SELECT COUNT(*) FROM chat_sanctions`).Scan(&n)
+	if n != 3 {
+		t.Errorf("rows after prune = %d, want 3", n)
+	}
+}
+
+func TestHasMessage(t *testing.T) {
+	s := newTestStore(t)
+	msg := &models.ChatMessage{Room: "manga:one-piece", UserID: "u1", Username: "u1", Type: "chat", Message: "hi"}
+	if err := s.Save(msg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	deleted := &models.ChatMessage{Room: "manga:one-piece", UserID: "u1", Username: "u1", Type: "chat", Message: "oops"}
+	if err := s.Save(deleted); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := s.Delete(deleted.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	for _, tt := range []struct {
+		room string
+		id   int64
+		want bool
+	}{
+		{"manga:one-piece", msg.ID, true},
+		{"lobby", msg.ID, false},
+		{"manga:one-piece", deleted.ID, false},
+		{"manga:one-piece", 1 << 40, false},
+	} {
+		got, err := s.HasMessage(tt.room, tt.id)
+		if err != nil {
+			t.Fatalf("HasMessage: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("HasMessage(%s, %d) = %v, want %v", tt.room, tt.id, got, tt.want)
+		}
+	}
+}
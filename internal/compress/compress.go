@@ -0,0 +1,204 @@
+// Package compress nén response HTTP bằng brotli hoặc gzip theo Accept-Encoding của client.
+// Body nhỏ hơn MinSize, content type không nén được (ảnh...), 304/204 và WebSocket upgrade
+// được trả nguyên.
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// MinSize: body nhỏ hơn thì nén không đáng (header gzip/brotli + CPU)
+const MinSize = 1024
+
+const (
+	Brotli = "br"
+	Gzip   = "gzip"
+)
+
+var (
+	gzipPool = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	// level 5: tỉ lệ nén gần mức mặc định (11) nhưng nhanh hơn nhiều, hợp cho response động
+	brotliPool = sync.Pool{New: func() any { return brotli.NewWriterLevel(io.Discard, 5) }}
+)
+
+// Negotiate chọn encoding từ Accept-Encoding (có q-value); cùng q thì ưu tiên br. "" = không nén.
+// q=0 nghĩa là từ chối encoding đó; "*" chỉ áp cho encoding không được liệt kê riêng.
+func Negotiate(acceptEncoding string) string {
+	listed := map[string]float64{}
+	star := -1.0 // < 0: không có "*"
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q, ok := qValue(params)
+		if !ok {
+			continue
+		}
+		if name == "*" {
+			star = q
+		} else {
+			listed[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range []string{Brotli, Gzip} { // br trước => thắng khi cùng q
+		q, ok := listed[enc]
+		if !ok {
+			q = star
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// qValue đọc q trong phần params ("q=0.5", có thể kèm param khác); không có q => 1
+func qValue(params string) (float64, bool) {
+	for _, p := range strings.Split(params, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(k), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0, false
+		}
+		return q, true
+	}
+	return 1, true
+}
+
+// compressible: chỉ nén text (JSON, HTML, JS, CSS, problem+json...)
+func compressible(contentType string) bool {
+	ct, _, _ := strings.Cut(contentType, ";")
+	ct = strings.TrimSpace(strings.ToLower(ct))
+	return strings.HasPrefix(ct, "text/") ||
+		strings.HasSuffix(ct, "json") ||
+		strings.HasSuffix(ct, "javascript") ||
+		strings.HasSuffix(ct, "xml") ||
+		ct == "image/svg+xml"
+}
+
+// Middleware nén response theo Accept-Encoding, đặt Vary: Accept-Encoding cho response nén được
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+		w := &writer{ResponseWriter: c.Writer, enc: Negotiate(c.GetHeader("Accept-Encoding"))}
+		c.Writer = w
+		defer w.close()
+		c.Next()
+	}
+}
+
+// writer giữ body trong buf cho tới khi đủ MinSize (hoặc handler xong) rồi mới quyết định nén
+type writer struct {
+	gin.ResponseWriter
+	enc     string
+	buf     []byte
+	decided bool
+	out     io.Writer // ResponseWriter gốc hoặc encoder
+	closer  func()
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < MinSize {
+			return len(b), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return w.out.Write(b)
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// decide chọn nén hay không theo status/header/kích thước rồi xả buf
+func (w *writer) decide() error {
+	w.decided = true
+	w.out = w.ResponseWriter
+	h := w.Header()
+	status := w.Status()
+	// header đã gửi (WriteHeaderNow) thì không đổi Content-Encoding được nữa
+	eligible := !w.ResponseWriter.Written() && status >= http.StatusOK && status != http.StatusNoContent &&
+		status != http.StatusNotModified && status != http.StatusPartialContent &&
+		h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type"))
+	// 304 mang cùng Vary với 200 tương ứng để cache giữ đúng biến thể
+	if eligible || status == http.StatusNotModified {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if eligible && w.enc != "" && len(w.buf) >= MinSize {
+		h.Set("Content-Encoding", w.enc)
+		h.Del("Content-Length")
+		switch w.enc {
+		case Brotli:
+			bw := brotliPool.Get().(*brotli.Writer)
+			bw.Reset(w.ResponseWriter)
+			w.out, w.closer = bw, func() { _ = bw.Close(); brotliPool.Put(bw) }
+		case Gzip:
+			gw := gzipPool.Get().(*gzip.Writer)
+			gw.Reset(w.ResponseWriter)
+			w.out, w.closer = gw, func() { _ = gw.Close(); gzipPool.Put(gw) }
+		}
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(buf)
+	return err
+}
+
+// close chạy sau khi handler xong: body nhỏ được quyết định ở đây, encoder được flush
+func (w *writer) close() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if w.closer != nil {
+		w.closer()
+	}
+}
+
+// Flush: handler stream (vd SSE) thì quyết định ngay và đẩy dữ liệu đã nén xuống client
+func (w *writer) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if f, ok := w.out.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Hijack dùng được khi chưa ghi gì (WebSocket đã bị loại từ đầu nên hiếm khi tới đây)
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	w.out = w.ResponseWriter
+	return w.ResponseWriter.Hijack()
+}
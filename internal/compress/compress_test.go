@@ -0,0 +1,43 @@
+package compress
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", Gzip},
+		{"br", Brotli},
+		{"gzip, br", Brotli},
+		{"GZIP", Gzip},
+		{"gzip, deflate, br, zstd", Brotli},
+		{"gzip;q=1.0, br;q=0.5", Gzip},
+		{"br;q=0.8, gzip;q=0.8", Brotli},
+		// q=0 là từ chối
+		{"br;q=0", ""},
+		{"br;q=0, gzip", Gzip},
+		{"gzip;q=0, br;q=0", ""},
+		{"br;q=0.000", ""},
+		// "*" chỉ áp cho encoding không liệt kê riêng
+		{"*", Brotli},
+		{"*;q=0", ""},
+		{"br;q=0, *", Gzip},
+		{"*, br;q=0", Gzip},
+		{"gzip;q=0, *;q=0.5", Brotli},
+		{"*;q=0, gzip", Gzip},
+		{"br;q=0.2, *;q=0.5", Gzip},
+		// q lỗi => bỏ phần tử đó
+		{"br;q=abc, gzip", Gzip},
+		{"br;q=2, gzip;q=0.1", Gzip},
+		{"br;level=1;q=0.3, gzip;q=0.2", Brotli},
+		{" gzip ; q=0.5 , br ; q=0.4 ", Gzip},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package feed
+
+// Audience là rule lọc event dùng chung cho TCP sync và /ws/feed:
+//   - notification: chỉ người nhận
+//   - progress, library: của chính user hoặc người user đang follow
+//   - kết nối không xác thực (UserID rỗng): chỉ progress (stream công khai như TCP sync cũ)
+//   - activity Private (activity_public=false, list private) chỉ chính user thấy, như social.Feed
+type Audience struct {
+	UserID    string
+	Following map[string]bool
+}
+
+func (a Audience) Allows(e Event) bool {
+	if a.UserID == "" {
+		return e.Type == TypeProgress && !e.Private
+	}
+	switch e.Type {
+	case TypeNotification:
+		return e.UserID == a.UserID
+	case TypeProgress, TypeLibrary:
+		return e.UserID == a.UserID || (a.Following[e.UserID] && !e.Private)
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"mangahub/internal/broker"
+	"mangahub/internal/health"
+	"mangahub/internal/logging"
+	"mangahub/internal/metrics"
+	"mangahub/internal/tracing"
+	"mangahub/pkg/models"
+)
+
+var (
+	logger = logging.For("feed")
+	tracer = tracing.Tracer("mangahub/internal/feed")
+)
+
+// Loại event trong feed
+const (
+	TypeProgress     = "progress"
+	TypeLibrary      = "library"
+	TypeNotification = "notification"
+)
+
+// Event có số thứ tự tăng dần (Seq) để client resume sau khi reconnect
+type Event struct {
+	Seq       uint64 `json:"seq"`
+	Type      string `json:"type"`
+	UserID    string `json:"user_id"` // người thực hiện (progress/library) hoặc người nhận (notification)
+	Payload   any    `json:"payload"`
+	Timestamp int64  `json:"timestamp"`
+	RequestID string `json:"request_id,omitempty"` // request HTTP/gRPC sinh ra event (để trace qua log)
+	// Private: activity không công khai (activity_public=false hoặc list trong list_privacy),
+	// chỉ chính user thấy
+	Private bool `json:"-"`
+	// trace context của span publish; nơi deliver (TCP, /ws/feed) tạo span con từ đây
+	Trace tracing.Carrier `json:"-"`
+}
+
+// FollowingFunc trả về danh sách user_id mà userID đang follow
+type FollowingFunc func(userID string) ([]string, error)
+
+// PrivacyFunc cho biết activity của userID trên reading list listName có bị ẩn không
+// (cùng rule với social.Feed)
+type PrivacyFunc func(userID, listName string) (bool, error)
+
+// Bus là nguồn event chung cho TCP sync và /ws/feed: giữ `size` event gần nhất
+// trong ring buffer và fan-out tới các subscription
+type Bus struct {
+	mu        sync.Mutex
+	seq       uint64
+	buf       []Event // ring buffer
+	next      int     // vị trí ghi tiếp theo
+	full      bool
+	subs      map[*Subscription]struct{}
+	following FollowingFunc
+	privacy   PrivacyFunc
+	fan       *broker.Fanout
+}
+
+func NewBus(size int) *Bus {
+	if size <= 0 {
+		size = 1024
+	}
+	return &Bus{buf: make([]Event, size), subs: make(map[*Subscription]struct{})}
+}
+
+// SetFollowing cấu hình nguồn quan hệ follow giữa user (dùng cho Audience)
+func (b *Bus) SetFollowing(fn FollowingFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.following = fn
+}
+
+// SetPrivacy cấu hình rule ẩn activity (progress/library) với người khác
+func (b *Bus) SetPrivacy(fn PrivacyFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.privacy = fn
+}
+
+// private tính cờ Private lúc publish; lỗi đọc privacy => ẩn cho chắc
+func (b *Bus) private(typ, userID string, payload any) bool {
+	if typ != TypeProgress && typ != TypeLibrary {
+		return false
+	}
+	b.mu.Lock()
+	fn := b.privacy
+	b.mu.Unlock()
+	if fn == nil {
+		return false
+	}
+	hidden, err := fn(userID, listOf(payload))
+	if err != nil {
+		logger.Warn("load privacy failed", slog.String("user_id", userID), logging.Err(err))
+		return true
+	}
+	return hidden
+}
+
+// listOf lấy list_name của payload progress/library ("default" nếu không có)
+func listOf(payload any) string {
+	var p struct {
+		ListName string `json:"list_name"`
+	}
+	switch v := payload.(type) {
+	case models.ProgressUpdate:
+		p.ListName = v.ListName
+	default:
+		if raw, err := json.Marshal(v); err == nil {
+			json.Unmarshal(raw, &p)
+		}
+	}
+	if p.ListName == "" {
+		return "default"
+	}
+	return p.ListName
+}
+
+// Audience tạo rule lọc cho 1 user ("" = kết nối không xác thực)
+func (b *Bus) Audience(userID string) Audience {
+	a := Audience{UserID: userID, Following: map[string]bool{}}
+	b.mu.Lock()
+	fn := b.following
+	b.mu.Unlock()
+	if userID == "" || fn == nil {
+		return a
+	}
+	ids, err := fn(userID)
+	if err != nil {
+		logger.Warn("load following failed", slog.String("user_id", userID), logging.Err(err))
+	}
+	for _, id := range ids {
+		a.Following[id] = true
+	}
+	return a
+}
+
+// SubscriberCount là số subscription đang mở (/ws/feed + TCP sync)
+func (b *Bus) SubscriberCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Health: bus chỉ nằm trong process, lấy được lock là còn sống
+func (b *Bus) Health(ctx context.Context) health.Report {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return health.Up(map[string]any{"subscribers": len(b.subs), "seq": b.seq})
+}
+
+// Seq là số thứ tự của event mới nhất
+func (b *Bus) Seq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+type remoteEvent struct {
+	Type      string          `json:"type"`
+	UserID    string          `json:"user_id"`
+	Payload   json.RawMessage `json:"payload"`
+	RequestID string          `json:"request_id,omitempty"`
+	Private   bool            `json:"private,omitempty"`
+	Trace     tracing.Carrier `json:"trace,omitempty"`
+}
+
+// UseBroker: event publish ở instance khác được đưa vào bus này (với Seq của local),
+// nên Seq chỉ có nghĩa trong 1 instance: reconnect sang node khác sẽ nhận resumed=false.
+func (b *Bus) UseBroker(br broker.Broker) error {
+	fan, err := broker.NewFanout(br, "feed.events", func(data []byte) {
+		var e remoteEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			logger.Warn("broker: bad event", logging.Err(err))
+			return
+		}
+		ctx := tracing.Extract(logging.WithRequestID(context.Background(), e.RequestID), e.Trace)
+		b.publish(ctx, e.Type, e.UserID, e.Payload, e.Private)
+	})
+	if err != nil {
+		return err
+	}
+	b.fan = fan
+	return nil
+}
+
+// Publish gán Seq và gửi event tới mọi subscription phù hợp (và các instance khác).
+// Subscription đầy buffer bị đóng (client reconnect và resume bằng seq).
+func (b *Bus) Publish(typ, userID string, payload any) Event {
+	return b.PublishContext(context.Background(), typ, userID, payload)
+}
+
+// PublishContext như Publish, event mang request ID và trace context của ctx (nếu có)
+func (b *Bus) PublishContext(ctx context.Context, typ, userID string, payload any) Event {
+	e, _ := b.publishAll(ctx, typ, userID, payload)
+	return e
+}
+
+func (b *Bus) publishAll(ctx context.Context, typ, userID string, payload any) (Event, int) {
+	ctx, span := tracer.Start(ctx, "feed.publish", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("feed.type", typ), attribute.String("feed.user_id", userID)))
+	defer span.End()
+
+	private := b.private(typ, userID, payload)
+	if b.fan != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			logger.ErrorContext(ctx, "marshal payload failed", slog.String("type", typ), logging.Err(err))
+			tracing.RecordError(span, err)
+		} else {
+			b.fan.Publish(remoteEvent{Type: typ, UserID: userID, Payload: raw, RequestID: logging.RequestID(ctx), Private: private, Trace: tracing.Inject(ctx)})
+		}
+	}
+	e, n := b.publish(ctx, typ, userID, payload, private)
+	span.SetAttributes(attribute.Int64("feed.seq", int64(e.Seq)), attribute.Int("feed.delivered", n))
+	return e, n
+}
+
+// publish trả về thêm số subscription đã nhận event
+func (b *Bus) publish(ctx context.Context, typ, userID string, payload any, private bool) (Event, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e := Event{Seq: b.seq, Type: typ, UserID: userID, Payload: payload, Timestamp: time.Now().Unix(),
+		RequestID: logging.RequestID(ctx), Private: private, Trace: tracing.Inject(ctx)}
+	b.buf[b.next] = e
+	b.next = (b.next + 1) % len(b.buf)
+	if b.next == 0 {
+		b.full = true
+	}
+
+	delivered := 0
+	for s := range b.subs {
+		if !s.match(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+			delivered++
+		default:
+			logger.WarnContext(ctx, "subscriber lagging, dropping subscription", slog.Uint64("seq", e.Seq))
+			metrics.Dropped(metrics.DropFeedSubscriber)
+			b.closeLocked(s)
+		}
+	}
+	logger.DebugContext(ctx, "event published",
+		slog.Uint64("seq", e.Seq), slog.String("type", typ), slog.String("user_id", userID), slog.Int("delivered", delivered))
+	return e, delivered
+}
+
+// SendToUser cho phép đăng ký Bus như 1 notify.UserSender (notification vào feed)
+func (b *Bus) SendToUser(userID string, payload any) int {
+	_, n := b.publishAll(context.Background(), TypeNotification, userID, payload)
+	return n
+}
+
+// PumpProgress đọc progress từ channel của HTTP handler và publish vào bus
+func (b *Bus) PumpProgress(ch <-chan models.ProgressUpdate) {
+	for u := range ch {
+		ctx := tracing.Extract(logging.WithRequestID(context.Background(), u.RequestID), u.Trace)
+		b.PublishContext(ctx, TypeProgress, u.UserID, u)
+	}
+}
+
+// Subscription nhận event qua C; C bị đóng khi Close hoặc khi subscriber chậm
+type Subscription struct {
+	C     <-chan Event
+	ch    chan Event
+	match func(Event) bool
+	bus   *Bus
+}
+
+// Subscribe đăng ký nhận event thoả match. since > 0: trả kèm các event có Seq > since
+// còn trong buffer; resumed=false nếu đã mất event (buffer bị ghi đè hoặc server restart),
+// khi đó client nên tải lại trạng thái qua REST.
+func (b *Bus) Subscribe(match func(Event) bool, since uint64) (sub *Subscription, backlog []Event, resumed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	resumed = true
+	if since > 0 {
+		oldest := b.oldestLocked()
+		if since > b.seq || (oldest > 0 && since+1 < oldest) {
+			resumed = false
+		}
+		for _, e := range b.orderedLocked() {
+			if e.Seq > since && match(e) {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	ch := make(chan Event, 64)
+	sub = &Subscription{C: ch, ch: ch, match: match, bus: b}
+	b.subs[sub] = struct{}{}
+	return sub, backlog, resumed
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.closeLocked(s)
+}
+
+func (b *Bus) closeLocked(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Seq của event cũ nhất còn trong buffer (0 nếu buffer rỗng)
+func (b *Bus) oldestLocked() uint64 {
+	if b.seq == 0 {
+		return 0
+	}
+	if !b.full {
+		return b.buf[0].Seq
+	}
+	return b.buf[b.next].Seq
+}
+
+// các event trong buffer theo thứ tự cũ -> mới
+func (b *Bus) orderedLocked() []Event {
+	if !b.full {
+		return b.buf[:b.next]
+	}
+	out := make([]Event, 0, len(b.buf))
+	out = append(out, b.buf[b.next:]...)
+	return append(out, b.buf[:b.next]...)
+}
@@ -3,13 +3,24 @@ package grpc
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"strings"
+	"sync"
+	"time"
 
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/metadata"
+	pb "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
+	"mangahub/internal/apierr"
+	"mangahub/internal/health"
 	"mangahub/internal/library"
 	"mangahub/internal/manga"
+	"mangahub/internal/metrics"
+	"mangahub/internal/notify"
+	"mangahub/internal/recommend"
+	"mangahub/internal/stats"
 	"mangahub/proto"
 )
 
@@ -17,12 +28,84 @@ import (
 type Server struct {
 	proto.UnimplementedMangaServiceServer
 	db *sql.DB
+
+	// user_id -> các stream StreamNotifications đang mở
+	mu      sync.Mutex
+	streams map[string]map[chan *proto.Notification]struct{}
+
+	// xác thực JWT trong metadata "authorization" (StreamNotifications)
+	auth AuthFunc
+
+	// trạng thái listener, cập nhật bởi main qua SetServing
+	serving bool
+	addr    string
+	lastErr error
+}
+
+// AuthFunc kiểm tra token, trả về user_id
+type AuthFunc func(token string) (string, error)
+
+// SetAuth bật xác thực cho các RPC dữ liệu riêng của user (stats, recommend, notification);
+// chưa gọi thì các RPC đó bị từ chối
+func (s *Server) SetAuth(fn AuthFunc) {
+	s.auth = fn
+}
+
+// authUser đọc "authorization: Bearer <jwt>" trong metadata của request
+func (s *Server) authUser(ctx context.Context) (string, error) {
+	if s.auth == nil {
+		return "", apierr.Unauthenticated("authentication not configured")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	for _, v := range md.Get("authorization") {
+		if t, ok := strings.CutPrefix(v, "Bearer "); ok {
+			token = t
+			break
+		}
+	}
+	if token == "" {
+		return "", apierr.Unauthenticated("missing bearer token").WithReason("missing_token")
+	}
+	userID, err := s.auth(token)
+	if err != nil {
+		return "", apierr.Unauthenticated("invalid token").WithReason("invalid_token")
+	}
+	return userID, nil
+}
+
+// SetServing ghi nhận gRPC server đã bắt đầu Serve trên addr (err != nil => đã dừng)
+func (s *Server) SetServing(addr string, serving bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addr, s.serving = addr, serving
+	if err != nil {
+		s.lastErr = err
+	}
+}
+
+// Health: up khi listener đang Serve
+func (s *Server) Health(ctx context.Context) health.Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	streams := 0
+	for _, chs := range s.streams {
+		streams += len(chs)
+	}
+	details := map[string]any{"addr": s.addr, "serving": s.serving, "notification_streams": streams}
+	if !s.serving {
+		return health.Down(health.ErrString(s.lastErr), details)
+	}
+	rep := health.Up(details)
+	rep.LastError = health.ErrString(s.lastErr)
+	return rep
 }
 
 // Tạo server ở gRPC server
 func NewServer(db *sql.DB) *Server {
 	return &Server{
-		db: db,
+		db:      db,
+		streams: make(map[string]map[chan *proto.Notification]struct{}),
 	}
 }
 
@@ -30,12 +113,9 @@ func NewServer(db *sql.DB) *Server {
 func (s *Server) GetManga(ctx context.Context, req *proto.GetMangaRequest) (*proto.MangaResponse, error) {
 
 	// Get manga from db
-	m, err := manga.GetByID(s.db, req.Id)
+	m, err := manga.GetByID(ctx, s.db, req.Id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Errorf(codes.NotFound, "manga not found: %v", req.Id)
-		}
-		return nil, status.Errorf(codes.Internal, "failed to get manga: %v", err)
+		return nil, apierr.DB(err, "manga")
 	}
 
 	// Parse genres
@@ -53,26 +133,38 @@ func (s *Server) GetManga(ctx context.Context, req *proto.GetMangaRequest) (*pro
 	}, nil
 }
 
-// Search manga implementation
+// Search manga implementation (cùng phân trang cursor với GET /manga)
 func (s *Server) SearchManga(ctx context.Context, req *proto.SearchRequest) (*proto.SearchResponse, error) {
-	// Cai dat mac dinnh
-	limit := int(req.Limit)
-	if limit <= 0 {
-		limit = 20
+	if req.SortBy != "" && !manga.ValidSort(req.SortBy) {
+		return nil, apierr.InvalidField("sort_by", "invalid", "invalid sort_by, options: "+strings.Join(manga.SortOptions, ", "))
+	}
+	keep, err := readMask(req.ReadMask)
+	if err != nil {
+		return nil, err
 	}
-	offset := int(req.Offset)
 
-	//search manga
-	results, err := manga.Search(s.db, req.Query, req.Genre, req.Status, limit, offset)
+	page, err := manga.SearchPage(ctx, s.db, manga.SearchParams{
+		Query:     req.Query,
+		Genre:     req.Genre,
+		Status:    req.Status,
+		SortBy:    req.SortBy,
+		Limit:     int(req.Limit),
+		Offset:    int(req.Offset),
+		Cursor:    req.Cursor,
+		WithTotal: req.IncludeTotal,
+	})
+	if errors.Is(err, manga.ErrInvalidCursor) {
+		return nil, apierr.InvalidField("cursor", "invalid", "invalid cursor (expired or from another query)")
+	}
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to search manga: %v", err)
+		return nil, apierr.DB(err, "manga")
 	}
 
 	// Convert to proto
-	protoResults := make([]*proto.MangaResponse, 0, len(results))
-	for _, m := range results {
+	protoResults := make([]*proto.MangaResponse, 0, len(page.Results))
+	for _, m := range page.Results {
 		genres := parseGenres(m.Genres)
-		protoResults = append(protoResults, &proto.MangaResponse{
+		mr := &proto.MangaResponse{
 			Id:            m.ID,
 			Title:         m.Title,
 			Author:        m.Author,
@@ -80,27 +172,36 @@ func (s *Server) SearchManga(ctx context.Context, req *proto.SearchRequest) (*pr
 			Status:        m.Status,
 			TotalChapters: int32(m.TotalChapters),
 			Description:   m.Description,
-		})
+		}
+		prune(mr, keep)
+		protoResults = append(protoResults, mr)
 	}
 
-	return &proto.SearchResponse{
-		Results: protoResults,
-		Limit:   int32(limit),
-		Offset:  int32(offset),
-	}, nil
+	resp := &proto.SearchResponse{
+		Results:    protoResults,
+		Limit:      int32(page.Limit),
+		Offset:     req.Offset,
+		SortBy:     req.SortBy,
+		NextCursor: page.NextCursor,
+	}
+	if page.Total != nil {
+		total := int32(*page.Total)
+		resp.Total = &total
+	}
+	return resp, nil
 }
 
 // UpdateProgress implementation
 func (s *Server) UpdateProgress(ctx context.Context, req *proto.ProgressRequest) (*proto.ProgressResponse, error) {
 	// Update progress in database
-	err := library.UpsertProgress(s.db, library.Progress{
+	err := library.UpsertProgress(ctx, s.db, library.Progress{
 		UserID:         req.UserId,
 		MangaID:        req.MangaId,
 		CurrentChapter: int(req.CurrentChapter),
 		Status:         req.Status,
 	})
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update progress: %v", err)
+		return nil, apierr.DB(err, "progress")
 	}
 
 	return &proto.ProgressResponse{
@@ -109,6 +210,204 @@ func (s *Server) UpdateProgress(ctx context.Context, req *proto.ProgressRequest)
 	}, nil
 }
 
+// GetUserStats implementation (cùng số liệu với GET /me/stats, gồm cả list private nên chỉ
+// chủ tài khoản được xem). Cần JWT trong metadata; user_id (nếu có) phải trùng user của token.
+func (s *Server) GetUserStats(ctx context.Context, req *proto.UserStatsRequest) (*proto.UserStatsResponse, error) {
+	userID, err := s.authUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserId != "" && req.UserId != userID {
+		return nil, apierr.Forbidden("cannot read another user's stats")
+	}
+
+	st, err := stats.ForUser(s.db, userID, time.Now())
+	if err != nil {
+		return nil, apierr.DB(err, "stats")
+	}
+
+	statusCounts := make(map[string]int32, len(st.StatusCounts))
+	for k, v := range st.StatusCounts {
+		statusCounts[k] = int32(v)
+	}
+
+	return &proto.UserStatsResponse{
+		UserId:            st.UserID,
+		TotalManga:        int32(st.TotalManga),
+		StatusCounts:      statusCounts,
+		CompletionRate:    st.CompletionRate,
+		AvgDaysToFinish:   st.AvgDaysToFinish,
+		ChaptersToday:     int32(st.ChaptersToday),
+		ChaptersThisWeek:  int32(st.ChaptersThisWeek),
+		ChaptersThisMonth: int32(st.ChaptersThisMonth),
+		CurrentStreak:     int32(st.CurrentStreak),
+		LongestStreak:     int32(st.LongestStreak),
+		Daily:             toProtoPeriods(st.Daily),
+		Weekly:            toProtoPeriods(st.Weekly),
+		Monthly:           toProtoPeriods(st.Monthly),
+		Genres:            toProtoNameCounts(st.Genres),
+		Authors:           toProtoNameCounts(st.Authors),
+	}, nil
+}
+
+func toProtoPeriods(in []stats.PeriodCount) []*proto.PeriodCount {
+	out := make([]*proto.PeriodCount, 0, len(in))
+	for _, p := range in {
+		out = append(out, &proto.PeriodCount{Period: p.Period, Chapters: int32(p.Chapters)})
+	}
+	return out
+}
+
+func toProtoNameCounts(in []stats.NameCount) []*proto.NameCount {
+	out := make([]*proto.NameCount, 0, len(in))
+	for _, n := range in {
+		out = append(out, &proto.NameCount{Name: n.Name, Count: int32(n.Count)})
+	}
+	return out
+}
+
+// Recommend implementation (cùng logic với GET /me/recommendations); user lấy từ JWT
+func (s *Server) Recommend(ctx context.Context, req *proto.RecommendRequest) (*proto.RecommendResponse, error) {
+	userID, err := s.authUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserId != "" && req.UserId != userID {
+		return nil, apierr.Forbidden("cannot read another user's recommendations")
+	}
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	recs, err := recommend.ForUser(s.db, userID, limit)
+	if err != nil {
+		return nil, apierr.DB(err, "recommendations")
+	}
+
+	results := make([]*proto.Recommendation, 0, len(recs))
+	for _, r := range recs {
+		results = append(results, &proto.Recommendation{
+			Manga: &proto.MangaResponse{
+				Id:            r.Manga.ID,
+				Title:         r.Manga.Title,
+				Author:        r.Manga.Author,
+				Genres:        parseGenres(r.Manga.Genres),
+				Status:        r.Manga.Status,
+				TotalChapters: int32(r.Manga.TotalChapters),
+				Description:   r.Manga.Description,
+			},
+			Score:  r.Score,
+			Reason: r.Reason,
+		})
+	}
+
+	return &proto.RecommendResponse{Results: results}, nil
+}
+
+// StreamNotifications giữ stream mở và đẩy notification của user cho tới khi client huỷ.
+// Cần JWT trong metadata; user_id (nếu có) phải trùng user của token.
+func (s *Server) StreamNotifications(req *proto.NotificationStreamRequest, stream proto.MangaService_StreamNotificationsServer) error {
+	userID, err := s.authUser(stream.Context())
+	if err != nil {
+		return err
+	}
+	if req.UserId == "" {
+		req.UserId = userID
+	}
+	if req.UserId != userID {
+		return apierr.Forbidden("cannot stream another user's notifications")
+	}
+
+	ch := make(chan *proto.Notification, 16)
+	s.mu.Lock()
+	if s.streams[req.UserId] == nil {
+		s.streams[req.UserId] = make(map[chan *proto.Notification]struct{})
+	}
+	s.streams[req.UserId][ch] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams[req.UserId], ch)
+		if len(s.streams[req.UserId]) == 0 {
+			delete(s.streams, req.UserId)
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case n := <-ch:
+			if err := stream.Send(n); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// SendToUser cho notify.Dispatcher (kênh gRPC)
+func (s *Server) SendToUser(userID string, payload any) int {
+	n, ok := payload.(notify.Notification)
+	if !ok {
+		return 0
+	}
+	msg := &proto.Notification{
+		Id:        n.ID,
+		UserId:    n.UserID,
+		Type:      n.Type,
+		MangaId:   n.MangaID,
+		Chapter:   int32(n.Chapter),
+		Message:   n.Message,
+		CreatedAt: n.CreatedAt.Unix(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent := 0
+	for ch := range s.streams[userID] {
+		select {
+		case ch <- msg:
+			sent++
+		default:
+			// stream chậm => bỏ qua, notification vẫn còn trong DB
+			metrics.Dropped(metrics.DropGRPCStream)
+		}
+	}
+	return sent
+}
+
+// readMask kiểm tra FieldMask theo MangaResponse; nil = giữ mọi field
+func readMask(mask *fieldmaskpb.FieldMask) (map[protoreflect.Name]bool, error) {
+	if len(mask.GetPaths()) == 0 {
+		return nil, nil
+	}
+	if !mask.IsValid(&proto.MangaResponse{}) {
+		return nil, apierr.InvalidField("read_mask", "invalid", "invalid read_mask, paths must be MangaResponse fields: "+strings.Join(mask.GetPaths(), ", "))
+	}
+	keep := map[protoreflect.Name]bool{"id": true}
+	for _, p := range mask.GetPaths() {
+		keep[protoreflect.Name(p)] = true
+	}
+	return keep, nil
+}
+
+// prune xoá các field không nằm trong keep (sparse fieldset như ?fields= bên HTTP)
+func prune(m pb.Message, keep map[protoreflect.Name]bool) {
+	if keep == nil {
+		return
+	}
+	r := m.ProtoReflect()
+	r.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if !keep[fd.Name()] {
+			r.Clear(fd)
+		}
+		return true
+	})
+}
+
 // Parse genres method
 func parseGenres(genresJSON string) []string {
 	if genresJSON == "" {
@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Trạng thái của 1 thành phần
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Report là trạng thái nội bộ 1 server/subsystem tự báo cáo
+type Report struct {
+	Status    string         `json:"status"`
+	Details   map[string]any `json:"details,omitempty"` // client count, addr, ...
+	LastError string         `json:"last_error,omitempty"`
+}
+
+func (r Report) Up() bool { return r.Status == StatusUp }
+
+// HealthChecker implement bởi từng server (tcpsync, udpnotify, chat hub, gRPC, ...)
+type HealthChecker interface {
+	Health(ctx context.Context) Report
+}
+
+// CheckerFunc cho các check đơn giản (vd. db ping)
+type CheckerFunc func(ctx context.Context) Report
+
+func (f CheckerFunc) Health(ctx context.Context) Report { return f(ctx) }
+
+// Up/Down tạo Report nhanh
+func Up(details map[string]any) Report { return Report{Status: StatusUp, Details: details} }
+
+func Down(err string, details map[string]any) Report {
+	return Report{Status: StatusDown, LastError: err, Details: details}
+}
+
+// ErrString trả "" nếu err nil (dùng cho LastError)
+func ErrString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Result là kết quả tổng hợp cho /livez, /readyz
+type Result struct {
+	Status     string            `json:"status"` // up | down
+	Timestamp  int64             `json:"timestamp"`
+	Components map[string]Report `json:"components"`
+}
+
+type entry struct {
+	name    string
+	checker HealthChecker
+	live    bool
+}
+
+// Registry gom các checker. Mọi checker đều tính cho readiness;
+// checker đăng ký bằng AddLiveness còn tính cho liveness (chỉ nên là event loop nội bộ,
+// không phải dependency bên ngoài, để restart không giải quyết được thì không bị kill).
+type Registry struct {
+	mu      sync.Mutex
+	entries []entry
+	timeout time.Duration
+}
+
+func NewRegistry(timeout time.Duration) *Registry {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Registry{timeout: timeout}
+}
+
+func (r *Registry) Add(name string, c HealthChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry{name: name, checker: c})
+}
+
+func (r *Registry) AddLiveness(name string, c HealthChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry{name: name, checker: c, live: true})
+}
+
+// Live chỉ chạy checker liveness
+func (r *Registry) Live(ctx context.Context) Result {
+	return r.run(ctx, true)
+}
+
+// Ready chạy mọi checker; 1 thành phần down => not ready
+func (r *Registry) Ready(ctx context.Context) Result {
+	return r.run(ctx, false)
+}
+
+// run chạy các checker song song, mỗi checker bị giới hạn bởi timeout
+func (r *Registry) run(ctx context.Context, liveOnly bool) Result {
+	r.mu.Lock()
+	entries := make([]entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		if !liveOnly || e.live {
+			entries = append(entries, e)
+		}
+	}
+	r.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	reports := make([]Report, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func(i int, e entry) {
+			defer wg.Done()
+			done := make(chan Report, 1)
+			go func() { done <- e.checker.Health(ctx) }()
+			select {
+			case rep := <-done:
+				reports[i] = rep
+			case <-ctx.Done():
+				reports[i] = Down("health check timed out", nil)
+			}
+		}(i, e)
+	}
+	wg.Wait()
+
+	res := Result{Status: StatusUp, Timestamp: time.Now().Unix(), Components: map[string]Report{}}
+	for i, e := range entries {
+		res.Components[e.name] = reports[i]
+		if !reports[i].Up() {
+			res.Status = StatusDown
+		}
+	}
+	return res
+}
@@ -0,0 +1,86 @@
+// Package httpcache ghi response JSON kèm ETag / Last-Modified / Cache-Control và trả 304
+// cho conditional GET (If-None-Match, If-Modified-Since) theo RFC 9110.
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/apierr"
+)
+
+type Options struct {
+	CacheControl string
+	LastModified time.Time // zero = không gửi Last-Modified
+	// ModifiedExact: body chỉ phụ thuộc dữ liệu có mốc LastModified nên If-Modified-Since đủ để
+	// trả 304. false => Last-Modified chỉ để tham khảo, client revalidate bằng ETag.
+	ModifiedExact bool
+}
+
+// ETag yếu (W/) tính từ body: cùng nội dung => cùng ETag, bất kể encoding khi truyền
+func ETag(body []byte) string {
+	h := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(h[:12]) + `"`
+}
+
+// JSON marshal obj, gắn validator + Cache-Control rồi trả 304 nếu request conditional khớp,
+// ngược lại 200 kèm body
+func JSON(c *gin.Context, obj any, o Options) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		apierr.Write(c, apierr.Internal(err))
+		return
+	}
+	etag := ETag(body)
+	h := c.Writer.Header()
+	h.Set("ETag", etag)
+	if o.CacheControl != "" {
+		h.Set("Cache-Control", o.CacheControl)
+	}
+	if !o.LastModified.IsZero() {
+		h.Set("Last-Modified", o.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if NotModified(c.Request, etag, o) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// NotModified: If-None-Match được ưu tiên; chỉ khi không có mới xét If-Modified-Since
+func NotModified(r *http.Request, etag string, o Options) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchETag(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || !o.ModifiedExact || o.LastModified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// HTTP date chỉ chính xác tới giây
+	return !o.LastModified.Truncate(time.Second).After(t)
+}
+
+// matchETag so sánh yếu với danh sách trong If-None-Match ("*" khớp mọi thứ)
+func matchETag(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
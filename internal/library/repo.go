@@ -1,6 +1,21 @@
 package library
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"mangahub/internal/popularity"
+	"mangahub/internal/tracing"
+)
+
+var tracer = tracing.Tracer("mangahub/internal/library")
+
+// loại event ghi vào reading_history (dùng cho activity feed)
+const (
+	EventLibrary  = "library"
+	EventProgress = "progress"
+)
 
 type Progress struct {
 	UserID         string `json:"user_id"`
@@ -11,13 +26,32 @@ type Progress struct {
 }
 
 // Bonus: UpsertProgress now supports list_name for multiple reading lists
-func UpsertProgress(db *sql.DB, p Progress) error {
+// Mỗi lần cập nhật đều ghi thêm 1 dòng vào reading_history để tính thống kê
+func UpsertProgress(ctx context.Context, db *sql.DB, p Progress) error {
+	ctx, span := tracing.Start(ctx, tracer, "library.UpsertProgress")
+	defer span.End()
 	// Default to empty string if list_name not provided (backward compatible)
 	listName := p.ListName
 	if listName == "" {
 		listName = "default"
 	}
-	_, err := db.Exec(`
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var prevChapter int
+	var prevStatus, prevList string
+	err = tx.QueryRowContext(ctx, `SELECT current_chapter, COALESCE(status, ''), COALESCE(list_name, 'default') FROM user_progress WHERE user_id=? AND manga_id=?`,
+		p.UserID, p.MangaID).Scan(&prevChapter, &prevStatus, &prevList)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	added := err == sql.ErrNoRows
+
+	if _, err := tx.ExecContext(ctx, `
 	INSERT INTO user_progress(user_id, manga_id, current_chapter, status, list_name)
 	VALUES(?,?,?,?,?)
 	ON CONFLICT(user_id, manga_id)
@@ -25,21 +59,54 @@ func UpsertProgress(db *sql.DB, p Progress) error {
 	              status=excluded.status,
 	              list_name=excluded.list_name,
 	              updated_at=CURRENT_TIMESTAMP
-	`, p.UserID, p.MangaID, p.CurrentChapter, p.Status, listName)
-	return err
+	`, p.UserID, p.MangaID, p.CurrentChapter, p.Status, listName); err != nil {
+		return err
+	}
+
+	// chỉ tính số chapter đọc thêm (đọc lùi lại thì coi như 0)
+	chaptersRead := p.CurrentChapter - prevChapter
+	if chaptersRead < 0 {
+		chaptersRead = 0
+	}
+	// thêm mới / đổi status / đổi list => event library, còn lại là progress
+	event := EventProgress
+	if added || p.Status != prevStatus || listName != prevList {
+		event = EventLibrary
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO reading_history(user_id, manga_id, chapter, chapters_read, status, event, list_name) VALUES(?,?,?,?,?,?,?)`,
+		p.UserID, p.MangaID, p.CurrentChapter, chaptersRead, p.Status, event, listName); err != nil {
+		return err
+	}
+
+	// bộ đếm popularity/trending cập nhật tăng dần theo event
+	completedDelta := 0
+	if p.Status == "completed" && prevStatus != "completed" {
+		completedDelta = 1
+	} else if p.Status != "completed" && prevStatus == "completed" {
+		completedDelta = -1
+	}
+	if err := popularity.RecordTx(tx, p.MangaID, added, completedDelta); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func GetProgress(db *sql.DB, userID, mangaID string) (Progress, error) {
+func GetProgress(ctx context.Context, db *sql.DB, userID, mangaID string) (Progress, error) {
+	ctx, span := tracing.Start(ctx, tracer, "library.GetProgress")
+	defer span.End()
 	var p Progress
 	// Bonus: Include list_name in SELECT (handle case where column might not exist with COALESCE)
-	err := db.QueryRow(`SELECT user_id,manga_id,current_chapter,status,COALESCE(list_name, 'default') FROM user_progress WHERE user_id=? AND manga_id=?`,
+	err := db.QueryRowContext(ctx, `SELECT user_id,manga_id,current_chapter,status,COALESCE(list_name, 'default') FROM user_progress WHERE user_id=? AND manga_id=?`,
 		userID, mangaID).Scan(&p.UserID, &p.MangaID, &p.CurrentChapter, &p.Status, &p.ListName)
 	return p, err
 }
 
 // Bonus: Get progress by list name
-func GetProgressByList(db *sql.DB, userID, listName string) ([]Progress, error) {
-	rows, err := db.Query(`SELECT user_id,manga_id,current_chapter,status,COALESCE(list_name, 'default') FROM user_progress WHERE user_id=? AND list_name=?`,
+func GetProgressByList(ctx context.Context, db *sql.DB, userID, listName string) ([]Progress, error) {
+	ctx, span := tracing.Start(ctx, tracer, "library.GetProgressByList")
+	defer span.End()
+	rows, err := db.QueryContext(ctx, `SELECT user_id,manga_id,current_chapter,status,COALESCE(list_name, 'default') FROM user_progress WHERE user_id=? AND list_name=?`,
 		userID, listName)
 	if err != nil {
 		return nil, err
@@ -56,3 +123,54 @@ func GetProgressByList(db *sql.DB, userID, listName string) ([]Progress, error)
 	}
 	return results, rows.Err()
 }
+
+// ListAll trả về toàn bộ thư viện của user (mọi list), mới cập nhật trước
+func ListAll(ctx context.Context, db *sql.DB, userID string) ([]Progress, error) {
+	ctx, span := tracing.Start(ctx, tracer, "library.ListAll")
+	defer span.End()
+	rows, err := db.QueryContext(ctx, `SELECT user_id,manga_id,current_chapter,status,COALESCE(list_name, 'default') FROM user_progress WHERE user_id=?
+	ORDER BY updated_at DESC, manga_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []Progress{}
+	for rows.Next() {
+		var p Progress
+		if err := rows.Scan(&p.UserID, &p.MangaID, &p.CurrentChapter, &p.Status, &p.ListName); err != nil {
+			return nil, err
+		}
+		results = append(results, p)
+	}
+	return results, rows.Err()
+}
+
+// GetProgressMany như GetProgress cho nhiều manga 1 lần (dataloader GraphQL);
+// manga chưa có trong thư viện thì không có trong map
+func GetProgressMany(ctx context.Context, db *sql.DB, userID string, mangaIDs []string) (map[string]Progress, error) {
+	ctx, span := tracing.Start(ctx, tracer, "library.GetProgressMany")
+	defer span.End()
+	out := make(map[string]Progress, len(mangaIDs))
+	if len(mangaIDs) == 0 {
+		return out, nil
+	}
+	args := []any{userID}
+	for _, id := range mangaIDs {
+		args = append(args, id)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT user_id,manga_id,current_chapter,status,COALESCE(list_name, 'default') FROM user_progress
+	WHERE user_id=? AND manga_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(mangaIDs)), ",")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Progress
+		if err := rows.Scan(&p.UserID, &p.MangaID, &p.CurrentChapter, &p.Status, &p.ListName); err != nil {
+			return nil, err
+		}
+		out[p.MangaID] = p
+	}
+	return out, rows.Err()
+}
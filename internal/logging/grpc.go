@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadata key của gRPC luôn là chữ thường
+var grpcRequestIDKey = strings.ToLower(RequestIDHeader)
+
+// requestIDFromIncoming lấy x-request-id từ metadata hoặc sinh mới, và gửi lại cho client qua header
+func requestIDFromIncoming(ctx context.Context) (context.Context, string) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(grpcRequestIDKey); len(v) > 0 && ValidRequestID(v[0]) {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		id = NewRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(grpcRequestIDKey, id))
+	return WithRequestID(ctx, id), id
+}
+
+// UnaryServerInterceptor gắn request ID vào context và log mỗi call
+func UnaryServerInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, _ = requestIDFromIncoming(ctx)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor: stream dùng context đã gắn request ID (qua wrappedStream)
+func StreamServerInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, _ := requestIDFromIncoming(ss.Context())
+		logger.DebugContext(ctx, "grpc stream opened", slog.String("method", info.FullMethod))
+		start := time.Now()
+		err := handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context { return w.ctx }
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", status.Code(err).String()),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if err != nil {
+		level = slog.LevelWarn
+		if status.Code(err) == codes.Internal || status.Code(err) == codes.Unknown {
+			level = slog.LevelError
+		}
+		// err.Error() gồm cả nguyên nhân nội bộ (client chỉ thấy message của status)
+		attrs = append(attrs, Err(err))
+	}
+	logger.LogAttrs(ctx, level, "grpc call", attrs...)
+}
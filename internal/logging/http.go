@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CtxRequestIDKey: key trong gin.Context (ngoài request context) để handler lấy nhanh
+const CtxRequestIDKey = "request_id"
+
+// RequestIDMiddleware nhận X-Request-ID từ client (nếu hợp lệ) hoặc sinh mới,
+// gắn vào request context + response header để log và event phía sau mang cùng ID
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !ValidRequestID(id) {
+			id = NewRequestID()
+		}
+		c.Set(CtxRequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// AccessLog thay logger mặc định của gin: 1 record/request, 5xx => error, 4xx => warn
+func AccessLog(logger *slog.Logger, userKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if uid := c.GetString(userKey); uid != "" {
+			attrs = append(attrs, slog.String("user_id", uid))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "http request", attrs...)
+	}
+}
@@ -0,0 +1,129 @@
+// Package logging cấu hình slog cho toàn server: JSON/text, level theo subsystem
+// và request_id lấy từ context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config đọc từ env:
+//
+//	LOG_FORMAT=json|text (mặc định text)
+//	LOG_LEVEL=debug|info|warn|error (mặc định info)
+//	LOG_LEVELS=tcpsync=debug,chat=warn (override theo subsystem)
+type Config struct {
+	Format string
+	Level  slog.Level
+	Levels map[string]slog.Level
+}
+
+func ConfigFromEnv() Config {
+	cfg := Config{Format: os.Getenv("LOG_FORMAT"), Level: ParseLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo), Levels: map[string]slog.Level{}}
+	for _, kv := range strings.Split(os.Getenv("LOG_LEVELS"), ",") {
+		name, lvl, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok || name == "" {
+			continue
+		}
+		cfg.Levels[name] = ParseLevel(lvl, cfg.Level)
+	}
+	return cfg
+}
+
+// ParseLevel: chuỗi rỗng/không hợp lệ => def
+func ParseLevel(s string, def slog.Level) slog.Level {
+	var l slog.Level
+	if s == "" || l.UnmarshalText([]byte(strings.TrimSpace(s))) != nil {
+		return def
+	}
+	return l
+}
+
+type state struct {
+	base   slog.Handler
+	level  slog.Level
+	levels map[string]slog.Level
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{base: slog.NewTextHandler(os.Stderr, nil), level: slog.LevelInfo})
+}
+
+// Setup thay handler gốc và level; logger tạo bởi For trước đó cũng dùng cấu hình mới.
+// Log của package log chuẩn (gin, thư viện) cũng đi qua slog với subsystem "std".
+func Setup(w io.Writer, cfg Config) {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug} // lọc level ở subsystemHandler
+	var base slog.Handler
+	if cfg.Format == "json" {
+		base = slog.NewJSONHandler(w, opts)
+	} else {
+		base = slog.NewTextHandler(w, opts)
+	}
+	current.Store(&state{base: base, level: cfg.Level, levels: cfg.Levels})
+
+	slog.SetDefault(For("std"))
+}
+
+// For trả về logger của 1 subsystem (http, grpc, tcpsync, udpnotify, chat, feed, broker, ...).
+// An toàn để gán vào biến package-level.
+func For(subsystem string) *slog.Logger {
+	return slog.New(&subsystemHandler{subsystem: subsystem})
+}
+
+// subsystemHandler áp level theo subsystem, thêm subsystem + request_id (+ trace_id/span_id nếu ctx có span)
+// rồi chuyển cho handler gốc.
+// WithAttrs/WithGroup được ghi lại và áp lên handler gốc lúc Handle (vì handler gốc có thể đổi bởi Setup).
+type subsystemHandler struct {
+	subsystem string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *subsystemHandler) Enabled(_ context.Context, l slog.Level) bool {
+	st := current.Load()
+	min, ok := st.levels[h.subsystem]
+	if !ok {
+		min = st.level
+	}
+	return l >= min
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	base := current.Load().base.WithAttrs([]slog.Attr{slog.String("subsystem", h.subsystem)})
+	if id := RequestID(ctx); id != "" {
+		base = base.WithAttrs([]slog.Attr{slog.String("request_id", id)})
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		base = base.WithAttrs([]slog.Attr{slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String())})
+	}
+	for _, op := range h.ops {
+		base = op(base)
+	}
+	return base.Handle(ctx, r)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(b slog.Handler) slog.Handler { return b.WithAttrs(attrs) })
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(b slog.Handler) slog.Handler { return b.WithGroup(name) })
+}
+
+func (h *subsystemHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &subsystemHandler{subsystem: h.subsystem, ops: append(ops, op)}
+}
+
+// Err là attr chuẩn cho lỗi
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
@@ -0,0 +1,70 @@
+// Package lru là cache LRU trong process, an toàn cho nhiều goroutine.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+type entry[K comparable, V any] struct {
+	key K
+	val V
+}
+
+// Cache giữ tối đa size phần tử; thêm vào khi đầy thì bỏ phần tử lâu nhất chưa dùng
+type Cache[K comparable, V any] struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List // đầu list = mới dùng nhất
+	items map[K]*list.Element
+}
+
+func New[K comparable, V any](size int) *Cache[K, V] {
+	if size <= 0 {
+		size = 1
+	}
+	return &Cache[K, V]{size: size, ll: list.New(), items: make(map[K]*list.Element, size)}
+}
+
+// Get trả về value và đánh dấu key vừa được dùng
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		return el.Value.(*entry[K, V]).val, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Add thêm hoặc ghi đè key
+func (c *Cache[K, V]) Add(key K, val V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*entry[K, V]).val = val
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&entry[K, V]{key: key, val: val})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry[K, V]).key)
+	}
+}
+
+// Purge xoá toàn bộ
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	clear(c.items)
+}
+
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"mangahub/internal/logging"
+)
+
+var logger = logging.For("mail")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer gửi email (verification, reset password). Implement khác nhau cho dev/test/prod.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Stdout chỉ ghi email ra log (mặc định khi dev)
+type Stdout struct{}
+
+func (Stdout) Send(msg Message) error {
+	logger.Info("mail", slog.String("to", msg.To), slog.String("subject", msg.Subject), slog.String("body", msg.Body))
+	return nil
+}
+
+// File ghi mỗi email thành 1 file .eml trong Dir (dùng cho test end-to-end)
+type File struct {
+	Dir string
+	seq atomic.Int64
+}
+
+func (f *File) Send(msg Message) error {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%03d.eml", time.Now().UnixNano(), f.seq.Add(1))
+	return os.WriteFile(filepath.Join(f.Dir, name), format("", msg), 0644)
+}
+
+// SMTP gửi qua server SMTP (Addr dạng host:port); Auth nil = không xác thực
+type SMTP struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func (s SMTP) Send(msg Message) error {
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{msg.To}, format(s.From, msg))
+}
+
+// format dựng email dạng RFC 5322 đơn giản (text/plain)
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,110 @@
+package manga
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"mangahub/internal/broker"
+	"mangahub/internal/lru"
+	"mangahub/internal/metrics"
+)
+
+// Cache trong process cho các lượt đọc nóng của catalog: chi tiết theo id, trang search của sort
+// CatalogOnly và LastModified. Catalog hiếm khi đổi nên không có TTL; ghi vào bảng manga
+// phải gọi Invalidate (RaiseChapters đã gọi sẵn), lệnh xoá được phát cho các instance khác
+// qua broker (UseBroker).
+var (
+	byID  = lru.New[string, Manga](512)
+	pages = lru.New[string, Page](256)
+
+	cacheMu  sync.Mutex
+	gen      uint64 // tăng mỗi lần Invalidate
+	modified *time.Time
+
+	fanInvalidate *broker.Fanout // nil khi chạy 1 instance không broker
+)
+
+// UseBroker nối cache với các instance khác: Invalidate ở 1 node xoá cache ở mọi node
+func UseBroker(b broker.Broker) error {
+	fan, err := broker.NewFanout(b, "manga.invalidate", func([]byte) { purge() })
+	if err != nil {
+		return err
+	}
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	fanInvalidate = fan
+	return nil
+}
+
+// Invalidate xoá toàn bộ cache catalog ở node này và các node khác. Kết quả của truy vấn
+// đang chạy dở (bắt đầu trước Invalidate) sẽ không được đưa vào cache nữa.
+func Invalidate() {
+	purge()
+	cacheMu.Lock()
+	fan := fanInvalidate
+	cacheMu.Unlock()
+	fan.Publish(struct{}{})
+}
+
+func purge() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	gen++
+	byID.Purge()
+	pages.Purge()
+	modified = nil
+}
+
+func generation() uint64 {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	return gen
+}
+
+// store chỉ ghi cache khi chưa có Invalidate nào kể từ lúc đọc generation g
+func store(g uint64, fn func()) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if g == gen {
+		fn()
+	}
+}
+
+func cachedManga(id string) (Manga, bool) {
+	m, ok := byID.Get(id)
+	metrics.CacheLookup("manga_detail", ok)
+	return m, ok
+}
+
+func storeManga(g uint64, m Manga) {
+	store(g, func() { byID.Add(m.ID, m) })
+}
+
+// pageKey gồm mọi tham số ảnh hưởng tới trang (Limit đã chuẩn hoá)
+func pageKey(p SearchParams) string {
+	return fmt.Sprintf("%q|%q|%q|%q|%d|%d|%q|%t", p.Query, p.Genre, p.Status, p.SortBy, p.Limit, p.Offset, p.Cursor, p.WithTotal)
+}
+
+func cachedPage(key string) (Page, bool) {
+	page, ok := pages.Get(key)
+	metrics.CacheLookup("manga_search", ok)
+	return page, ok
+}
+
+func storePage(g uint64, key string, page Page) {
+	store(g, func() { pages.Add(key, page) })
+}
+
+func cachedModified() (time.Time, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if modified == nil {
+		return time.Time{}, false
+	}
+	return *modified, true
+}
+
+func storeModified(g uint64, t time.Time) {
+	store(g, func() { modified = &t })
+}
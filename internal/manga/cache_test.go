@@ -0,0 +1,50 @@
+package manga
+
+import (
+	"testing"
+	"time"
+
+	"mangahub/internal/broker"
+)
+
+// Invalidate ở 1 node xoá cache ở node khác và ngược lại
+func TestInvalidateAcrossNodes(t *testing.T) {
+	b := broker.NewMemory()
+	t.Cleanup(func() { b.Close() })
+	if err := UseBroker(b); err != nil {
+		t.Fatalf("UseBroker: %v", err)
+	}
+	t.Cleanup(func() {
+		cacheMu.Lock()
+		fanInvalidate = nil
+		cacheMu.Unlock()
+		purge()
+	})
+
+	// node khác trên cùng subject
+	remote := make(chan struct{}, 1)
+	other, err := broker.NewFanout(b, "manga.invalidate", func([]byte) { remote <- struct{}{} })
+	if err != nil {
+		t.Fatalf("NewFanout: %v", err)
+	}
+
+	storeManga(generation(), Manga{ID: "x"})
+	other.Publish(struct{}{})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := byID.Get("x"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("remote invalidation did not purge the local cache")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	Invalidate()
+	select {
+	case <-remote:
+	case <-time.After(2 * time.Second):
+		t.Fatal("local Invalidate was not published to other nodes")
+	}
+}
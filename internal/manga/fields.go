@@ -0,0 +1,82 @@
+package manga
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fields là tên (theo JSON) các field của Manga chọn được bằng sparse fieldset (?fields=)
+var Fields = []string{"id", "title", "author", "genres", "status", "total_chapters", "description", "updated_at"}
+
+// FieldSet là tập field client yêu cầu; nil = đủ field
+type FieldSet map[string]bool
+
+// ParseFields đọc danh sách cách nhau bởi dấu phẩy ("title,author"); chuỗi rỗng => nil.
+// id luôn được trả để client còn liên kết được tới chi tiết.
+func ParseFields(s string) (FieldSet, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	fs := FieldSet{"id": true}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !validField(f) {
+			return nil, fmt.Errorf("unknown field %q, options: %s", f, strings.Join(Fields, ", "))
+		}
+		fs[f] = true
+	}
+	return fs, nil
+}
+
+func validField(f string) bool {
+	for _, v := range Fields {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply trả về m (fs nil) hoặc map chỉ gồm các field được chọn
+func (fs FieldSet) Apply(m Manga) any {
+	if fs == nil {
+		return m
+	}
+	out := make(map[string]any, len(fs))
+	for f := range fs {
+		switch f {
+		case "id":
+			out[f] = m.ID
+		case "title":
+			out[f] = m.Title
+		case "author":
+			out[f] = m.Author
+		case "genres":
+			out[f] = m.Genres
+		case "status":
+			out[f] = m.Status
+		case "total_chapters":
+			out[f] = m.TotalChapters
+		case "description":
+			out[f] = m.Description
+		case "updated_at":
+			out[f] = m.UpdatedAt
+		}
+	}
+	return out
+}
+
+// ApplyAll áp dụng Apply cho cả trang kết quả
+func (fs FieldSet) ApplyAll(list []Manga) any {
+	if fs == nil {
+		return list
+	}
+	out := make([]any, len(list))
+	for i, m := range list {
+		out[i] = fs.Apply(m)
+	}
+	return out
+}
@@ -1,116 +1,383 @@
 package manga
 
-import "database/sql"
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mangahub/internal/tracing"
+)
+
+var tracer = tracing.Tracer("mangahub/internal/manga")
 
 type Manga struct {
-	ID            string `json:"id"`
-	Title         string `json:"title"`
-	Author        string `json:"author"`
-	Genres        string `json:"genres"` // JSON array as text theo spec :contentReference[oaicite:10]{index=10}
-	Status        string `json:"status"`
-	TotalChapters int    `json:"total_chapters"`
-	Description   string `json:"description"`
-}
-
-func Search(db *sql.DB, q, genre, status string, limit, offset int) ([]Manga, error) {
-	sqlQ := `SELECT id,title,author,genres,status,total_chapters,description
-	         FROM manga WHERE 1=1`
-	args := []any{}
+	ID            string    `json:"id"`
+	Title         string    `json:"title"`
+	Author        string    `json:"author"`
+	Genres        string    `json:"genres"` // JSON array as text theo spec :contentReference[oaicite:10]{index=10}
+	Status        string    `json:"status"`
+	TotalChapters int       `json:"total_chapters"`
+	Description   string    `json:"description"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
 
-	if q != "" {
-		sqlQ += " AND (title LIKE ? OR author LIKE ?)"
-		args = append(args, "%"+q+"%", "%"+q+"%")
-	}
-	if status != "" {
-		sqlQ += " AND status = ?"
-		args = append(args, status)
-	}
-	if genre != "" {
-		// genres lưu dạng JSON text => đơn giản demo: LIKE
-		sqlQ += " AND genres LIKE ?"
-		args = append(args, "%"+genre+"%")
-	}
-	sqlQ += " LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
+// Các giá trị sort_by hợp lệ cho SearchPage (dùng chung cho HTTP và gRPC)
+var SortOptions = []string{"title_asc", "title_desc", "author_asc", "author_desc", "chapters_asc", "chapters_desc", "rating_desc", "popularity_desc", "trending"}
 
-	rows, err := db.Query(sqlQ, args...)
-	if err != nil {
-		return nil, err
+func ValidSort(sortBy string) bool {
+	for _, s := range SortOptions {
+		if s == sortBy {
+			return true
+		}
 	}
-	defer rows.Close()
+	return false
+}
 
-	var res []Manga
-	for rows.Next() {
-		var m Manga
-		if err := rows.Scan(&m.ID, &m.Title, &m.Author, &m.Genres, &m.Status, &m.TotalChapters, &m.Description); err != nil {
-			return nil, err
-		}
-		res = append(res, m)
+// CatalogOnly: thứ tự của sort_by chỉ phụ thuộc cột bảng manga (không dùng review/popularity),
+// nên trang kết quả chỉ đổi khi catalog đổi => được cache và Last-Modified là chính xác
+func CatalogOnly(sortBy string) bool {
+	switch sortBy {
+	case "rating_desc", "popularity_desc", "trending":
+		return false
 	}
-	return res, rows.Err()
+	return true
 }
 
-// Bonus: Advanced search with sorting
-func AdvancedSearch(db *sql.DB, q, genre, status string, sortBy string, limit, offset int) ([]Manga, error) {
-	sqlQ := `SELECT id,title,author,genres,status,total_chapters,description
-	         FROM manga WHERE 1=1`
-	args := []any{}
+// Kích thước trang: limit <= 0 => DefaultPageSize, lớn hơn MaxPageSize bị cắt xuống
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
 
-	if q != "" {
-		sqlQ += " AND (title LIKE ? OR author LIKE ? OR description LIKE ?)"
-		args = append(args, "%"+q+"%", "%"+q+"%", "%"+q+"%")
-	}
-	if status != "" {
-		sqlQ += " AND status = ?"
-		args = append(args, status)
-	}
-	if genre != "" {
-		sqlQ += " AND genres LIKE ?"
-		args = append(args, "%"+genre+"%")
+// ErrInvalidCursor: cursor hỏng, hoặc tạo từ truy vấn khác (sort_by/bộ lọc không khớp)
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SearchParams là tham số tìm kiếm; Cursor (next_cursor của trang trước) được ưu tiên hơn Offset
+type SearchParams struct {
+	Query     string
+	Genre     string
+	Status    string
+	SortBy    string
+	Limit     int
+	Offset    int // giữ cho client cũ; trang sâu nên dùng Cursor
+	Cursor    string
+	WithTotal bool
+}
+
+// Page là 1 trang kết quả; NextCursor rỗng khi hết dữ liệu, Total chỉ có khi WithTotal
+type Page struct {
+	Results    []Manga
+	Limit      int
+	NextCursor string
+	Total      *int
+}
+
+// PageSize chuẩn hoá limit theo DefaultPageSize / MaxPageSize
+func PageSize(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultPageSize
+	case limit > MaxPageSize:
+		return MaxPageSize
 	}
+	return limit
+}
+
+// sortKey là 1 cột trong ORDER BY; mọi sort đều kết thúc bằng id để thứ tự là duy nhất (keyset)
+type sortKey struct {
+	expr string
+	desc bool
+}
 
-	// Bonus: Add sorting
+func sortKeys(sortBy string) []sortKey {
+	var primary sortKey
 	switch sortBy {
-	case "title_asc":
-		sqlQ += " ORDER BY title ASC"
 	case "title_desc":
-		sqlQ += " ORDER BY title DESC"
+		primary = sortKey{"title", true}
 	case "author_asc":
-		sqlQ += " ORDER BY author ASC"
+		primary = sortKey{"author", false}
 	case "author_desc":
-		sqlQ += " ORDER BY author DESC"
+		primary = sortKey{"author", true}
 	case "chapters_asc":
-		sqlQ += " ORDER BY total_chapters ASC"
+		primary = sortKey{"total_chapters", false}
 	case "chapters_desc":
-		sqlQ += " ORDER BY total_chapters DESC"
+		primary = sortKey{"total_chapters", true}
+	case "rating_desc":
+		// manga chưa có rating nằm cuối (score 1-10 nên -1 nhỏ hơn mọi rating)
+		primary = sortKey{"COALESCE((SELECT AVG(r.score) FROM reviews r WHERE r.manga_id = manga.id AND r.hidden = 0), -1)", true}
+	case "popularity_desc":
+		// số người đọc (bảng manga_stats, cập nhật bởi package popularity)
+		primary = sortKey{"COALESCE((SELECT ms.readers FROM manga_stats ms WHERE ms.manga_id = manga.id), 0)", true}
+	case "trending":
+		// lượt thêm + hoàn thành trong 7 ngày gần nhất
+		primary = sortKey{"COALESCE((SELECT SUM(d.adds + d.completions) FROM manga_daily_counts d WHERE d.manga_id = manga.id AND d.day >= date('now', '-7 days')), 0)", true}
 	default:
-		// Default: sort by title ascending
-		sqlQ += " ORDER BY title ASC"
+		primary = sortKey{"title", false}
 	}
+	keys := []sortKey{primary}
+	if primary.expr != "title" {
+		keys = append(keys, sortKey{"title", false})
+	}
+	return append(keys, sortKey{"id", false})
+}
 
-	sqlQ += " LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
+// cursor lưu giá trị sort key của phần tử cuối trang, kèm sort_by và dấu vân tay bộ lọc
+type cursor struct {
+	Sort   string `json:"s"`
+	Filter string `json:"f"`
+	Keys   []any  `json:"k"`
+}
 
-	rows, err := db.Query(sqlQ, args...)
+func filterHash(p SearchParams) string {
+	h := sha256.Sum256([]byte(p.Query + "\x00" + p.Genre + "\x00" + p.Status))
+	return hex.EncodeToString(h[:6])
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, p SearchParams, n int) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil {
+		return c, ErrInvalidCursor
+	}
+	if c.Sort != p.SortBy || c.Filter != filterHash(p) || len(c.Keys) != n {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// where dựng điều kiện lọc chung cho trang kết quả và COUNT(*)
+func where(p SearchParams) (string, []any) {
+	w := " WHERE 1=1"
+	args := []any{}
+	if p.Query != "" {
+		w += " AND (title LIKE ? OR author LIKE ? OR description LIKE ?)"
+		args = append(args, "%"+p.Query+"%", "%"+p.Query+"%", "%"+p.Query+"%")
+	}
+	if p.Status != "" {
+		w += " AND status = ?"
+		args = append(args, p.Status)
+	}
+	if p.Genre != "" {
+		// genres lưu dạng JSON text => đơn giản demo: LIKE
+		w += " AND genres LIKE ?"
+		args = append(args, "%"+p.Genre+"%")
+	}
+	return w, args
+}
+
+// SearchPage tìm manga theo keyset pagination: trang sau bắt đầu ngay sau sort key của phần tử
+// cuối trang trước nên không chậm dần như OFFSET và không lặp/sót khi catalog thay đổi.
+// Trang của sort CatalogOnly được cache tới lần Invalidate tiếp theo.
+func SearchPage(ctx context.Context, db *sql.DB, p SearchParams) (Page, error) {
+	ctx, span := tracing.Start(ctx, tracer, "manga.SearchPage")
+	defer span.End()
+
+	p.Limit = PageSize(p.Limit)
+	if !CatalogOnly(p.SortBy) {
+		return searchPage(ctx, db, p)
+	}
+	key := pageKey(p)
+	if page, ok := cachedPage(key); ok {
+		return page, nil
+	}
+	gen := generation()
+	page, err := searchPage(ctx, db, p)
+	if err == nil {
+		storePage(gen, key, page)
+	}
+	return page, err
+}
+
+func searchPage(ctx context.Context, db *sql.DB, p SearchParams) (Page, error) {
+	keys := sortKeys(p.SortBy)
+	filter, filterArgs := where(p)
+	args := append([]any{}, filterArgs...)
+
+	cols := make([]string, len(keys))
+	order := make([]string, len(keys))
+	for i, k := range keys {
+		cols[i] = fmt.Sprintf("%s AS k%d", k.expr, i)
+		order[i] = fmt.Sprintf("k%d", i)
+		if k.desc {
+			order[i] += " DESC"
+		}
+	}
+	sqlQ := `SELECT * FROM (SELECT id,title,author,genres,status,total_chapters,description,updated_at, ` + strings.Join(cols, ", ") +
+		` FROM manga` + filter + `)`
+
+	if p.Cursor != "" {
+		c, err := decodeCursor(p.Cursor, p, len(keys))
+		if err != nil {
+			return Page{}, err
+		}
+		// (k0 > v0) OR (k0 = v0 AND k1 > v1) OR ... (< với cột DESC)
+		var or []string
+		for i, k := range keys {
+			var and []string
+			for j := 0; j < i; j++ {
+				and = append(and, fmt.Sprintf("k%d = ?", j))
+				args = append(args, c.Keys[j])
+			}
+			op := ">"
+			if k.desc {
+				op = "<"
+			}
+			and = append(and, fmt.Sprintf("k%d %s ?", i, op))
+			args = append(args, c.Keys[i])
+			or = append(or, "("+strings.Join(and, " AND ")+")")
+		}
+		sqlQ += " WHERE " + strings.Join(or, " OR ")
+	}
+
+	sqlQ += " ORDER BY " + strings.Join(order, ", ") + " LIMIT ?"
+	args = append(args, p.Limit+1) // lấy dư 1 dòng để biết còn trang sau
+	if p.Cursor == "" && p.Offset > 0 {
+		sqlQ += " OFFSET ?"
+		args = append(args, p.Offset)
+	}
+
+	rows, err := db.QueryContext(ctx, sqlQ, args...)
 	if err != nil {
-		return nil, err
+		return Page{}, err
 	}
 	defer rows.Close()
 
-	var res []Manga
+	page := Page{Results: []Manga{}, Limit: p.Limit}
+	var last []any
 	for rows.Next() {
 		var m Manga
-		if err := rows.Scan(&m.ID, &m.Title, &m.Author, &m.Genres, &m.Status, &m.TotalChapters, &m.Description); err != nil {
-			return nil, err
+		vals := make([]any, len(keys))
+		dest := []any{&m.ID, &m.Title, &m.Author, &m.Genres, &m.Status, &m.TotalChapters, &m.Description, &m.UpdatedAt}
+		for i := range vals {
+			dest = append(dest, &vals[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return Page{}, err
+		}
+		if len(page.Results) == p.Limit {
+			page.NextCursor = encodeCursor(cursor{Sort: p.SortBy, Filter: filterHash(p), Keys: last})
+			break
+		}
+		for i, v := range vals {
+			if b, ok := v.([]byte); ok {
+				vals[i] = string(b)
+			}
+		}
+		page.Results = append(page.Results, m)
+		last = vals
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, err
+	}
+	rows.Close() // trả connection trước khi COUNT (DB chỉ có 1 connection)
+
+	if p.WithTotal {
+		// COUNT chỉ dùng bộ lọc, không tính sort key nên rẻ hơn nhiều so với trang kết quả
+		var total int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM manga`+filter, filterArgs...).Scan(&total); err != nil {
+			return Page{}, err
 		}
-		res = append(res, m)
+		page.Total = &total
 	}
-	return res, rows.Err()
+	return page, nil
 }
 
-func GetByID(db *sql.DB, id string) (Manga, error) {
+// GetByID đọc qua cache (chỉ cache manga tồn tại)
+func GetByID(ctx context.Context, db *sql.DB, id string) (Manga, error) {
+	if m, ok := cachedManga(id); ok {
+		return m, nil
+	}
+	ctx, span := tracing.Start(ctx, tracer, "manga.GetByID")
+	defer span.End()
+	gen := generation()
 	var m Manga
-	err := db.QueryRow(`SELECT id,title,author,genres,status,total_chapters,description FROM manga WHERE id = ?`, id).
-		Scan(&m.ID, &m.Title, &m.Author, &m.Genres, &m.Status, &m.TotalChapters, &m.Description)
+	err := db.QueryRowContext(ctx, `SELECT id,title,author,genres,status,total_chapters,description,updated_at FROM manga WHERE id = ?`, id).
+		Scan(&m.ID, &m.Title, &m.Author, &m.Genres, &m.Status, &m.TotalChapters, &m.Description, &m.UpdatedAt)
+	if err == nil {
+		storeManga(gen, m)
+	}
 	return m, err
 }
+
+// GetByIDs đọc nhiều manga 1 lần (dataloader GraphQL); id không tồn tại thì không có trong map
+func GetByIDs(ctx context.Context, db *sql.DB, ids []string) (map[string]Manga, error) {
+	out := make(map[string]Manga, len(ids))
+	seen := make(map[string]bool, len(ids))
+	var missing []any
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if m, ok := cachedManga(id); ok {
+			out[id] = m
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+
+	ctx, span := tracing.Start(ctx, tracer, "manga.GetByIDs")
+	defer span.End()
+	gen := generation()
+	rows, err := db.QueryContext(ctx, `SELECT id,title,author,genres,status,total_chapters,description,updated_at FROM manga WHERE id IN (`+
+		strings.TrimSuffix(strings.Repeat("?,", len(missing)), ",")+`)`, missing...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m Manga
+		if err := rows.Scan(&m.ID, &m.Title, &m.Author, &m.Genres, &m.Status, &m.TotalChapters, &m.Description, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out[m.ID] = m
+		storeManga(gen, m)
+	}
+	return out, rows.Err()
+}
+
+// LastModified là mốc updated_at mới nhất của catalog (zero khi chưa có manga)
+func LastModified(ctx context.Context, db *sql.DB) (time.Time, error) {
+	if t, ok := cachedModified(); ok {
+		return t, nil
+	}
+	gen := generation()
+	var t time.Time
+	err := db.QueryRowContext(ctx, `SELECT updated_at FROM manga ORDER BY updated_at DESC LIMIT 1`).Scan(&t)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return t, err
+	}
+	storeModified(gen, t)
+	return t, nil
+}
+
+// RaiseChapters nâng total_chapters lên chapter khi chapter mới vượt số hiện có
+// (manga đang ra có total_chapters = 0 cho tới chapter đầu tiên)
+func RaiseChapters(ctx context.Context, db *sql.DB, id string, chapter int) (bool, error) {
+	ctx, span := tracing.Start(ctx, tracer, "manga.RaiseChapters")
+	defer span.End()
+	res, err := db.ExecContext(ctx, `UPDATE manga SET total_chapters = ? WHERE id = ? AND total_chapters < ?`, chapter, id, chapter)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	if n > 0 {
+		Invalidate()
+	}
+	return n > 0, nil
+}
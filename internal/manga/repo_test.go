@@ -0,0 +1,241 @@
+package manga
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"mangahub/pkg/database"
+	"mangahub/pkg/models"
+)
+
+// catalog nhỏ có nhiều giá trị trùng (title, total_chapters) để kiểm tra tie-break theo title/id
+var testCatalog = []models.Manga{
+	{ID: "a2", Title: "Alpha", Author: "Kei", Status: "ongoing", TotalChapters: 10},
+	{ID: "a1", Title: "Alpha", Author: "Kei", Status: "ongoing", TotalChapters: 10},
+	{ID: "a3", Title: "Alpha", Author: "Ann", Status: "ongoing", TotalChapters: 5},
+	{ID: "b1", Title: "Beta", Author: "Bo", Status: "ongoing", TotalChapters: 10},
+	{ID: "d1", Title: "Delta", Author: "Kei", Status: "completed", TotalChapters: 10},
+	{ID: "c1", Title: "Gamma", Author: "Ann", Status: "ongoing", TotalChapters: 0},
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := database.SeedManga(db, testCatalog); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	// cache trang là biến package, không để test trước ảnh hưởng
+	Invalidate()
+	t.Cleanup(Invalidate)
+	return db
+}
+
+// walk đi hết các trang bằng NextCursor, trả về id theo thứ tự và số phần tử từng trang
+func walk(t *testing.T, db *sql.DB, p SearchParams) (ids []string, sizes []int) {
+	t.Helper()
+	for range 20 {
+		page, err := SearchPage(context.Background(), db, p)
+		if err != nil {
+			t.Fatalf("SearchPage(%+v): %v", p, err)
+		}
+		sizes = append(sizes, len(page.Results))
+		for _, m := range page.Results {
+			ids = append(ids, m.ID)
+		}
+		if page.NextCursor == "" {
+			return ids, sizes
+		}
+		p.Cursor = page.NextCursor
+	}
+	t.Fatal("pagination did not terminate")
+	return nil, nil
+}
+
+func TestSearchPageOrderAndTies(t *testing.T) {
+	db := newTestDB(t)
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"a1", "a2", "a3", "b1", "d1", "c1"}},
+		{"title_asc", []string{"a1", "a2", "a3", "b1", "d1", "c1"}},
+		{"title_desc", []string{"c1", "d1", "b1", "a1", "a2", "a3"}},
+		{"chapters_desc", []string{"a1", "a2", "b1", "d1", "a3", "c1"}},
+		{"chapters_asc", []string{"c1", "a3", "a1", "a2", "b1", "d1"}},
+		{"author_asc", []string{"a3", "c1", "b1", "a1", "a2", "d1"}},
+		{"author_desc", []string{"a1", "a2", "d1", "b1", "a3", "c1"}},
+	}
+	for _, tt := range tests {
+		for _, limit := range []int{1, 2, 4, 6, 50} {
+			ids, _ := walk(t, db, SearchParams{SortBy: tt.sort, Limit: limit})
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("sort %q limit %d: got %v, want %v", tt.sort, limit, ids, tt.want)
+			}
+		}
+	}
+}
+
+func TestSearchPageBoundaries(t *testing.T) {
+	db := newTestDB(t)
+	tests := []struct {
+		name  string
+		p     SearchParams
+		sizes []int
+	}{
+		{"exact multiple has no empty trailing page", SearchParams{Limit: 3}, []int{3, 3}},
+		{"last page partial", SearchParams{Limit: 4}, []int{4, 2}},
+		{"limit equals total", SearchParams{Limit: 6}, []int{6}},
+		{"limit above total", SearchParams{Limit: 7}, []int{6}},
+		{"default limit", SearchParams{}, []int{6}},
+		{"filter", SearchParams{Status: "completed", Limit: 1}, []int{1}},
+		{"no match", SearchParams{Query: "zzz", Limit: 2}, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, sizes := walk(t, db, tt.p)
+			if !slices.Equal(sizes, tt.sizes) {
+				t.Errorf("page sizes %v, want %v", sizes, tt.sizes)
+			}
+		})
+	}
+}
+
+func TestSearchPageCursorStableOnInsert(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	first, err := SearchPage(ctx, db, SearchParams{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// thêm manga đứng trước cursor: trang sau không được lặp lại phần tử đã thấy
+	if _, err := database.SeedManga(db, []models.Manga{{ID: "a0", Title: "Aardvark"}}); err != nil {
+		t.Fatal(err)
+	}
+	Invalidate()
+	second, err := SearchPage(ctx, db, SearchParams{Limit: 3, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range second.Results {
+		ids = append(ids, m.ID)
+	}
+	if want := []string{"b1", "d1", "c1"}; !slices.Equal(ids, want) {
+		t.Errorf("second page %v, want %v", ids, want)
+	}
+}
+
+func TestSearchPageCursorTampering(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	first, err := SearchPage(ctx, db, SearchParams{SortBy: "chapters_desc", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := first.NextCursor
+	raw, _ := base64.RawURLEncoding.DecodeString(valid)
+	flipped := []byte(valid)
+	flipped[len(flipped)/2] ^= 0x01
+
+	tests := []struct {
+		name string
+		p    SearchParams
+	}{
+		{"not base64", SearchParams{SortBy: "chapters_desc", Cursor: "!!!"}},
+		{"not json", SearchParams{SortBy: "chapters_desc", Cursor: base64.RawURLEncoding.EncodeToString([]byte("nope"))}},
+		{"truncated", SearchParams{SortBy: "chapters_desc", Cursor: valid[:len(valid)/2]}},
+		{"bit flip", SearchParams{SortBy: "chapters_desc", Cursor: string(flipped)}},
+		{"other sort", SearchParams{SortBy: "title_asc", Cursor: valid}},
+		{"other filter", SearchParams{SortBy: "chapters_desc", Status: "completed", Cursor: valid}},
+		{"other query", SearchParams{SortBy: "chapters_desc", Query: "a", Cursor: valid}},
+		{"wrong key count", SearchParams{SortBy: "chapters_desc", Cursor: encodeCursor(cursor{
+			Sort: "chapters_desc", Filter: filterHash(SearchParams{}), Keys: []any{10},
+		})}},
+		{"truncated json", SearchParams{SortBy: "chapters_desc", Cursor: base64.RawURLEncoding.EncodeToString(raw[:1])}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.Limit = 2
+			_, err := SearchPage(ctx, db, tt.p)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	// cursor hợp lệ của cùng truy vấn vẫn dùng được
+	if _, err := SearchPage(ctx, db, SearchParams{SortBy: "chapters_desc", Limit: 2, Cursor: valid}); err != nil {
+		t.Errorf("valid cursor rejected: %v", err)
+	}
+}
+
+func TestSearchPageTotal(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		p    SearchParams
+		want *int
+	}{
+		{"without total", SearchParams{Limit: 2}, nil},
+		{"all", SearchParams{Limit: 2, WithTotal: true}, ptr(6)},
+		{"status filter", SearchParams{Status: "ongoing", Limit: 2, WithTotal: true}, ptr(5)},
+		{"query filter", SearchParams{Query: "Alpha", Limit: 1, WithTotal: true}, ptr(3)},
+		{"no match", SearchParams{Query: "zzz", WithTotal: true}, ptr(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := SearchPage(ctx, db, tt.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (page.Total == nil) != (tt.want == nil) || (page.Total != nil && *page.Total != *tt.want) {
+				t.Errorf("total = %v, want %v", deref(page.Total), deref(tt.want))
+			}
+			// total không đổi theo trang
+			if page.NextCursor != "" && tt.p.WithTotal {
+				p := tt.p
+				p.Cursor = page.NextCursor
+				next, err := SearchPage(ctx, db, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if next.Total == nil || *next.Total != *tt.want {
+					t.Errorf("total on next page = %v, want %d", deref(next.Total), *tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	tests := []struct{ in, want int }{
+		{-1, DefaultPageSize}, {0, DefaultPageSize}, {1, 1}, {MaxPageSize, MaxPageSize}, {MaxPageSize + 1, MaxPageSize},
+	}
+	for _, tt := range tests {
+		if got := PageSize(tt.in); got != tt.want {
+			t.Errorf("PageSize(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func ptr(n int) *int { return &n }
+
+func deref(p *int) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
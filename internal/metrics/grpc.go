@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor đo latency + status code cho từng method unary
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		grpcInFlight.WithLabelValues(info.FullMethod).Inc()
+		defer grpcInFlight.WithLabelValues(info.FullMethod).Dec()
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		grpcDuration.WithLabelValues(info.FullMethod, "unary", status.Code(err).String()).
+			Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor đo thời gian sống của stream (vd. StreamNotifications)
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		grpcInFlight.WithLabelValues(info.FullMethod).Inc()
+		defer grpcInFlight.WithLabelValues(info.FullMethod).Dec()
+
+		start := time.Now()
+		err := handler(srv, ss)
+		grpcDuration.WithLabelValues(info.FullMethod, "stream", status.Code(err).String()).
+			Observe(time.Since(start).Seconds())
+		return err
+	}
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "mangahub"
+
+var (
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_server_handling_seconds",
+		Help:      "gRPC handling latency by method and status code (streams: full stream lifetime).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "type", "code"})
+
+	grpcInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_server_in_flight",
+		Help:      "gRPC calls/streams currently being handled.",
+	}, []string{"method"})
+
+	eventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_dropped_total",
+		Help:      "Events dropped because a queue or subscriber buffer was full.",
+	}, []string{"source"})
+
+	dbDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "SQLite statement latency by operation (select/insert/update/delete/other) and result.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"op", "result"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "In-process cache lookups by cache name and result (hit/miss).",
+	}, []string{"cache", "result"})
+)
+
+// Nguồn event bị drop (label "source" của events_dropped_total)
+const (
+	DropProgressChannel = "progress_channel"  // PATCH /progress khi channel progress đầy
+	DropChatSend        = "chat_send"         // buffer gửi của client WebSocket chat đầy
+	DropFeedSubscriber  = "feed_subscriber"   // subscriber /ws/feed hoặc TCP chậm bị ngắt
+	DropBrokerOutbound  = "broker_outbound"   // hàng đợi publish lên broker đầy
+	DropBrokerMemory    = "broker_memory"     // subscriber của memory broker đầy
+	DropGRPCStream      = "grpc_notification" // buffer stream notification gRPC đầy
+)
+
+// Dropped tăng bộ đếm event bị drop
+func Dropped(source string) {
+	eventsDropped.WithLabelValues(source).Inc()
+}
+
+// ObserveDB ghi thời gian 1 câu lệnh SQL (dùng làm hook cho pkg/database)
+func ObserveDB(op string, d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	dbDuration.WithLabelValues(op, result).Observe(d.Seconds())
+}
+
+// CacheLookup đếm 1 lần tra cache trong process
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// ClientGauge export số client đang kết nối của 1 subsystem (tcpsync, udpnotify, chat, feed...).
+// fn được gọi mỗi lần Prometheus scrape.
+func ClientGauge(subsystem string, fn func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "connected_clients",
+		Help:        "Clients currently connected, by subsystem.",
+		ConstLabels: prometheus.Labels{"subsystem": subsystem},
+	}, func() float64 { return float64(fn()) })
+}
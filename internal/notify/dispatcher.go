@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"mangahub/internal/logging"
+)
+
+var logger = logging.For("notify")
+
+const (
+	ChannelWebSocket = "websocket"
+	ChannelTCP       = "tcp"
+	ChannelGRPC      = "grpc"
+)
+
+// UserSender: transport biết client thuộc user nào (WebSocket, TCP sync, gRPC stream).
+// Trả về số kết nối đã nhận được.
+type UserSender interface {
+	SendToUser(userID string, payload any) int
+}
+
+// Senders gộp nhiều UserSender cho cùng 1 kênh (vd chat hub + /ws/feed đều là WebSocket)
+type Senders []UserSender
+
+func (ss Senders) SendToUser(userID string, payload any) int {
+	n := 0
+	for _, s := range ss {
+		n += s.SendToUser(userID, payload)
+	}
+	return n
+}
+
+// TopicPublisher: transport không xác thực user, lọc theo topic (UDP)
+type TopicPublisher interface {
+	PublishTopic(topic string, payload any) int
+}
+
+// Release là payload công khai (không gắn user) gửi qua UDP
+type Release struct {
+	Type    string `json:"type"`
+	MangaID string `json:"manga_id"`
+	Chapter int    `json:"chapter"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+// Dispatcher tạo notification cho follower và fan-out qua các transport đã đăng ký
+type Dispatcher struct {
+	db      *sql.DB
+	senders map[string]UserSender
+	udp     TopicPublisher
+}
+
+func NewDispatcher(db *sql.DB) *Dispatcher {
+	return &Dispatcher{db: db, senders: make(map[string]UserSender)}
+}
+
+// Register gắn transport theo tên kênh (ChannelWebSocket, ChannelTCP, ChannelGRPC)
+func (d *Dispatcher) Register(channel string, s UserSender) {
+	d.senders[channel] = s
+}
+
+func (d *Dispatcher) RegisterUDP(p TopicPublisher) {
+	d.udp = p
+}
+
+// Topic UDP cho 1 manga, client gửi "SUBSCRIBE manga:<id>"
+func MangaTopic(mangaID string) string {
+	return "manga:" + mangaID
+}
+
+// ReleaseChapter ghi nhận chapter mới, tạo notification cho từng follower và gửi realtime
+// theo preference của họ. Trả về số follower được thông báo (0 nếu chapter đã release rồi).
+// Release và notification ghi chung 1 transaction: lỗi giữa chừng thì gọi lại vẫn thông báo đủ.
+func (d *Dispatcher) ReleaseChapter(mangaID, mangaTitle string, chapter int, chapterTitle string) (int, error) {
+	msg := fmt.Sprintf("%s: chapter %d is out", mangaTitle, chapter)
+	if chapterTitle != "" {
+		msg += " - " + chapterTitle
+	}
+
+	created, err := d.record(mangaID, chapter, chapterTitle, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	anyUDP := false
+	for _, n := range created {
+		prefs, err := GetPrefs(d.db, n.UserID)
+		if err != nil {
+			logger.Warn("load prefs failed", slog.String("user_id", n.UserID), logging.Err(err))
+			prefs = DefaultPrefs()
+		}
+		d.deliver(ChannelWebSocket, prefs.WebSocket, n)
+		d.deliver(ChannelTCP, prefs.TCP, n)
+		d.deliver(ChannelGRPC, prefs.GRPC, n)
+		anyUDP = anyUDP || prefs.UDP
+	}
+
+	// UDP không biết user => publish 1 lần lên topic của manga
+	if anyUDP && d.udp != nil {
+		d.udp.PublishTopic(MangaTopic(mangaID), Release{
+			Type: TypeChapterRelease, MangaID: mangaID, Chapter: chapter, Title: chapterTitle, Message: msg,
+		})
+	}
+	return len(created), nil
+}
+
+// record lưu release và notification của mọi follower; nil nếu chapter đã release trước đó
+func (d *Dispatcher) record(mangaID string, chapter int, chapterTitle, msg string) ([]Notification, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	isNew, err := RecordRelease(tx, mangaID, chapter, chapterTitle)
+	if err != nil {
+		return nil, fmt.Errorf("record release: %w", err)
+	}
+	if !isNew {
+		return nil, nil
+	}
+	followers, err := Followers(tx, mangaID)
+	if err != nil {
+		return nil, fmt.Errorf("load followers: %w", err)
+	}
+	created := make([]Notification, 0, len(followers))
+	for _, userID := range followers {
+		n := Notification{UserID: userID, Type: TypeChapterRelease, MangaID: mangaID, Chapter: chapter, Message: msg}
+		if err := Create(tx, &n); err != nil {
+			return nil, fmt.Errorf("create notification: %w", err)
+		}
+		created = append(created, n)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit release: %w", err)
+	}
+	return created, nil
+}
+
+func (d *Dispatcher) deliver(channel string, enabled bool, n Notification) {
+	if !enabled {
+		return
+	}
+	if s, ok := d.senders[channel]; ok {
+		s.SendToUser(n.UserID, n)
+	}
+}
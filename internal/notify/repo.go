@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"database/sql"
+	"time"
+)
+
+const TypeChapterRelease = "chapter_release"
+
+// Notification lưu theo từng user (bảng notifications)
+type Notification struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	Type      string    `json:"type"`
+	MangaID   string    `json:"manga_id"`
+	Chapter   int       `json:"chapter"`
+	Message   string    `json:"message"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Kênh realtime user muốn nhận (mặc định bật hết)
+type Prefs struct {
+	WebSocket bool `json:"websocket"`
+	TCP       bool `json:"tcp"`
+	UDP       bool `json:"udp"`
+	GRPC      bool `json:"grpc"`
+}
+
+func DefaultPrefs() Prefs {
+	return Prefs{WebSocket: true, TCP: true, UDP: true, GRPC: true}
+}
+
+// dbtx là *sql.DB hoặc *sql.Tx (ghi release + notification trong 1 transaction)
+type dbtx interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func Follow(db *sql.DB, userID, mangaID string) error {
+	_, err := db.Exec(`INSERT OR IGNORE INTO manga_follows(user_id, manga_id) VALUES(?,?)`, userID, mangaID)
+	return err
+}
+
+func Unfollow(db *sql.DB, userID, mangaID string) error {
+	_, err := db.Exec(`DELETE FROM manga_follows WHERE user_id=? AND manga_id=?`, userID, mangaID)
+	return err
+}
+
+func IsFollowing(db *sql.DB, userID, mangaID string) (bool, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM manga_follows WHERE user_id=? AND manga_id=?`, userID, mangaID).Scan(&n)
+	return n > 0, err
+}
+
+func Followers(db dbtx, mangaID string) ([]string, error) {
+	rows, err := db.Query(`SELECT user_id FROM manga_follows WHERE manga_id=?`, mangaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// RecordRelease lưu chapter mới; trả về false nếu chapter này đã được release trước đó
+func RecordRelease(db dbtx, mangaID string, chapter int, title string) (bool, error) {
+	res, err := db.Exec(`INSERT OR IGNORE INTO chapter_releases(manga_id, chapter, title) VALUES(?,?,?)`, mangaID, chapter, title)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func Create(db dbtx, n *Notification) error {
+	res, err := db.Exec(`INSERT INTO notifications(user_id, type, manga_id, chapter, message) VALUES(?,?,?,?,?)`,
+		n.UserID, n.Type, n.MangaID, n.Chapter, n.Message)
+	if err != nil {
+		return err
+	}
+	n.ID, err = res.LastInsertId()
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+	return err
+}
+
+// List trả về notification mới nhất trước; unreadOnly = chỉ lấy chưa đọc
+func List(db *sql.DB, userID string, unreadOnly bool, limit, offset int) ([]Notification, error) {
+	q := `SELECT id, user_id, type, COALESCE(manga_id, ''), COALESCE(chapter, 0), message, read_at IS NOT NULL, created_at
+	      FROM notifications WHERE user_id=?`
+	if unreadOnly {
+		q += " AND read_at IS NULL"
+	}
+	q += " ORDER BY id DESC LIMIT ? OFFSET ?"
+
+	rows, err := db.Query(q, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []Notification{}
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.MangaID, &n.Chapter, &n.Message, &n.Read, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, n)
+	}
+	return res, rows.Err()
+}
+
+func UnreadCount(db *sql.DB, userID string) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id=? AND read_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+func MarkRead(db *sql.DB, userID string, id int64) error {
+	res, err := db.Exec(`UPDATE notifications SET read_at=COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id=? AND user_id=?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func MarkAllRead(db *sql.DB, userID string) (int, error) {
+	res, err := db.Exec(`UPDATE notifications SET read_at=CURRENT_TIMESTAMP WHERE user_id=? AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func GetPrefs(db *sql.DB, userID string) (Prefs, error) {
+	p := DefaultPrefs()
+	err := db.QueryRow(`SELECT websocket, tcp, udp, grpc FROM notification_prefs WHERE user_id=?`, userID).
+		Scan(&p.WebSocket, &p.TCP, &p.UDP, &p.GRPC)
+	if err == sql.ErrNoRows {
+		return DefaultPrefs(), nil
+	}
+	return p, err
+}
+
+func SetPrefs(db *sql.DB, userID string, p Prefs) error {
+	_, err := db.Exec(`
+	INSERT INTO notification_prefs(user_id, websocket, tcp, udp, grpc) VALUES(?,?,?,?,?)
+	ON CONFLICT(user_id) DO UPDATE SET websocket=excluded.websocket, tcp=excluded.tcp,
+	                                   udp=excluded.udp, grpc=excluded.grpc`,
+		userID, p.WebSocket, p.TCP, p.UDP, p.GRPC)
+	return err
+}
@@ -0,0 +1,136 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/apierr"
+)
+
+// Handler phục vụ spec dạng JSON (marshal 1 lần lúc tạo handler)
+func (d *Document) Handler() gin.HandlerFunc {
+	body, err := json.Marshal(d)
+	return func(c *gin.Context) {
+		if err != nil {
+			apierr.Write(c, apierr.Internal(err))
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
+
+// Missing trả về các route gin ("GET /manga/:id") chưa có operation trong spec.
+// Route có path bắt đầu bằng 1 trong skip (vd "/ui" static) được bỏ qua, HEAD cũng vậy.
+func (d *Document) Missing(routes gin.RoutesInfo, skip ...string) []string {
+	var out []string
+outer:
+	for _, rt := range routes {
+		if rt.Method == http.MethodHead {
+			continue
+		}
+		for _, p := range skip {
+			if rt.Path == p || strings.HasPrefix(rt.Path, p+"/") {
+				continue outer
+			}
+		}
+		if d.Lookup(rt.Method, rt.Path) == nil {
+			out = append(out, rt.Method+" "+rt.Path)
+		}
+	}
+	return out
+}
+
+// Validate kiểm tra path/query parameter và JSON body của request theo operation trong spec;
+// vi phạm => 400 validation_failed kèm danh sách field. Route không có trong spec thì cho qua.
+// Đặt sau middleware xác thực để request chưa login nhận 401 trước.
+func (d *Document) Validate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := d.Lookup(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		var errs []apierr.FieldError
+		for _, p := range op.Parameters {
+			var raw string
+			var ok bool
+			switch p.In {
+			case "path":
+				raw, ok = c.Param(p.Name), true
+			case "query":
+				raw, ok = c.GetQuery(p.Name)
+			default:
+				continue
+			}
+			if !ok || raw == "" {
+				if p.Required {
+					errs = append(errs, apierr.Field(p.Name, "required", p.Name+" required"))
+				}
+				continue
+			}
+			errs = append(errs, d.validate(p.Schema, d.paramValue(p.Schema, raw), p.Name)...)
+		}
+
+		if rb := op.RequestBody; rb != nil && c.Request.Body != nil {
+			limit := d.MaxBodyBytes
+			if limit <= 0 {
+				limit = DefaultMaxBodyBytes
+			}
+			raw, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					apierr.Write(c, apierr.TooLarge(limit))
+					return
+				}
+				apierr.Write(c, apierr.Invalid("cannot read body"))
+				return
+			}
+			// handler đọc lại body bằng ShouldBindJSON
+			c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+
+			if len(bytes.TrimSpace(raw)) == 0 {
+				if rb.Required {
+					apierr.Write(c, apierr.Invalid("request body required"))
+					return
+				}
+			} else if mt, ok := rb.Content["application/json"]; ok {
+				dec := json.NewDecoder(bytes.NewReader(raw))
+				dec.UseNumber()
+				var v any
+				if err := dec.Decode(&v); err != nil {
+					apierr.Write(c, apierr.Invalid("invalid json"))
+					return
+				}
+				errs = append(errs, d.validate(mt.Schema, v, "")...)
+			}
+		}
+
+		if len(errs) > 0 {
+			apierr.Write(c, apierr.Validation(errs...))
+			return
+		}
+		c.Next()
+	}
+}
+
+// paramValue đổi chuỗi trong path/query sang kiểu của schema để validate chung với body
+func (d *Document) paramValue(s *Schema, raw string) any {
+	switch d.resolve(s).Type {
+	case "integer", "number":
+		return json.Number(raw)
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
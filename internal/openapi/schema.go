@@ -0,0 +1,247 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"mangahub/internal/apierr"
+)
+
+// Schema là tập con JSON Schema của OpenAPI 3.0 mà validator hỗ trợ
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Enum        []any              `json:"enum,omitempty"`
+	Default     any                `json:"default,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	// AdditionalProperties: với map (object không có Properties) là schema của value
+	AdditionalProperties *Schema `json:"additionalProperties,omitempty"`
+}
+
+func Ref(name string) *Schema { return &Schema{Ref: "#/components/schemas/" + name} }
+
+func String() *Schema  { return &Schema{Type: "string"} }
+func Integer() *Schema { return &Schema{Type: "integer"} }
+func Number() *Schema  { return &Schema{Type: "number"} }
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+func Array(items *Schema) *Schema { return &Schema{Type: "array", Items: items} }
+
+// Object với các field bắt buộc liệt kê trong required
+func Object(props map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: props, Required: required}
+}
+
+// Map là object tuỳ ý key, value theo schema v (nil = bất kỳ)
+func Map(v *Schema) *Schema {
+	if v == nil {
+		v = &Schema{}
+	}
+	return &Schema{Type: "object", AdditionalProperties: v}
+}
+
+// Các setter trả về chính s để viết gọn trong định nghĩa spec
+
+func (s *Schema) Desc(d string) *Schema { s.Description = d; return s }
+func (s *Schema) Fmt(f string) *Schema  { s.Format = f; return s }
+func (s *Schema) Null() *Schema         { s.Nullable = true; return s }
+func (s *Schema) Def(v any) *Schema     { s.Default = v; return s }
+func (s *Schema) Match(p string) *Schema {
+	s.Pattern = p
+	return s
+}
+
+// Len giới hạn độ dài chuỗi (đếm rune); max < 0 = không giới hạn
+func (s *Schema) Len(min, max int) *Schema {
+	if min > 0 {
+		s.MinLength = &min
+	}
+	if max >= 0 {
+		s.MaxLength = &max
+	}
+	return s
+}
+
+func (s *Schema) Min(v float64) *Schema { s.Minimum = &v; return s }
+func (s *Schema) Max(v float64) *Schema { s.Maximum = &v; return s }
+
+func (s *Schema) OneOf(values ...string) *Schema {
+	for _, v := range values {
+		s.Enum = append(s.Enum, v)
+	}
+	return s
+}
+
+// resolve theo $ref (chỉ hỗ trợ #/components/schemas/<name>)
+func (d *Document) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+var patterns sync.Map // pattern -> *regexp.Regexp
+
+func compiled(p string) *regexp.Regexp {
+	if re, ok := patterns.Load(p); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(p)
+	patterns.Store(p, re)
+	return re
+}
+
+// validate kiểm tra v (đã decode với UseNumber) theo s; field là tên theo JSON để báo lỗi
+func (d *Document) validate(s *Schema, v any, field string) []apierr.FieldError {
+	s = d.resolve(s)
+	if s == nil {
+		return nil
+	}
+	name := field
+	if name == "" {
+		name = "body"
+	}
+	var errs []apierr.FieldError
+	bad := func(reason, format string, args ...any) []apierr.FieldError {
+		return append(errs, apierr.Field(field, reason, name+" "+fmt.Sprintf(format, args...)))
+	}
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return bad("invalid", "must not be null")
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return bad("invalid", "must be an object")
+		}
+		for _, req := range s.Required {
+			if _, ok := obj[req]; !ok {
+				errs = append(errs, apierr.Field(join(field, req), "required", join(field, req)+" required"))
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // thứ tự lỗi ổn định
+		for _, k := range keys {
+			if ps, ok := s.Properties[k]; ok {
+				errs = append(errs, d.validate(ps, obj[k], join(field, k))...)
+			} else if s.AdditionalProperties != nil {
+				errs = append(errs, d.validate(s.AdditionalProperties, obj[k], join(field, k))...)
+			}
+		}
+		return errs
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return bad("invalid", "must be an array")
+		}
+		for i, item := range arr {
+			errs = append(errs, d.validate(s.Items, item, fmt.Sprintf("%s[%d]", field, i))...)
+		}
+		return errs
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return bad("invalid", "must be a string")
+		}
+		n := utf8.RuneCountInString(str)
+		if s.MinLength != nil && n < *s.MinLength {
+			if n == 0 {
+				return bad("required", "must not be empty")
+			}
+			return bad("too_short", "must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return bad("too_long", "must be at most %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" && !compiled(s.Pattern).MatchString(str) {
+			return bad("invalid", "has invalid format")
+		}
+	case "integer", "number":
+		kind := "a number"
+		if s.Type == "integer" {
+			kind = "an integer"
+		}
+		num, ok := v.(json.Number)
+		if !ok {
+			return bad("invalid", "must be %s", kind)
+		}
+		f, err := num.Float64()
+		if err != nil {
+			return bad("invalid", "must be %s", kind)
+		}
+		if s.Type == "integer" {
+			if _, err := num.Int64(); err != nil {
+				return bad("invalid", "must be an integer")
+			}
+		}
+		if (s.Minimum != nil && f < *s.Minimum) || (s.Maximum != nil && f > *s.Maximum) {
+			return bad("out_of_range", "must be %s", rangeText(s))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return bad("invalid", "must be a boolean")
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return bad("invalid", "must be one of: %s", enumText(s.Enum))
+	}
+	return errs
+}
+
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func rangeText(s *Schema) string {
+	switch {
+	case s.Minimum != nil && s.Maximum != nil:
+		return fmt.Sprintf("between %v and %v", *s.Minimum, *s.Maximum)
+	case s.Minimum != nil:
+		return fmt.Sprintf(">= %v", *s.Minimum)
+	default:
+		return fmt.Sprintf("<= %v", *s.Maximum)
+	}
+}
+
+func inEnum(enum []any, v any) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumText(enum []any) string {
+	out := make([]string, len(enum))
+	for i, e := range enum {
+		out[i] = fmt.Sprint(e)
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,185 @@
+// Package openapi dựng tài liệu OpenAPI 3 bằng code Go (đặt cạnh chỗ đăng ký route),
+// phục vụ /openapi.json, kiểm tra mọi route gin đều có trong spec và validate request theo schema.
+package openapi
+
+import "strings"
+
+const Version = "3.0.3"
+
+// Document là gốc của spec (chỉ gồm phần MangaHub dùng tới)
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+
+	// AliasPrefix: route gin không có prefix (alias cũ) được tra như AliasPrefix+path
+	AliasPrefix string `json:"-"`
+	// MaxBodyBytes: body lớn hơn bị Validate từ chối với 413 (0 = DefaultMaxBodyBytes)
+	MaxBodyBytes int64 `json:"-"`
+}
+
+// DefaultMaxBodyBytes đủ cho mọi body JSON của API
+const DefaultMaxBodyBytes = 1 << 20
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem: method viết thường ("get", "post", ...) -> operation
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // "path" | "query" | "header" (header không được validate)
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// New tạo Document rỗng với bearer JWT là security scheme "bearer"
+func New(title, version, description string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version, Description: description},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"}},
+		},
+	}
+}
+
+// Bearer là security requirement cho route cần JWT
+var Bearer = []map[string][]string{{"bearer": {}}}
+
+// Add đăng ký operation; path theo cú pháp OpenAPI ("/manga/{id}")
+func (d *Document) Add(method, path string, op *Operation) {
+	item := d.Paths[path]
+	if item == nil {
+		item = PathItem{}
+		d.Paths[path] = item
+	}
+	if op.Responses == nil {
+		op.Responses = map[string]Response{}
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// Define thêm schema dùng chung vào components, trả về $ref tới nó
+func (d *Document) Define(name string, s *Schema) *Schema {
+	d.Components.Schemas[name] = s
+	return Ref(name)
+}
+
+// Lookup tìm operation cho route gin (":id" / "*path" => "{id}" / "{path}").
+// Route alias cũ không có prefix được tra thêm dưới AliasPrefix.
+func (d *Document) Lookup(method, ginPath string) *Operation {
+	if ginPath == "" {
+		return nil
+	}
+	p := PathFromGin(ginPath)
+	m := strings.ToLower(method)
+	if op := d.Paths[p][m]; op != nil {
+		return op
+	}
+	if d.AliasPrefix != "" && !strings.HasPrefix(p, d.AliasPrefix+"/") {
+		return d.Paths[d.AliasPrefix+p][m]
+	}
+	return nil
+}
+
+// PathFromGin đổi "/manga/:id" thành "/manga/{id}"
+func PathFromGin(p string) string {
+	parts := strings.Split(p, "/")
+	for i, s := range parts {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			parts[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// JSON là content application/json với schema s
+func JSON(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+// Body là request body JSON bắt buộc
+func Body(s *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: JSON(s)}
+}
+
+// OK là response 200 JSON
+func OK(desc string, s *Schema) Response {
+	return Response{Description: desc, Content: JSON(s)}
+}
+
+func PathParam(name, desc string, s *Schema) Parameter {
+	return Parameter{Name: name, In: "path", Description: desc, Required: true, Schema: s}
+}
+
+func QueryParam(name, desc string, s *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: desc, Schema: s}
+}
+
+func HeaderParam(name, desc string, s *Schema) Parameter {
+	return Parameter{Name: name, In: "header", Description: desc, Schema: s}
+}
@@ -0,0 +1,151 @@
+package popularity
+
+import (
+	"database/sql"
+	"fmt"
+
+	"mangahub/internal/manga"
+)
+
+// Bộ đếm theo manga; *_7d / *_30d lấy từ bucket theo ngày
+type Counters struct {
+	MangaID        string `json:"manga_id"`
+	Readers        int    `json:"readers"`
+	Completions    int    `json:"completions"`
+	Adds7d         int    `json:"adds_7d"`
+	Adds30d        int    `json:"adds_30d"`
+	Completions7d  int    `json:"completions_7d"`
+	Completions30d int    `json:"completions_30d"`
+}
+
+type Trending struct {
+	Manga       manga.Manga `json:"manga"`
+	Adds        int         `json:"adds"`
+	Completions int         `json:"completions"`
+	Score       int         `json:"score"`
+}
+
+// RecordTx cập nhật bộ đếm trong cùng transaction với user_progress.
+// added = user vừa thêm manga vào thư viện; completedDelta = +1 khi chuyển sang completed, -1 khi bỏ completed.
+func RecordTx(tx *sql.Tx, mangaID string, added bool, completedDelta int) error {
+	if !added && completedDelta == 0 {
+		return nil
+	}
+	readers := 0
+	if added {
+		readers = 1
+	}
+	if _, err := tx.Exec(`
+	INSERT INTO manga_stats(manga_id, readers, completions) VALUES(?,?,?)
+	ON CONFLICT(manga_id) DO UPDATE SET readers = readers + excluded.readers,
+	                                    completions = MAX(completions + excluded.completions, 0),
+	                                    updated_at = CURRENT_TIMESTAMP
+	`, mangaID, readers, completedDelta); err != nil {
+		return fmt.Errorf("update manga_stats: %w", err)
+	}
+
+	// bucket theo ngày chỉ đếm sự kiện dương (dùng cho trending)
+	completions := 0
+	if completedDelta > 0 {
+		completions = completedDelta
+	}
+	if readers == 0 && completions == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(`
+	INSERT INTO manga_daily_counts(manga_id, day, adds, completions) VALUES(?, date('now'), ?, ?)
+	ON CONFLICT(manga_id, day) DO UPDATE SET adds = adds + excluded.adds,
+	                                         completions = completions + excluded.completions
+	`, mangaID, readers, completions); err != nil {
+		return fmt.Errorf("update manga_daily_counts: %w", err)
+	}
+	return nil
+}
+
+// Backfill dựng lại bộ đếm từ user_progress + reading_history khi manga_stats còn trống
+// (DB cũ trước khi có tính năng này). Trả về số manga được backfill.
+func Backfill(db *sql.DB) (int, error) {
+	var existing int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM manga_stats`).Scan(&existing); err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`
+	INSERT INTO manga_stats(manga_id, readers, completions)
+	SELECT manga_id, COUNT(*), SUM(CASE WHEN status='completed' THEN 1 ELSE 0 END)
+	FROM user_progress GROUP BY manga_id`)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`
+	INSERT OR REPLACE INTO manga_daily_counts(manga_id, day, adds, completions)
+	SELECT manga_id, day, SUM(is_add), SUM(is_done) FROM (
+		SELECT manga_id, date(MIN(read_at)) AS day, 1 AS is_add, 0 AS is_done
+		FROM reading_history GROUP BY user_id, manga_id
+		UNION ALL
+		SELECT manga_id, date(MIN(read_at)), 0, 1
+		FROM reading_history WHERE status='completed' GROUP BY user_id, manga_id
+	) GROUP BY manga_id, day`); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func GetCounters(db *sql.DB, mangaID string) (Counters, error) {
+	c := Counters{MangaID: mangaID}
+	err := db.QueryRow(`SELECT readers, completions FROM manga_stats WHERE manga_id=?`, mangaID).
+		Scan(&c.Readers, &c.Completions)
+	if err != nil && err != sql.ErrNoRows {
+		return c, err
+	}
+	err = db.QueryRow(`
+	SELECT COALESCE(SUM(CASE WHEN day >= date('now','-7 days') THEN adds END), 0),
+	       COALESCE(SUM(adds), 0),
+	       COALESCE(SUM(CASE WHEN day >= date('now','-7 days') THEN completions END), 0),
+	       COALESCE(SUM(completions), 0)
+	FROM manga_daily_counts WHERE manga_id=? AND day >= date('now','-30 days')`, mangaID).
+		Scan(&c.Adds7d, &c.Adds30d, &c.Completions7d, &c.Completions30d)
+	return c, err
+}
+
+// TopTrending xếp hạng theo số lượt thêm + hoàn thành trong `days` ngày gần nhất
+func TopTrending(db *sql.DB, days, limit int) ([]Trending, error) {
+	rows, err := db.Query(`
+	SELECT m.id, m.title, m.author, m.genres, m.status, m.total_chapters, m.description, m.updated_at,
+	       SUM(d.adds), SUM(d.completions), SUM(d.adds + d.completions) AS score
+	FROM manga_daily_counts d JOIN manga m ON m.id = d.manga_id
+	WHERE d.day >= date('now', ?)
+	GROUP BY m.id
+	ORDER BY score DESC, m.title ASC
+	LIMIT ?`, fmt.Sprintf("-%d days", days), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []Trending{}
+	for rows.Next() {
+		var t Trending
+		m := &t.Manga
+		if err := rows.Scan(&m.ID, &m.Title, &m.Author, &m.Genres, &m.Status, &m.TotalChapters, &m.Description, &m.UpdatedAt,
+			&t.Adds, &t.Completions, &t.Score); err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"time"
+
+	"mangahub/internal/logging"
+)
+
+// LoginGuard chống brute-force đăng nhập: đếm lần sai theo username và theo IP,
+// khoá tăng dần (exponential backoff). IP nên có Threshold cao hơn vì nhiều user có thể chung NAT.
+type LoginGuard struct {
+	Store Store
+	User  LockoutPolicy
+	IP    LockoutPolicy
+}
+
+func DefaultLoginGuard(store Store) *LoginGuard {
+	return &LoginGuard{
+		Store: store,
+		User:  LockoutPolicy{Threshold: 5, Base: 30 * time.Second, Max: 15 * time.Minute, Window: time.Hour},
+		IP:    LockoutPolicy{Threshold: 20, Base: time.Minute, Max: time.Hour, Window: time.Hour},
+	}
+}
+
+// Attempt đếm lần đăng nhập trước khi kiểm tra password, để các request song song không cùng
+// lọt qua trước khi lần sai đầu tiên được ghi. Trả về thời gian khoá còn lại (0 = được thử).
+func (g *LoginGuard) Attempt(username, ip string) time.Duration {
+	ipKey, userKey := "login:ip:"+ip, "login:user:"+username
+	wait, err := g.Store.Attempt(ipKey, g.IP)
+	if err != nil {
+		logger.Warn("login lockout record failed", slog.String("ip", ip), logging.Err(err))
+	}
+	if wait > 0 {
+		return wait
+	}
+	wait, err = g.Store.Attempt(userKey, g.User)
+	if err != nil {
+		logger.Warn("login lockout record failed", slog.String("username", username), logging.Err(err))
+	}
+	if wait > 0 {
+		// lần thử không diễn ra => không tính cho IP
+		g.forgive(ipKey, g.IP)
+	}
+	return wait
+}
+
+// Success: đăng nhập đúng => reset bộ đếm của username và bỏ lần thử vừa đếm cho IP
+// (bộ đếm IP không reset để attacker không tự reset bằng tài khoản riêng)
+func (g *LoginGuard) Success(username, ip string) {
+	if err := g.Store.Reset("login:user:" + username); err != nil {
+		logger.Warn("login lockout reset failed", slog.String("username", username), logging.Err(err))
+	}
+	g.forgive("login:ip:"+ip, g.IP)
+}
+
+func (g *LoginGuard) forgive(key string, p LockoutPolicy) {
+	if err := g.Store.Forgive(key, p); err != nil {
+		logger.Warn("login lockout forgive failed", slog.String("key", key), logging.Err(err))
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"mangahub/internal/apierr"
+	"mangahub/internal/logging"
+)
+
+var logger = logging.For("ratelimit")
+
+// Middleware giới hạn theo IP và (nếu đã login) theo user, mỗi budget 1 name riêng.
+// userKey là key context chứa user_id (auth.CtxUserIDKey); đặt sau RequireJWT để có user.
+// Store lỗi thì cho qua để rate limit không làm sập API.
+func Middleware(store Store, name string, l Limit, userKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := []string{name + ":ip:" + c.ClientIP()}
+		if id := c.GetString(userKey); id != "" {
+			keys = append(keys, name+":user:"+id)
+		}
+		for _, key := range keys {
+			ok, wait, err := store.Allow(key, l)
+			if err != nil {
+				logger.WarnContext(c.Request.Context(), "store error", slog.String("key", key), logging.Err(err))
+				continue
+			}
+			if !ok {
+				TooManyRequests(c, wait, "rate limit exceeded")
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// WritesOnly bỏ qua GET/HEAD/OPTIONS, dùng cho budget ghi
+func WritesOnly(h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		h(c)
+	}
+}
+
+// TooManyRequests trả 429 kèm Retry-After (giây, làm tròn lên)
+func TooManyRequests(c *gin.Context, wait time.Duration, msg string) {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(secs))
+	apierr.Write(c, apierr.RateLimited(msg).WithMeta("retry_after", secs))
+}
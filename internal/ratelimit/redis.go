@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"mangahub/internal/health"
+)
+
+// Redis là Store dùng chung giữa các instance; mỗi thao tác là 1 script Lua (atomic)
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// GCRA: key giữ theoretical arrival time (ms); trả về số ms phải chờ (0 = cho qua)
+var allowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+local nexttat = tat + interval
+local allow_at = nexttat - burst * interval
+if now < allow_at then
+	return math.max(math.ceil(allow_at - now), 1)
+end
+redis.call('SET', KEYS[1], nexttat, 'PX', math.max(math.ceil(nexttat - now), 1))
+return 0
+`)
+
+// đang khoá => trả về -(ms còn lại); ngược lại tăng bộ đếm, gia hạn window và trả về số lần thử liên tiếp
+var attemptScript = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[2])
+if ttl > 0 then return -ttl end
+local n = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return n
+`)
+
+// giảm bộ đếm; dưới ngưỡng (ARGV[1]) thì gỡ khoá
+var forgiveScript = redis.NewScript(`
+local n = redis.call('DECR', KEYS[1])
+if n <= 0 then redis.call('DEL', KEYS[1]) end
+if n < tonumber(ARGV[1]) then redis.call('DEL', KEYS[2]) end
+return n
+`)
+
+// NewRedis nhận URL dạng redis://[:password@]host:port/db; key được gắn prefix "mangahub:rl:"
+func NewRedis(url string) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &Redis{client: client, prefix: "mangahub:rl:"}, nil
+}
+
+// Allow lỗi Redis thì cho qua (fail-open) kèm error để caller log
+func (r *Redis) Allow(key string, l Limit) (bool, time.Duration, error) {
+	if l.Rate <= 0 || l.Burst <= 0 {
+		return false, time.Hour, nil
+	}
+	interval := float64(time.Second/time.Millisecond) / l.Rate
+	wait, err := allowScript.Run(context.Background(), r.client, []string{r.prefix + "gcra:" + key},
+		time.Now().UnixMilli(), interval, l.Burst).Int64()
+	if err != nil {
+		return true, 0, err
+	}
+	return wait == 0, time.Duration(wait) * time.Millisecond, nil
+}
+
+// Attempt lỗi Redis thì cho thử (fail-open như Allow) kèm error để caller log
+func (r *Redis) Attempt(key string, p LockoutPolicy) (time.Duration, error) {
+	ctx := context.Background()
+	n, err := attemptScript.Run(ctx, r.client, []string{r.prefix + "fail:" + key, r.prefix + "lock:" + key},
+		p.Window.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return time.Duration(-n) * time.Millisecond, nil
+	}
+	if lock := p.lockFor(int(n)); lock > 0 {
+		if err := r.client.Set(ctx, r.prefix+"lock:"+key, n, lock).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+func (r *Redis) Forgive(key string, p LockoutPolicy) error {
+	return forgiveScript.Run(context.Background(), r.client, []string{r.prefix + "fail:" + key, r.prefix + "lock:" + key},
+		p.Threshold).Err()
+}
+
+func (r *Redis) LockedFor(key string) (time.Duration, error) {
+	d, err := r.client.PTTL(context.Background(), r.prefix+"lock:"+key).Result()
+	if err != nil || d < 0 {
+		return 0, err
+	}
+	return d, nil
+}
+
+func (r *Redis) Reset(key string) error {
+	return r.client.Del(context.Background(), r.prefix+"fail:"+key, r.prefix+"lock:"+key).Err()
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+// Health ping Redis (store lỗi thì rate limit fail-open nhưng vẫn báo down)
+func (r *Redis) Health(ctx context.Context) health.Report {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return health.Down(err.Error(), map[string]any{"kind": "redis"})
+	}
+	return health.Up(map[string]any{"kind": "redis"})
+}
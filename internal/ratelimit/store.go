@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limit: nạp Rate request/giây, cho phép dồn tối đa Burst request
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute tiện cho budget nhỏ (vd. /auth/*)
+func PerMinute(n, burst int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: burst}
+}
+
+// LockoutPolicy: sau Threshold lần sai liên tiếp thì khoá Base, mỗi lần sai tiếp theo khoá gấp đôi
+// (tối đa Max). Bộ đếm tự reset sau Window không có lần sai nào.
+type LockoutPolicy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+}
+
+// lockFor tính thời gian khoá cho lần sai thứ n
+func (p LockoutPolicy) lockFor(n int) time.Duration {
+	if n < p.Threshold {
+		return 0
+	}
+	d := p.Base
+	for i := p.Threshold; i < n && d < p.Max; i++ {
+		d *= 2
+	}
+	if d > p.Max {
+		d = p.Max
+	}
+	return d
+}
+
+// Store giữ state rate limit/lockout; Memory cho 1 instance, Redis để chia sẻ giữa các instance
+type Store interface {
+	// Allow lấy 1 lượt cho key; bị chặn thì trả về thời gian phải chờ
+	Allow(key string, l Limit) (bool, time.Duration, error)
+	// Attempt ghi nhận 1 lần thử trước khi kiểm tra password (coi như sai cho tới khi Forgive):
+	// key đang bị khoá => trả về thời gian còn lại và không đếm; chạm ngưỡng thì khoá từ lần này
+	Attempt(key string, p LockoutPolicy) (time.Duration, error)
+	// Forgive bỏ 1 lần thử đã đếm (vd đăng nhập đúng); gỡ khoá nếu bộ đếm xuống dưới ngưỡng
+	Forgive(key string, p LockoutPolicy) error
+	// LockedFor: thời gian khoá còn lại của key
+	LockedFor(key string) (time.Duration, error)
+	// Reset xoá bộ đếm sai và khoá của key
+	Reset(key string) error
+}
+
+// Memory là Store trong RAM (GCRA + bộ đếm sai), entry hết hạn được dọn định kỳ khi có request
+type Memory struct {
+	mu        sync.Mutex
+	tat       map[string]time.Time // theoretical arrival time của GCRA
+	failures  map[string]*failure
+	lastSweep time.Time
+}
+
+type failure struct {
+	count       int
+	last        time.Time
+	window      time.Duration
+	lockedUntil time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{tat: map[string]time.Time{}, failures: map[string]*failure{}, lastSweep: time.Now()}
+}
+
+func (m *Memory) Allow(key string, l Limit) (bool, time.Duration, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	if l.Rate <= 0 || l.Burst <= 0 {
+		return false, time.Hour, nil
+	}
+	interval := time.Duration(float64(time.Second) / l.Rate)
+	tat := m.tat[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	if allowAt := next.Add(-time.Duration(l.Burst) * interval); now.Before(allowAt) {
+		return false, allowAt.Sub(now), nil
+	}
+	m.tat[key] = next
+	return true, 0, nil
+}
+
+func (m *Memory) Attempt(key string, p LockoutPolicy) (time.Duration, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	f := m.failures[key]
+	if f != nil && now.Before(f.lockedUntil) {
+		return f.lockedUntil.Sub(now), nil
+	}
+	if f == nil || now.Sub(f.last) > f.window {
+		f = &failure{}
+		m.failures[key] = f
+	}
+	f.count++
+	f.last = now
+	f.window = p.Window
+	if lock := p.lockFor(f.count); lock > 0 {
+		f.lockedUntil = now.Add(lock)
+	}
+	return 0, nil
+}
+
+func (m *Memory) Forgive(key string, p LockoutPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f := m.failures[key]
+	if f == nil {
+		return nil
+	}
+	if f.count > 0 {
+		f.count--
+	}
+	if p.lockFor(f.count) == 0 {
+		f.lockedUntil = time.Time{}
+	}
+	return nil
+}
+
+func (m *Memory) LockedFor(key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f := m.failures[key]; f != nil {
+		if d := time.Until(f.lockedUntil); d > 0 {
+			return d, nil
+		}
+	}
+	return 0, nil
+}
+
+func (m *Memory) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.failures, key)
+	return nil
+}
+
+// sweep bỏ entry đã hết hạn, tối đa 1 lần/phút (phải giữ m.mu)
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for k, t := range m.tat {
+		if t.Before(now) {
+			delete(m.tat, k)
+		}
+	}
+	for k, f := range m.failures {
+		if now.Sub(f.last) > f.window && now.After(f.lockedUntil) {
+			delete(m.failures, k)
+		}
+	}
+}
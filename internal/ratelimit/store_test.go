@@ -0,0 +1,235 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestLockFor(t *testing.T) {
+	p := LockoutPolicy{Threshold: 3, Base: 30 * time.Second, Max: 5 * time.Minute, Window: time.Hour}
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, 30 * time.Second},
+		{4, time.Minute},
+		{5, 2 * time.Minute},
+		{6, 4 * time.Minute},
+		{7, 5 * time.Minute}, // chạm Max
+		{50, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := p.lockFor(tt.n); got != tt.want {
+			t.Errorf("lockFor(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+
+	// Base > Max: không bao giờ vượt Max
+	if got := (LockoutPolicy{Threshold: 1, Base: time.Hour, Max: time.Minute}).lockFor(1); got != time.Minute {
+		t.Errorf("lockFor with Base > Max = %v, want %v", got, time.Minute)
+	}
+}
+
+func TestMemoryAllow(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   Limit
+		calls   int
+		allowed int
+	}{
+		{"burst then block", Limit{Rate: 1, Burst: 3}, 5, 3},
+		{"single burst", Limit{Rate: 0.5, Burst: 1}, 3, 1},
+		{"zero rate blocks", Limit{Rate: 0, Burst: 5}, 2, 0},
+		{"zero burst blocks", Limit{Rate: 10, Burst: 0}, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory()
+			got := 0
+			var lastWait time.Duration
+			for i := 0; i < tt.calls; i++ {
+				ok, wait, err := m.Allow("k", tt.limit)
+				if err != nil {
+					t.Fatalf("Allow: %v", err)
+				}
+				if ok {
+					got++
+					if wait != 0 {
+						t.Errorf("allowed call %d returned wait %v", i, wait)
+					}
+				} else {
+					lastWait = wait
+				}
+			}
+			if got != tt.allowed {
+				t.Errorf("allowed %d of %d calls, want %d", got, tt.calls, tt.allowed)
+			}
+			if got < tt.calls && lastWait <= 0 {
+				t.Errorf("blocked call returned wait %v, want > 0", lastWait)
+			}
+		})
+	}
+
+	t.Run("wait is about one interval", func(t *testing.T) {
+		m := NewMemory()
+		l := Limit{Rate: 2, Burst: 1}
+		m.Allow("k", l)
+		_, wait, _ := m.Allow("k", l)
+		if wait <= 400*time.Millisecond || wait > 500*time.Millisecond {
+			t.Errorf("wait = %v, want ~500ms", wait)
+		}
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		m := NewMemory()
+		l := Limit{Rate: 1, Burst: 1}
+		if ok, _, _ := m.Allow("a", l); !ok {
+			t.Fatal("first call for a blocked")
+		}
+		if ok, _, _ := m.Allow("b", l); !ok {
+			t.Fatal("first call for b blocked by a")
+		}
+		if ok, _, _ := m.Allow("a", l); ok {
+			t.Fatal("second call for a allowed")
+		}
+	})
+
+	t.Run("refills over time", func(t *testing.T) {
+		m := NewMemory()
+		l := Limit{Rate: 100, Burst: 1}
+		m.Allow("k", l)
+		time.Sleep(15 * time.Millisecond)
+		if ok, _, _ := m.Allow("k", l); !ok {
+			t.Fatal("not refilled after one interval")
+		}
+	})
+}
+
+func TestMemoryAttempt(t *testing.T) {
+	p := LockoutPolicy{Threshold: 3, Base: time.Minute, Max: time.Hour, Window: time.Hour}
+
+	t.Run("locks at threshold", func(t *testing.T) {
+		m := NewMemory()
+		for i := 1; i <= 3; i++ {
+			if wait, _ := m.Attempt("k", p); wait != 0 {
+				t.Fatalf("attempt %d blocked for %v", i, wait)
+			}
+		}
+		wait, _ := m.Attempt("k", p)
+		if wait <= 0 || wait > time.Minute {
+			t.Fatalf("attempt after threshold: wait = %v, want (0, 1m]", wait)
+		}
+		if d, _ := m.LockedFor("k"); d <= 0 {
+			t.Fatalf("LockedFor = %v, want > 0", d)
+		}
+		// lần thử bị chặn không được đếm thêm
+		if n := m.failures["k"].count; n != 3 {
+			t.Fatalf("count = %d, want 3", n)
+		}
+	})
+
+	t.Run("forgive lifts lock below threshold", func(t *testing.T) {
+		m := NewMemory()
+		for i := 0; i < 3; i++ {
+			m.Attempt("k", p)
+		}
+		if err := m.Forgive("k", p); err != nil {
+			t.Fatalf("Forgive: %v", err)
+		}
+		if d, _ := m.LockedFor("k"); d != 0 {
+			t.Fatalf("LockedFor after forgive = %v, want 0", d)
+		}
+		if wait, _ := m.Attempt("k", p); wait != 0 {
+			t.Fatalf("attempt after forgive blocked for %v", wait)
+		}
+	})
+
+	t.Run("reset clears counter", func(t *testing.T) {
+		m := NewMemory()
+		m.Attempt("k", p)
+		m.Attempt("k", p)
+		m.Reset("k")
+		for i := 0; i < 2; i++ {
+			m.Attempt("k", p)
+		}
+		if d, _ := m.LockedFor("k"); d != 0 {
+			t.Fatalf("locked after reset: %v", d)
+		}
+	})
+
+	t.Run("forgive unknown key", func(t *testing.T) {
+		if err := NewMemory().Forgive("nope", p); err != nil {
+			t.Fatalf("Forgive: %v", err)
+		}
+	})
+}
+
+func TestLoginGuard(t *testing.T) {
+	g := &LoginGuard{
+		Store: NewMemory(),
+		User:  LockoutPolicy{Threshold: 2, Base: time.Minute, Max: time.Hour, Window: time.Hour},
+		IP:    LockoutPolicy{Threshold: 3, Base: time.Minute, Max: time.Hour, Window: time.Hour},
+	}
+
+	// thành công không làm tăng bộ đếm IP
+	for i := 0; i < 5; i++ {
+		if wait := g.Attempt("alice", "1.2.3.4"); wait != 0 {
+			t.Fatalf("successful login %d blocked for %v", i, wait)
+		}
+		g.Success("alice", "1.2.3.4")
+	}
+
+	// 2 lần sai khoá username, IP còn thử được với user khác
+	g.Attempt("bob", "1.2.3.4")
+	g.Attempt("bob", "1.2.3.4")
+	if wait := g.Attempt("bob", "1.2.3.4"); wait == 0 {
+		t.Fatal("bob not locked after threshold")
+	}
+	if wait := g.Attempt("carol", "1.2.3.4"); wait != 0 {
+		t.Fatalf("carol blocked for %v", wait)
+	}
+	// lần thử bị chặn của bob không tính cho IP: IP mới đếm 3 lần => đã khoá
+	if wait := g.Attempt("dave", "1.2.3.4"); wait == 0 {
+		t.Fatal("ip not locked after threshold")
+	}
+	if wait := g.Attempt("dave", "5.6.7.8"); wait != 0 {
+		t.Fatalf("other ip blocked for %v", wait)
+	}
+}
+
+func TestRedisAttempt(t *testing.T) {
+	srv := miniredis.RunT(t)
+	r, err := NewRedis("redis://" + srv.Addr())
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+	defer r.Close()
+	p := LockoutPolicy{Threshold: 2, Base: time.Minute, Max: time.Hour, Window: time.Hour}
+
+	for i := 1; i <= 2; i++ {
+		if wait, err := r.Attempt("k", p); err != nil || wait != 0 {
+			t.Fatalf("attempt %d: wait %v, err %v", i, wait, err)
+		}
+	}
+	wait, err := r.Attempt("k", p)
+	if err != nil || wait <= 0 || wait > time.Minute {
+		t.Fatalf("attempt after threshold: wait %v, err %v", wait, err)
+	}
+	if err := r.Forgive("k", p); err != nil {
+		t.Fatalf("Forgive: %v", err)
+	}
+	if d, _ := r.LockedFor("k"); d != 0 {
+		t.Fatalf("LockedFor after forgive = %v, want 0", d)
+	}
+	if err := r.Forgive("unknown", p); err != nil {
+		t.Fatalf("Forgive unknown: %v", err)
+	}
+	if srv.Exists(r.prefix + "fail:unknown") {
+		t.Fatal("forgive left a negative counter behind")
+	}
+}
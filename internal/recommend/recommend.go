@@ -0,0 +1,367 @@
+package recommend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"mangahub/internal/logging"
+	"mangahub/internal/manga"
+)
+
+var logger = logging.For("recommend")
+
+// trọng số khi trộn các loại tương đồng
+const (
+	weightCoRead = 0.6
+	weightGenre  = 0.3
+	weightAuthor = 0.1
+
+	// chỉ giữ top N manga tương tự cho mỗi manga
+	neighborsPerManga = 20
+)
+
+type Recommendation struct {
+	Manga  manga.Manga `json:"manga"`
+	Score  float64     `json:"score"`
+	Reason string      `json:"reason"` // "similar" | "popular"
+}
+
+type item struct {
+	id     string
+	author string
+	genres map[string]bool
+}
+
+// Precompute tính lại bảng manga_similarity (item-item) từ user_progress và genre/author
+func Precompute(db *sql.DB) (int, error) {
+	items, err := loadItems(db)
+	if err != nil {
+		return 0, err
+	}
+	readers, err := loadReaders(db)
+	if err != nil {
+		return 0, err
+	}
+
+	// co-occurrence: số user có cả a và b trong thư viện
+	perManga := map[string]int{}
+	co := map[[2]string]int{}
+	for _, lib := range readers {
+		for i := range lib {
+			perManga[lib[i]]++
+			for j := i + 1; j < len(lib); j++ {
+				a, b := lib[i], lib[j]
+				if a > b {
+					a, b = b, a
+				}
+				co[[2]string{a, b}]++
+			}
+		}
+	}
+
+	type neighbor struct {
+		id    string
+		score float64
+	}
+	sims := map[string][]neighbor{}
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			a, b := items[i], items[j]
+			key := [2]string{a.id, b.id}
+			if key[0] > key[1] {
+				key[0], key[1] = key[1], key[0]
+			}
+
+			var coScore float64
+			if n := co[key]; n > 0 {
+				// cosine trên vector user nhị phân
+				coScore = float64(n) / math.Sqrt(float64(perManga[a.id]*perManga[b.id]))
+			}
+			score := weightCoRead*coScore + weightGenre*jaccard(a.genres, b.genres)
+			if a.author != "" && a.author == b.author {
+				score += weightAuthor
+			}
+			if score <= 0 {
+				continue
+			}
+			sims[a.id] = append(sims[a.id], neighbor{b.id, score})
+			sims[b.id] = append(sims[b.id], neighbor{a.id, score})
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM manga_similarity`); err != nil {
+		return 0, err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO manga_similarity(manga_id, similar_id, score) VALUES(?,?,?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	rowsWritten := 0
+	for id, list := range sims {
+		sort.Slice(list, func(i, j int) bool { return list[i].score > list[j].score })
+		if len(list) > neighborsPerManga {
+			list = list[:neighborsPerManga]
+		}
+		for _, n := range list {
+			if _, err := stmt.Exec(id, n.id, n.score); err != nil {
+				return 0, err
+			}
+			rowsWritten++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return rowsWritten, nil
+}
+
+// RunPeriodic chạy Precompute ngay và sau đó mỗi `every` (gọi trong goroutine)
+func RunPeriodic(db *sql.DB, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		if n, err := Precompute(db); err != nil {
+			logger.Error("precompute failed", logging.Err(err))
+		} else {
+			logger.Info("refreshed similarity rows", slog.Int("rows", n))
+		}
+		<-ticker.C
+	}
+}
+
+// ForUser gợi ý manga chưa có trong thư viện của user; nếu chưa có dữ liệu thì fallback theo độ phổ biến
+func ForUser(db *sql.DB, userID string, limit int) ([]Recommendation, error) {
+	rows, err := db.Query(`
+	SELECT s.similar_id, SUM(s.score) AS total
+	FROM user_progress p
+	JOIN manga_similarity s ON s.manga_id = p.manga_id
+	WHERE p.user_id = ? AND p.status != 'dropped'
+	  AND s.similar_id NOT IN (SELECT manga_id FROM user_progress WHERE user_id = ?)
+	GROUP BY s.similar_id
+	ORDER BY total DESC
+	LIMIT ?`, userID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	res, err := collect(db, rows, "similar")
+	if err != nil {
+		return nil, err
+	}
+	if len(res) > 0 {
+		return res, nil
+	}
+	return popularInPreferredGenres(db, userID, limit)
+}
+
+// cold start: manga phổ biến nhất (theo số người đọc) trong các genre user hay đọc
+func popularInPreferredGenres(db *sql.DB, userID string, limit int) ([]Recommendation, error) {
+	genres, err := preferredGenres(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `SELECT m.id, (SELECT COUNT(*) FROM user_progress p WHERE p.manga_id = m.id) AS readers
+	      FROM manga m
+	      WHERE m.id NOT IN (SELECT manga_id FROM user_progress WHERE user_id = ?)`
+	args := []any{userID}
+	if len(genres) > 0 {
+		q += " AND ("
+		for i, g := range genres {
+			if i > 0 {
+				q += " OR "
+			}
+			q += "m.genres LIKE ?"
+			args = append(args, `%"`+g+`"%`)
+		}
+		q += ")"
+	}
+	q += " ORDER BY readers DESC, m.title ASC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return collect(db, rows, "popular")
+}
+
+// top 3 genre trong thư viện của user
+func preferredGenres(db *sql.DB, userID string) ([]string, error) {
+	rows, err := db.Query(`SELECT m.genres FROM user_progress p JOIN manga m ON m.id = p.manga_id WHERE p.user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var genresJSON sql.NullString
+		if err := rows.Scan(&genresJSON); err != nil {
+			return nil, err
+		}
+		var list []string
+		if json.Unmarshal([]byte(genresJSON.String), &list) == nil {
+			for _, g := range list {
+				counts[g]++
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	genres := make([]string, 0, len(counts))
+	for g := range counts {
+		genres = append(genres, g)
+	}
+	sort.Slice(genres, func(i, j int) bool {
+		if counts[genres[i]] != counts[genres[j]] {
+			return counts[genres[i]] > counts[genres[j]]
+		}
+		return genres[i] < genres[j]
+	})
+	if len(genres) > 3 {
+		genres = genres[:3]
+	}
+	return genres, nil
+}
+
+// đọc (id, score) rồi load chi tiết manga
+func collect(db *sql.DB, rows *sql.Rows, reason string) ([]Recommendation, error) {
+	type scored struct {
+		id    string
+		score float64
+	}
+	var list []scored
+	for rows.Next() {
+		var s scored
+		if err := rows.Scan(&s.id, &s.score); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	res := make([]Recommendation, 0, len(list))
+	for _, s := range list {
+		m, err := manga.GetByID(context.Background(), db, s.id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, Recommendation{Manga: m, Score: s.score, Reason: reason})
+	}
+	return res, nil
+}
+
+func loadItems(db *sql.DB) ([]item, error) {
+	rows, err := db.Query(`SELECT id, COALESCE(author, ''), COALESCE(genres, '') FROM manga`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []item
+	for rows.Next() {
+		var it item
+		var genresJSON string
+		if err := rows.Scan(&it.id, &it.author, &genresJSON); err != nil {
+			return nil, err
+		}
+		it.genres = map[string]bool{}
+		var list []string
+		if json.Unmarshal([]byte(genresJSON), &list) == nil {
+			for _, g := range list {
+				it.genres[g] = true
+			}
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// user_id -> danh sách manga trong thư viện (bỏ qua dropped)
+func loadReaders(db *sql.DB) (map[string][]string, error) {
+	rows, err := db.Query(`SELECT user_id, manga_id FROM user_progress WHERE status != 'dropped'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	readers := map[string][]string{}
+	for rows.Next() {
+		var userID, mangaID string
+		if err := rows.Scan(&userID, &mangaID); err != nil {
+			return nil, err
+		}
+		readers[userID] = append(readers[userID], mangaID)
+	}
+	return readers, rows.Err()
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for g := range a {
+		if b[g] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// Similar trả về id các manga tương tự nhất (theo manga_similarity) cho mỗi manga, tối đa limit
+func Similar(db *sql.DB, mangaIDs []string, limit int) (map[string][]string, error) {
+	out := make(map[string][]string, len(mangaIDs))
+	args := make([]any, 0, len(mangaIDs)+1)
+	for _, id := range mangaIDs {
+		out[id] = []string{}
+		args = append(args, id)
+	}
+	if len(mangaIDs) == 0 {
+		return out, nil
+	}
+	args = append(args, limit)
+	rows, err := db.Query(`
+	SELECT manga_id, similar_id FROM (
+		SELECT manga_id, similar_id, ROW_NUMBER() OVER (PARTITION BY manga_id ORDER BY score DESC, similar_id) AS rn
+		FROM manga_similarity WHERE manga_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(mangaIDs)), ",")+`)
+	) WHERE rn <= ? ORDER BY manga_id, rn`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, similar string
+		if err := rows.Scan(&id, &similar); err != nil {
+			return nil, err
+		}
+		out[id] = append(out[id], similar)
+	}
+	return out, rows.Err()
+}
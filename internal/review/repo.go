@@ -0,0 +1,266 @@
+package review
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	MinScore = 1
+	MaxScore = 10
+)
+
+var (
+	ErrInvalidScore = errors.New("score must be between 1 and 10")
+	ErrOwnReview    = errors.New("cannot vote on your own review")
+)
+
+// 1 user chỉ có 1 review (rating + text tuỳ chọn) cho mỗi manga
+type Review struct {
+	ID           int64     `json:"id"`
+	UserID       string    `json:"user_id"`
+	Username     string    `json:"username"`
+	MangaID      string    `json:"manga_id"`
+	Score        int       `json:"score"`
+	Body         string    `json:"body"`
+	Spoiler      bool      `json:"spoiler"`
+	HelpfulCount int       `json:"helpful_count"`
+	Hidden       bool      `json:"hidden,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Tổng hợp rating của 1 manga; Histogram[i] = số lượt chấm điểm i+1
+type Summary struct {
+	Average   float64 `json:"average"`
+	Count     int     `json:"count"`
+	Histogram []int   `json:"histogram"`
+}
+
+// Upsert tạo hoặc sửa review của user cho manga
+func Upsert(db *sql.DB, r Review) error {
+	if r.Score < MinScore || r.Score > MaxScore {
+		return ErrInvalidScore
+	}
+	_, err := db.Exec(`
+	INSERT INTO reviews(user_id, manga_id, score, body, spoiler)
+	VALUES(?,?,?,?,?)
+	ON CONFLICT(user_id, manga_id)
+	DO UPDATE SET score=excluded.score,
+	              body=excluded.body,
+	              spoiler=excluded.spoiler,
+	              updated_at=CURRENT_TIMESTAMP
+	`, r.UserID, r.MangaID, r.Score, r.Body, r.Spoiler)
+	return err
+}
+
+// Delete xoá review của user cho manga cùng các vote của nó (không có FK cascade)
+func Delete(db *sql.DB, userID, mangaID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM review_votes WHERE review_id IN (SELECT id FROM reviews WHERE user_id=? AND manga_id=?)`,
+		userID, mangaID); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`DELETE FROM reviews WHERE user_id=? AND manga_id=?`, userID, mangaID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// GetSummary bỏ qua các review đã bị admin ẩn
+func GetSummary(db *sql.DB, mangaID string) (Summary, error) {
+	s := Summary{Histogram: make([]int, MaxScore)}
+
+	rows, err := db.Query(`SELECT score, COUNT(*) FROM reviews WHERE manga_id=? AND hidden=0 GROUP BY score`, mangaID)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+
+	total := 0
+	for rows.Next() {
+		var score, n int
+		if err := rows.Scan(&score, &n); err != nil {
+			return s, err
+		}
+		if score < MinScore || score > MaxScore {
+			continue
+		}
+		s.Histogram[score-1] = n
+		s.Count += n
+		total += score * n
+	}
+	if s.Count > 0 {
+		s.Average = float64(total) / float64(s.Count)
+	}
+	return s, rows.Err()
+}
+
+// Summaries như GetSummary cho nhiều manga 1 lần (dataloader GraphQL); manga chưa có review
+// vẫn có Summary rỗng trong map
+func Summaries(db *sql.DB, mangaIDs []string) (map[string]Summary, error) {
+	out := make(map[string]Summary, len(mangaIDs))
+	args := make([]any, len(mangaIDs))
+	for i, id := range mangaIDs {
+		out[id] = Summary{Histogram: make([]int, MaxScore)}
+		args[i] = id
+	}
+	if len(args) == 0 {
+		return out, nil
+	}
+	rows, err := db.Query(`SELECT manga_id, score, COUNT(*) FROM reviews WHERE hidden=0 AND manga_id IN (`+placeholders(len(args))+`)
+	GROUP BY manga_id, score`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := map[string]int{}
+	for rows.Next() {
+		var mangaID string
+		var score, n int
+		if err := rows.Scan(&mangaID, &score, &n); err != nil {
+			return nil, err
+		}
+		if score < MinScore || score > MaxScore {
+			continue
+		}
+		s := out[mangaID]
+		s.Histogram[score-1] = n
+		s.Count += n
+		out[mangaID] = s
+		totals[mangaID] += score * n
+	}
+	for id, s := range out {
+		if s.Count > 0 {
+			s.Average = float64(totals[id]) / float64(s.Count)
+			out[id] = s
+		}
+	}
+	return out, rows.Err()
+}
+
+const helpfulExpr = `(SELECT COUNT(*) FROM review_votes v WHERE v.review_id = r.id)`
+
+// cột có alias để dùng được cả trong subquery (ListTop)
+const reviewCols = `r.id AS id, r.user_id AS user_id, COALESCE(u.username, r.user_id) AS username, r.manga_id AS manga_id,
+	             r.score AS score, COALESCE(r.body, '') AS body, r.spoiler AS spoiler, ` + helpfulExpr + ` AS helpful,
+	             r.hidden AS hidden, r.created_at AS created_at, r.updated_at AS updated_at`
+
+// orderBy theo sortBy: "helpful" (mặc định) hoặc "recent"
+func orderBy(sortBy string) string {
+	if sortBy == "recent" {
+		return "r.updated_at DESC, r.id DESC"
+	}
+	return helpfulExpr + " DESC, r.updated_at DESC"
+}
+
+// List trả về review công khai; sortBy: "helpful" (mặc định) hoặc "recent"
+func List(db *sql.DB, mangaID, sortBy string, limit, offset int) ([]Review, error) {
+	q := `SELECT ` + reviewCols + `
+	      FROM reviews r LEFT JOIN users u ON u.id = r.user_id
+	      WHERE r.manga_id=? AND r.hidden=0
+	      ORDER BY ` + orderBy(sortBy) + ` LIMIT ? OFFSET ?`
+
+	rows, err := db.Query(q, mangaID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []Review{}
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// ListTop lấy tối đa limit review đầu (theo sortBy) của mỗi manga trong 1 truy vấn
+func ListTop(db *sql.DB, mangaIDs []string, sortBy string, limit int) (map[string][]Review, error) {
+	out := make(map[string][]Review, len(mangaIDs))
+	args := make([]any, 0, len(mangaIDs)+1)
+	for _, id := range mangaIDs {
+		out[id] = []Review{}
+		args = append(args, id)
+	}
+	if len(mangaIDs) == 0 {
+		return out, nil
+	}
+	args = append(args, limit)
+	q := `SELECT id, user_id, username, manga_id, score, body, spoiler, helpful, hidden, created_at, updated_at FROM (
+	        SELECT ` + reviewCols + `, ROW_NUMBER() OVER (PARTITION BY r.manga_id ORDER BY ` + orderBy(sortBy) + `) AS rn
+	        FROM reviews r LEFT JOIN users u ON u.id = r.user_id
+	        WHERE r.hidden=0 AND r.manga_id IN (` + placeholders(len(mangaIDs)) + `)
+	      ) WHERE rn <= ? ORDER BY manga_id, rn`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		out[r.MangaID] = append(out[r.MangaID], r)
+	}
+	return out, rows.Err()
+}
+
+func scanReview(rows *sql.Rows) (Review, error) {
+	var r Review
+	err := rows.Scan(&r.ID, &r.UserID, &r.Username, &r.MangaID, &r.Score, &r.Body, &r.Spoiler,
+		&r.HelpfulCount, &r.Hidden, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// Vote đánh dấu review là hữu ích (mỗi user 1 lần, không tự vote review của mình)
+func Vote(db *sql.DB, reviewID int64, userID string) error {
+	var owner string
+	if err := db.QueryRow(`SELECT user_id FROM reviews WHERE id=? AND hidden=0`, reviewID).Scan(&owner); err != nil {
+		return err
+	}
+	if owner == userID {
+		return ErrOwnReview
+	}
+	_, err := db.Exec(`INSERT OR IGNORE INTO review_votes(review_id, user_id) VALUES(?,?)`, reviewID, userID)
+	return err
+}
+
+func Unvote(db *sql.DB, reviewID int64, userID string) error {
+	_, err := db.Exec(`DELETE FROM review_votes WHERE review_id=? AND user_id=?`, reviewID, userID)
+	return err
+}
+
+// SetHidden là hook moderation cho admin (ẩn/hiện review vi phạm)
+func SetHidden(db *sql.DB, reviewID int64, hidden bool, reason, moderatorID string) error {
+	res, err := db.Exec(`UPDATE reviews SET hidden=?, hidden_reason=?, moderated_by=? WHERE id=?`,
+		hidden, reason, moderatorID, reviewID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package social
+
+import (
+	"database/sql"
+	"time"
+
+	"mangahub/internal/stats"
+)
+
+// ProfileStats là phần tóm tắt của stats.Stats hiển thị công khai
+type ProfileStats struct {
+	TotalManga        int               `json:"total_manga"`
+	StatusCounts      map[string]int    `json:"status_counts"`
+	CompletionRate    float64           `json:"completion_rate"`
+	ChaptersThisMonth int               `json:"chapters_this_month"`
+	CurrentStreak     int               `json:"current_streak"`
+	LongestStreak     int               `json:"longest_streak"`
+	TopGenres         []stats.NameCount `json:"top_genres"`
+}
+
+type ListEntry struct {
+	MangaID        string `json:"manga_id"`
+	Title          string `json:"title"`
+	CurrentChapter int    `json:"current_chapter"`
+	Status         string `json:"status"`
+}
+
+// Profile công khai của 1 user; Stats/Lists bỏ trống khi profile private
+type Profile struct {
+	Username    string                 `json:"username"`
+	JoinedAt    time.Time              `json:"joined_at"`
+	Followers   int                    `json:"followers"`
+	Following   int                    `json:"following"`
+	IsFollowing bool                   `json:"is_following"` // viewer có đang follow không
+	Private     bool                   `json:"private"`
+	Stats       *ProfileStats          `json:"stats,omitempty"`
+	Lists       map[string][]ListEntry `json:"lists,omitempty"`
+}
+
+// GetProfile dựng profile của userID theo góc nhìn viewerID ("" = chưa login).
+// Chính chủ luôn thấy đầy đủ, kể cả list private.
+func GetProfile(db *sql.DB, userID, username string, joinedAt time.Time, viewerID string) (Profile, error) {
+	p := Profile{Username: username, JoinedAt: joinedAt}
+
+	var err error
+	if p.Followers, p.Following, err = Counts(db, userID); err != nil {
+		return Profile{}, err
+	}
+	if viewerID != "" && viewerID != userID {
+		if p.IsFollowing, err = IsFollowing(db, viewerID, userID); err != nil {
+			return Profile{}, err
+		}
+	}
+
+	privacy, err := GetPrivacy(db, userID)
+	if err != nil {
+		return Profile{}, err
+	}
+	self := viewerID == userID
+	if !privacy.ProfilePublic && !self {
+		p.Private = true
+		return p, nil
+	}
+
+	statsFor := stats.Public
+	if self {
+		statsFor = stats.ForUser
+	}
+	st, err := statsFor(db, userID, time.Now())
+	if err != nil {
+		return Profile{}, err
+	}
+	top := st.Genres
+	if len(top) > 5 {
+		top = top[:5]
+	}
+	p.Stats = &ProfileStats{
+		TotalManga:        st.TotalManga,
+		StatusCounts:      st.StatusCounts,
+		CompletionRate:    st.CompletionRate,
+		ChaptersThisMonth: st.ChaptersThisMonth,
+		CurrentStreak:     st.CurrentStreak,
+		LongestStreak:     st.LongestStreak,
+		TopGenres:         top,
+	}
+
+	hidden := map[string]bool{}
+	if !self {
+		for _, name := range privacy.PrivateLists {
+			hidden[name] = true
+		}
+	}
+	if p.Lists, err = lists(db, userID, hidden); err != nil {
+		return Profile{}, err
+	}
+	return p, nil
+}
+
+func lists(db *sql.DB, userID string, hidden map[string]bool) (map[string][]ListEntry, error) {
+	rows, err := db.Query(`
+	SELECT COALESCE(p.list_name, 'default'), p.manga_id, COALESCE(m.title, ''), p.current_chapter, COALESCE(p.status, '')
+	FROM user_progress p LEFT JOIN manga m ON m.id = p.manga_id
+	WHERE p.user_id=? ORDER BY p.updated_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[string][]ListEntry{}
+	for rows.Next() {
+		var list string
+		var e ListEntry
+		if err := rows.Scan(&list, &e.MangaID, &e.Title, &e.CurrentChapter, &e.Status); err != nil {
+			return nil, err
+		}
+		if hidden[list] {
+			continue
+		}
+		res[list] = append(res[list], e)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,185 @@
+package social
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrSelfFollow = errors.New("cannot follow yourself")
+
+// Privacy của user; mặc định public hết
+type Privacy struct {
+	ProfilePublic  bool     `json:"profile_public"`  // false: profile chỉ hiện username + follow counts
+	ActivityPublic bool     `json:"activity_public"` // false: không xuất hiện trong feed của follower
+	PrivateLists   []string `json:"private_lists"`   // reading list ẩn khỏi profile và feed
+}
+
+func DefaultPrivacy() Privacy {
+	return Privacy{ProfilePublic: true, ActivityPublic: true, PrivateLists: []string{}}
+}
+
+// Activity là 1 dòng trong feed: progress hoặc thay đổi library của người được follow
+type Activity struct {
+	ID         int64     `json:"id"`
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	Type       string    `json:"type"` // library | progress
+	MangaID    string    `json:"manga_id"`
+	MangaTitle string    `json:"manga_title"`
+	Chapter    int       `json:"chapter"`
+	Status     string    `json:"status"`
+	ListName   string    `json:"list_name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func Follow(db *sql.DB, followerID, followeeID string) error {
+	if followerID == followeeID {
+		return ErrSelfFollow
+	}
+	_, err := db.Exec(`INSERT OR IGNORE INTO user_follows(follower_id, followee_id) VALUES(?,?)`, followerID, followeeID)
+	return err
+}
+
+func Unfollow(db *sql.DB, followerID, followeeID string) error {
+	_, err := db.Exec(`DELETE FROM user_follows WHERE follower_id=? AND followee_id=?`, followerID, followeeID)
+	return err
+}
+
+func IsFollowing(db *sql.DB, followerID, followeeID string) (bool, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM user_follows WHERE follower_id=? AND followee_id=?`, followerID, followeeID).Scan(&n)
+	return n > 0, err
+}
+
+// Counts trả về số follower và số người đang follow
+func Counts(db *sql.DB, userID string) (followers, following int, err error) {
+	err = db.QueryRow(`
+	SELECT (SELECT COUNT(*) FROM user_follows WHERE followee_id=?),
+	       (SELECT COUNT(*) FROM user_follows WHERE follower_id=?)`, userID, userID).Scan(&followers, &following)
+	return
+}
+
+// VisibleFollowing: người userID follow và đang để activity public
+// (dùng cho live feed, implement feed.FollowingFunc)
+func VisibleFollowing(db *sql.DB, userID string) ([]string, error) {
+	rows, err := db.Query(`
+	SELECT f.followee_id FROM user_follows f
+	LEFT JOIN user_privacy p ON p.user_id = f.followee_id
+	WHERE f.follower_id=? AND COALESCE(p.activity_public, 1) = 1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func GetPrivacy(db *sql.DB, userID string) (Privacy, error) {
+	p := DefaultPrivacy()
+	err := db.QueryRow(`SELECT profile_public, activity_public FROM user_privacy WHERE user_id=?`, userID).
+		Scan(&p.ProfilePublic, &p.ActivityPublic)
+	if err != nil && err != sql.ErrNoRows {
+		return Privacy{}, err
+	}
+
+	rows, err := db.Query(`SELECT list_name FROM list_privacy WHERE user_id=? ORDER BY list_name`, userID)
+	if err != nil {
+		return Privacy{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return Privacy{}, err
+		}
+		p.PrivateLists = append(p.PrivateLists, name)
+	}
+	return p, rows.Err()
+}
+
+// ActivityHidden: activity của userID trên listName bị ẩn với người khác
+// (activity_public=false hoặc list private), cùng rule với Feed
+func ActivityHidden(db *sql.DB, userID, listName string) (bool, error) {
+	p, err := GetPrivacy(db, userID)
+	if err != nil {
+		return true, err
+	}
+	return !p.ActivityPublic || slices.Contains(p.PrivateLists, listName), nil
+}
+
+func SetPrivacy(db *sql.DB, userID string, p Privacy) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`
+	INSERT INTO user_privacy(user_id, profile_public, activity_public) VALUES(?,?,?)
+	ON CONFLICT(user_id) DO UPDATE SET profile_public=excluded.profile_public, activity_public=excluded.activity_public`,
+		userID, p.ProfilePublic, p.ActivityPublic); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM list_privacy WHERE user_id=?`, userID); err != nil {
+		return err
+	}
+	for _, name := range p.PrivateLists {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO list_privacy(user_id, list_name) VALUES(?,?)`, userID, name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Feed: activity của người viewer follow, mới -> cũ, phân trang bằng id < beforeID (0 = mới nhất).
+// Bỏ qua user tắt activity_public và event thuộc list private.
+func Feed(db *sql.DB, viewerID string, beforeID int64, limit int) ([]Activity, error) {
+	q := `
+	SELECT h.id, h.user_id, u.username, COALESCE(h.event, 'progress'), h.manga_id, COALESCE(m.title, ''),
+	       h.chapter, COALESCE(h.status, ''), COALESCE(h.list_name, 'default'), h.read_at
+	FROM reading_history h
+	JOIN user_follows f ON f.followee_id = h.user_id AND f.follower_id = ?
+	JOIN users u ON u.id = h.user_id
+	LEFT JOIN manga m ON m.id = h.manga_id
+	LEFT JOIN user_privacy p ON p.user_id = h.user_id
+	WHERE COALESCE(p.activity_public, 1) = 1
+	  AND NOT EXISTS (SELECT 1 FROM list_privacy lp WHERE lp.user_id = h.user_id AND lp.list_name = COALESCE(h.list_name, 'default'))`
+	args := []any{viewerID}
+	if beforeID > 0 {
+		q += " AND h.id < ?"
+		args = append(args, beforeID)
+	}
+	q += " ORDER BY h.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []Activity{}
+	for rows.Next() {
+		var a Activity
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Username, &a.Type, &a.MangaID, &a.MangaTitle,
+			&a.Chapter, &a.Status, &a.ListName, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,270 @@
+package stats
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// số chapter đọc trong 1 khoảng (ngày "2006-01-02", tuần "2006-W01", tháng "2006-01")
+type PeriodCount struct {
+	Period   string `json:"period"`
+	Chapters int    `json:"chapters"`
+}
+
+// dùng cho genre / author breakdown
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Dashboard cá nhân của 1 user
+type Stats struct {
+	UserID            string         `json:"user_id"`
+	TotalManga        int            `json:"total_manga"`
+	StatusCounts      map[string]int `json:"status_counts"`
+	CompletionRate    float64        `json:"completion_rate"`
+	AvgDaysToFinish   float64        `json:"avg_days_to_finish"`
+	ChaptersToday     int            `json:"chapters_today"`
+	ChaptersThisWeek  int            `json:"chapters_this_week"`
+	ChaptersThisMonth int            `json:"chapters_this_month"`
+	CurrentStreak     int            `json:"current_streak"`
+	LongestStreak     int            `json:"longest_streak"`
+	Daily             []PeriodCount  `json:"daily"`
+	Weekly            []PeriodCount  `json:"weekly"`
+	Monthly           []PeriodCount  `json:"monthly"`
+	Genres            []NameCount    `json:"genres"`
+	Authors           []NameCount    `json:"authors"`
+}
+
+// SQLite lưu CURRENT_TIMESTAMP dạng này (UTC)
+const sqliteTime = "2006-01-02 15:04:05"
+
+// ForUser tính toàn bộ thống kê từ user_progress + reading_history, mốc thời gian là now
+func ForUser(db *sql.DB, userID string, now time.Time) (Stats, error) {
+	return forUser(db, userID, now, "")
+}
+
+// Public như ForUser nhưng bỏ các manga trong list private (list_privacy), dùng cho profile người khác xem
+func Public(db *sql.DB, userID string, now time.Time) (Stats, error) {
+	return forUser(db, userID, now, `
+	AND NOT EXISTS (SELECT 1 FROM list_privacy lp WHERE lp.user_id = t.user_id AND lp.list_name = COALESCE(NULLIF(t.list_name, ''), 'default'))`)
+}
+
+// hide là điều kiện thêm vào WHERE của mọi truy vấn (bảng được alias là t)
+func forUser(db *sql.DB, userID string, now time.Time, hide string) (Stats, error) {
+	now = now.UTC()
+	s := Stats{UserID: userID, StatusCounts: map[string]int{}}
+
+	if err := statusCounts(db, userID, hide, &s); err != nil {
+		return Stats{}, err
+	}
+	if err := avgDaysToFinish(db, userID, hide, &s); err != nil {
+		return Stats{}, err
+	}
+	if err := breakdowns(db, userID, hide, &s); err != nil {
+		return Stats{}, err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	// tuần bắt đầu từ thứ 2
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var err error
+	if s.ChaptersToday, err = chaptersSince(db, userID, hide, today); err != nil {
+		return Stats{}, err
+	}
+	if s.ChaptersThisWeek, err = chaptersSince(db, userID, hide, weekStart); err != nil {
+		return Stats{}, err
+	}
+	if s.ChaptersThisMonth, err = chaptersSince(db, userID, hide, monthStart); err != nil {
+		return Stats{}, err
+	}
+
+	if s.Daily, err = chaptersBy(db, userID, hide, "%Y-%m-%d", today.AddDate(0, 0, -29)); err != nil {
+		return Stats{}, err
+	}
+	if s.Weekly, err = chaptersBy(db, userID, hide, "%Y-W%W", weekStart.AddDate(0, 0, -7*11)); err != nil {
+		return Stats{}, err
+	}
+	if s.Monthly, err = chaptersBy(db, userID, hide, "%Y-%m", monthStart.AddDate(0, -11, 0)); err != nil {
+		return Stats{}, err
+	}
+
+	if err := streaks(db, userID, hide, today, &s); err != nil {
+		return Stats{}, err
+	}
+	return s, nil
+}
+
+func statusCounts(db *sql.DB, userID, hide string, s *Stats) error {
+	rows, err := db.Query(`SELECT COALESCE(status, ''), COUNT(*) FROM user_progress t WHERE user_id=?`+hide+` GROUP BY status`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return err
+		}
+		if status == "" {
+			status = "unknown"
+		}
+		s.StatusCounts[status] += n
+		s.TotalManga += n
+	}
+	if s.TotalManga > 0 {
+		s.CompletionRate = float64(s.StatusCounts["completed"]) / float64(s.TotalManga)
+	}
+	return rows.Err()
+}
+
+// thời gian hoàn thành = lần đầu đọc -> lần đầu chuyển sang completed
+func avgDaysToFinish(db *sql.DB, userID, hide string, s *Stats) error {
+	var avg sql.NullFloat64
+	err := db.QueryRow(`
+	SELECT AVG(done - started) FROM (
+		SELECT julianday(MIN(read_at)) AS started,
+		       julianday(MIN(CASE WHEN status='completed' THEN read_at END)) AS done
+		FROM reading_history t WHERE user_id=?`+hide+` GROUP BY manga_id
+	) WHERE done IS NOT NULL`, userID).Scan(&avg)
+	if err != nil {
+		return err
+	}
+	s.AvgDaysToFinish = avg.Float64
+	return nil
+}
+
+func breakdowns(db *sql.DB, userID, hide string, s *Stats) error {
+	rows, err := db.Query(`
+	SELECT COALESCE(m.author, ''), COALESCE(m.genres, '')
+	FROM user_progress t JOIN manga m ON m.id = t.manga_id
+	WHERE t.user_id=?`+hide, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	genres := map[string]int{}
+	authors := map[string]int{}
+	for rows.Next() {
+		var author, genresJSON string
+		if err := rows.Scan(&author, &genresJSON); err != nil {
+			return err
+		}
+		if author != "" {
+			authors[author]++
+		}
+		var list []string
+		if json.Unmarshal([]byte(genresJSON), &list) == nil {
+			for _, g := range list {
+				genres[g]++
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.Genres = sortedCounts(genres)
+	s.Authors = sortedCounts(authors)
+	return nil
+}
+
+func chaptersSince(db *sql.DB, userID, hide string, since time.Time) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COALESCE(SUM(chapters_read), 0) FROM reading_history t WHERE user_id=? AND read_at >= ?`+hide,
+		userID, since.Format(sqliteTime)).Scan(&n)
+	return n, err
+}
+
+func chaptersBy(db *sql.DB, userID, hide, format string, since time.Time) ([]PeriodCount, error) {
+	rows, err := db.Query(`
+	SELECT strftime(?, read_at) AS period, SUM(chapters_read)
+	FROM reading_history t
+	WHERE user_id=? AND read_at >= ? AND chapters_read > 0`+hide+`
+	GROUP BY period ORDER BY period`, format, userID, since.Format(sqliteTime))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []PeriodCount{}
+	for rows.Next() {
+		var pc PeriodCount
+		if err := rows.Scan(&pc.Period, &pc.Chapters); err != nil {
+			return nil, err
+		}
+		res = append(res, pc)
+	}
+	return res, rows.Err()
+}
+
+// streak = số ngày liên tiếp có đọc; current streak vẫn tính nếu hôm nay chưa đọc nhưng hôm qua có
+func streaks(db *sql.DB, userID, hide string, today time.Time, s *Stats) error {
+	rows, err := db.Query(`SELECT DISTINCT date(read_at) FROM reading_history t WHERE user_id=? AND chapters_read > 0`+hide+` ORDER BY 1 DESC`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			return err
+		}
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			continue
+		}
+		days = append(days, t)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(days) == 0 {
+		return nil
+	}
+
+	run := 1
+	s.LongestStreak = 1
+	for i := 1; i < len(days); i++ {
+		if days[i-1].Sub(days[i]) == 24*time.Hour {
+			run++
+		} else {
+			run = 1
+		}
+		if run > s.LongestStreak {
+			s.LongestStreak = run
+		}
+	}
+
+	if gap := today.Sub(days[0]); gap > 24*time.Hour {
+		return nil
+	}
+	s.CurrentStreak = 1
+	for i := 1; i < len(days) && days[i-1].Sub(days[i]) == 24*time.Hour; i++ {
+		s.CurrentStreak++
+	}
+	return nil
+}
+
+func sortedCounts(m map[string]int) []NameCount {
+	res := make([]NameCount, 0, len(m))
+	for name, n := range m {
+		res = append(res, NameCount{Name: name, Count: n})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
@@ -0,0 +1,188 @@
+package stats
+
+import (
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"mangahub/pkg/database"
+	"mangahub/pkg/models"
+)
+
+var testCatalog = []models.Manga{
+	{ID: "m1", Title: "One", Author: "Kei", Genres: []string{"action", "drama"}, Status: "ongoing", TotalChapters: 100},
+	{ID: "m2", Title: "Two", Author: "Ann", Genres: []string{"drama"}, Status: "completed", TotalChapters: 20},
+	{ID: "m3", Title: "Three", Author: "Kei", Genres: []string{"horror"}, Status: "ongoing", TotalChapters: 50},
+}
+
+// now của mọi test: thứ 4 2026-10-14, tuần bắt đầu thứ 2 12/10
+var testNow = time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := database.SeedManga(db, testCatalog); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	return db
+}
+
+func addProgress(t *testing.T, db *sql.DB, user, mangaID, status, list string) {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO user_progress (user_id, manga_id, current_chapter, status, list_name) VALUES (?, ?, 1, ?, ?)`,
+		user, mangaID, status, list); err != nil {
+		t.Fatalf("insert progress: %v", err)
+	}
+}
+
+func addRead(t *testing.T, db *sql.DB, user, mangaID, list, at string, chapters int, status string) {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO reading_history (user_id, manga_id, chapter, chapters_read, status, read_at, list_name) VALUES (?, ?, 0, ?, ?, ?, ?)`,
+		user, mangaID, chapters, status, at, list); err != nil {
+		t.Fatalf("insert history: %v", err)
+	}
+}
+
+// seedHistory: chuỗi 12/10-14/10 nối với 11/10 (4 ngày tới hôm nay), 10/10 chỉ đổi status
+// (chapters_read 0, không tính streak), chuỗi dài nhất 19/9-23/9 (5 ngày)
+func seedHistory(t *testing.T, db *sql.DB) {
+	addProgress(t, db, "u1", "m1", "reading", "")
+	addProgress(t, db, "u1", "m2", "completed", "")
+	addRead(t, db, "u1", "m1", "default", "2026-10-14 10:00:00", 3, "reading")
+	addRead(t, db, "u1", "m1", "default", "2026-10-13 09:00:00", 2, "reading")
+	addRead(t, db, "u1", "m1", "default", "2026-10-12 20:00:00", 1, "reading")
+	addRead(t, db, "u1", "m1", "default", "2026-10-11 23:59:59", 4, "reading")
+	addRead(t, db, "u1", "m1", "default", "2026-10-10 12:00:00", 0, "reading")
+	addRead(t, db, "u1", "m1", "default", "2026-10-05 08:00:00", 2, "reading")
+	for day := 19; day <= 23; day++ {
+		addRead(t, db, "u1", "m2", "default", time.Date(2026, 9, day, 12, 0, 0, 0, time.UTC).Format(sqliteTime), 4, "reading")
+	}
+	addRead(t, db, "u1", "m2", "default", "2026-09-23 13:00:00", 0, "completed")
+	// user khác không được lẫn vào
+	addProgress(t, db, "u2", "m3", "reading", "")
+	addRead(t, db, "u2", "m3", "default", "2026-10-14 11:00:00", 9, "reading")
+}
+
+func TestForUser(t *testing.T) {
+	db := newTestDB(t)
+	seedHistory(t, db)
+
+	s, err := ForUser(db, "u1", testNow)
+	if err != nil {
+		t.Fatalf("ForUser: %v", err)
+	}
+
+	if s.TotalManga != 2 || s.StatusCounts["reading"] != 1 || s.StatusCounts["completed"] != 1 || s.CompletionRate != 0.5 {
+		t.Errorf("status: total %d counts %v rate %v", s.TotalManga, s.StatusCounts, s.CompletionRate)
+	}
+	// m2: đọc lần đầu 19/9 12:00, completed 23/9 13:00
+	if want := 4 + 1.0/24; s.AvgDaysToFinish < want-1e-6 || s.AvgDaysToFinish > want+1e-6 {
+		t.Errorf("AvgDaysToFinish = %v, want %v", s.AvgDaysToFinish, want)
+	}
+	if s.ChaptersToday != 3 || s.ChaptersThisWeek != 6 || s.ChaptersThisMonth != 12 {
+		t.Errorf("today/week/month = %d/%d/%d, want 3/6/12", s.ChaptersToday, s.ChaptersThisWeek, s.ChaptersThisMonth)
+	}
+	if s.CurrentStreak != 4 || s.LongestStreak != 5 {
+		t.Errorf("streak current/longest = %d/%d, want 4/5", s.CurrentStreak, s.LongestStreak)
+	}
+
+	wantWeekly := []PeriodCount{{"2026-W37", 8}, {"2026-W38", 12}, {"2026-W40", 6}, {"2026-W41", 6}}
+	if !slices.Equal(s.Weekly, wantWeekly) {
+		t.Errorf("Weekly = %v, want %v", s.Weekly, wantWeekly)
+	}
+	wantMonthly := []PeriodCount{{"2026-09", 20}, {"2026-10", 12}}
+	if !slices.Equal(s.Monthly, wantMonthly) {
+		t.Errorf("Monthly = %v, want %v", s.Monthly, wantMonthly)
+	}
+	if len(s.Daily) != 10 || s.Daily[len(s.Daily)-1] != (PeriodCount{"2026-10-14", 3}) {
+		t.Errorf("Daily = %v", s.Daily)
+	}
+
+	wantGenres := []NameCount{{"drama", 2}, {"action", 1}}
+	if !slices.Equal(s.Genres, wantGenres) {
+		t.Errorf("Genres = %v, want %v", s.Genres, wantGenres)
+	}
+	wantAuthors := []NameCount{{"Ann", 1}, {"Kei", 1}}
+	if !slices.Equal(s.Authors, wantAuthors) {
+		t.Errorf("Authors = %v, want %v", s.Authors, wantAuthors)
+	}
+}
+
+// current streak còn giữ khi hôm nay chưa đọc nhưng hôm qua có, mất khi cách 2 ngày
+func TestStreakGrace(t *testing.T) {
+	db := newTestDB(t)
+	seedHistory(t, db)
+
+	for _, tt := range []struct {
+		now  time.Time
+		want int
+	}{
+		{testNow.AddDate(0, 0, 1), 4},
+		{testNow.AddDate(0, 0, 2), 0},
+	} {
+		s, err := ForUser(db, "u1", tt.now)
+		if err != nil {
+			t.Fatalf("ForUser: %v", err)
+		}
+		if s.CurrentStreak != tt.want || s.LongestStreak != 5 {
+			t.Errorf("now %s: streak current/longest = %d/%d, want %d/5", tt.now.Format(time.DateOnly), s.CurrentStreak, s.LongestStreak, tt.want)
+		}
+	}
+}
+
+// tuần tính từ thứ 2: sáng thứ 2 12/10 thì chủ nhật 11/10 thuộc tuần trước
+func TestWeekStartsMonday(t *testing.T) {
+	db := newTestDB(t)
+	addRead(t, db, "u1", "m1", "default", "2026-10-11 23:59:59", 4, "reading")
+	addRead(t, db, "u1", "m1", "default", "2026-10-12 07:00:00", 1, "reading")
+
+	s, err := ForUser(db, "u1", time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ForUser: %v", err)
+	}
+	if s.ChaptersToday != 1 || s.ChaptersThisWeek != 1 || s.ChaptersThisMonth != 5 {
+		t.Errorf("today/week/month = %d/%d/%d, want 1/1/5", s.ChaptersToday, s.ChaptersThisWeek, s.ChaptersThisMonth)
+	}
+	if s.CurrentStreak != 2 {
+		t.Errorf("CurrentStreak = %d, want 2", s.CurrentStreak)
+	}
+}
+
+func TestPublicHidesPrivateLists(t *testing.T) {
+	db := newTestDB(t)
+	seedHistory(t, db)
+	addProgress(t, db, "u1", "m3", "reading", "secret")
+	addRead(t, db, "u1", "m3", "secret", "2026-10-14 12:00:00", 7, "reading")
+	if _, err := db.Exec(`INSERT INTO list_privacy (user_id, list_name) VALUES ('u1', 'secret')`); err != nil {
+		t.Fatalf("insert privacy: %v", err)
+	}
+
+	own, err := ForUser(db, "u1", testNow)
+	if err != nil {
+		t.Fatalf("ForUser: %v", err)
+	}
+	pub, err := Public(db, "u1", testNow)
+	if err != nil {
+		t.Fatalf("Public: %v", err)
+	}
+	if own.TotalManga != 3 || own.ChaptersToday != 10 {
+		t.Errorf("own total/today = %d/%d, want 3/10", own.TotalManga, own.ChaptersToday)
+	}
+	if pub.TotalManga != 2 || pub.ChaptersToday != 3 || pub.ChaptersThisWeek != 6 {
+		t.Errorf("public total/today/week = %d/%d/%d, want 2/3/6", pub.TotalManga, pub.ChaptersToday, pub.ChaptersThisWeek)
+	}
+	for _, g := range pub.Genres {
+		if g.Name == "horror" {
+			t.Errorf("public genres include private manga: %v", pub.Genres)
+		}
+	}
+}
@@ -2,38 +2,86 @@ package tcpsync
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"mangahub/pkg/models"
+	"go.opentelemetry.io/otel/attribute"
+
+	"mangahub/internal/broker"
+	"mangahub/internal/feed"
+	"mangahub/internal/health"
+	"mangahub/internal/logging"
+	"mangahub/internal/tracing"
+)
+
+var (
+	logger = logging.For("tcpsync")
+	tracer = tracing.Tracer("mangahub/internal/tcpsync")
 )
 
-// Server nhận progress events và broadcast cho mọi TCP client
+// writeTimeout: client không đọc kịp bị ngắt, không giữ s.mu (và mọi client khác) mãi
+const writeTimeout = 5 * time.Second
+
+// AuthFunc kiểm tra token (JWT) client gửi lên, trả về user_id
+type AuthFunc func(token string) (string, error)
+
+// Server nhận progress/library events từ feed.Bus và gửi cho TCP client theo feed.Audience
 type Server struct {
 	addr string
 
 	mu      sync.Mutex
-	clients map[net.Conn]struct{}
+	clients map[net.Conn]feed.Audience // UserID rỗng nếu chưa AUTH
+
+	// số kết nối cho Health/ClientCount, cập nhật cùng clients nhưng đọc không cần s.mu
+	nClients atomic.Int64
+	nAuthed  atomic.Int64
 
-	broadcast <-chan models.ProgressUpdate
+	bus  *feed.Bus
+	auth AuthFunc
+	fan  *broker.Fanout // notification cho user kết nối ở instance khác
+
+	// trạng thái cho health check (mutex riêng vì s.mu bị giữ khi ghi vào conn)
+	stateMu   sync.Mutex
+	listening bool
+	looping   bool
+	lastErr   error
 }
 
-func New(addr string, broadcast <-chan models.ProgressUpdate) *Server {
+func New(addr string, bus *feed.Bus) *Server {
 	return &Server{
-		addr:      addr,
-		clients:   make(map[net.Conn]struct{}),
-		broadcast: broadcast,
+		addr:    addr,
+		clients: make(map[net.Conn]feed.Audience),
+		bus:     bus,
 	}
 }
 
+// SetAuth bật lệnh "AUTH <token>" để gắn kết nối với user (nhận notification riêng)
+func (s *Server) SetAuth(fn AuthFunc) {
+	s.auth = fn
+}
+
 func (s *Server) Start() error {
 	ln, err := net.Listen("tcp", s.addr)
 	if err != nil {
+		s.setErr(err)
 		return err
 	}
-	log.Printf("TCP Sync listening on %s", s.addr)
+	s.stateMu.Lock()
+	s.listening = true
+	s.stateMu.Unlock()
+	defer func() {
+		s.stateMu.Lock()
+		s.listening = false
+		s.stateMu.Unlock()
+	}()
+	logger.Info("listening", slog.String("addr", s.addr))
 
 	// Goroutine: nhận event từ channel và broadcast
 	go s.broadcastLoop()
@@ -42,13 +90,18 @@ func (s *Server) Start() error {
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
-			log.Println("tcp accept:", err)
+			logger.Warn("accept failed", logging.Err(err))
+			s.setErr(err)
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			time.Sleep(10 * time.Millisecond)
 			continue
 		}
 		s.addClient(conn)
-		log.Printf("TCP client connected: %s", conn.RemoteAddr().String())
+		logger.Info("client connected", slog.String("remote", conn.RemoteAddr().String()))
 
-		// (optional) đọc để phát hiện disconnect
+		// đọc để phát hiện disconnect + lệnh AUTH
 		go s.readLoop(conn)
 	}
 }
@@ -56,44 +109,217 @@ func (s *Server) Start() error {
 func (s *Server) addClient(conn net.Conn) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.clients[conn] = struct{}{}
+	s.clients[conn] = feed.Audience{}
+	s.nClients.Add(1)
+}
+
+// dropLocked xoá kết nối khỏi clients và đóng nó; gọi khi đang giữ s.mu
+func (s *Server) dropLocked(conn net.Conn) {
+	if aud, ok := s.clients[conn]; ok {
+		delete(s.clients, conn)
+		s.nClients.Add(-1)
+		if aud.UserID != "" {
+			s.nAuthed.Add(-1)
+		}
+	}
+	_ = conn.Close()
+}
+
+// ClientCount là số kết nối TCP đang mở (kể cả chưa AUTH)
+func (s *Server) ClientCount() int {
+	return int(s.nClients.Load())
+}
+
+func (s *Server) setErr(err error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.lastErr = err
+}
+
+// Health: up khi listener đã bind và broadcast loop đang chạy.
+// Không lấy s.mu: lock đó bị giữ trong lúc ghi vào conn.
+func (s *Server) Health(ctx context.Context) health.Report {
+	clients, authed := s.nClients.Load(), s.nAuthed.Load()
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	details := map[string]any{
+		"addr": s.addr, "listening": s.listening, "broadcast_loop": s.looping,
+		"clients": clients, "authenticated": authed,
+	}
+	if !s.listening || !s.looping {
+		return health.Down(health.ErrString(s.lastErr), details)
+	}
+	rep := health.Up(details)
+	rep.LastError = health.ErrString(s.lastErr)
+	return rep
 }
 
 func (s *Server) removeClient(conn net.Conn) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.clients, conn)
-	_ = conn.Close()
+	s.dropLocked(conn)
 }
 
 func (s *Server) readLoop(conn net.Conn) {
-	// Client không cần gửi gì; read để biết khi nào client disconnect
+	// Client chỉ cần gửi (tuỳ chọn) "AUTH <jwt>"; các dòng khác bỏ qua
 	sc := bufio.NewScanner(conn)
 	for sc.Scan() {
-		// ignore any incoming line
+		line := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(strings.ToUpper(line), "AUTH ") || s.auth == nil {
+			continue
+		}
+		userID, err := s.auth(strings.TrimSpace(line[len("AUTH "):]))
+		if err != nil {
+			s.writeLine(conn, map[string]string{"type": "error", "error": "invalid token"})
+			continue
+		}
+		aud := s.bus.Audience(userID)
+		s.mu.Lock()
+		if prev, ok := s.clients[conn]; ok {
+			s.clients[conn] = aud
+			if prev.UserID == "" {
+				s.nAuthed.Add(1)
+			}
+		}
+		s.mu.Unlock()
+		s.writeLine(conn, map[string]string{"type": "auth_ok", "user_id": userID})
 	}
 	s.removeClient(conn)
-	log.Printf("TCP client disconnected: %s", conn.RemoteAddr().String())
+	logger.Info("client disconnected", slog.String("remote", conn.RemoteAddr().String()))
 }
 
-func (s *Server) broadcastLoop() {
-	for evt := range s.broadcast {
-		b, err := json.Marshal(evt)
-		if err != nil {
-			log.Println("tcp marshal:", err)
+func (s *Server) writeLine(conn net.Conn, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = write(conn, append(b, '\n'))
+}
+
+// write ghi 1 dòng với deadline
+func write(conn net.Conn, b []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	_, err := conn.Write(b)
+	return err
+}
+
+type remoteNotification struct {
+	UserID string          `json:"user_id"`
+	Line   json.RawMessage `json:"line"`
+}
+
+// UseBroker: progress/library đã đi qua feed.Bus, ở đây chỉ đồng bộ notification theo user
+func (s *Server) UseBroker(b broker.Broker) error {
+	fan, err := broker.NewFanout(b, "tcp.notification", func(data []byte) {
+		var n remoteNotification
+		if err := json.Unmarshal(data, &n); err != nil {
+			logger.Warn("broker: bad notification", logging.Err(err))
+			return
+		}
+		s.sendLine(n.UserID, append([]byte(n.Line), '\n'))
+	})
+	if err != nil {
+		return err
+	}
+	s.fan = fan
+	return nil
+}
+
+// SendToUser gửi 1 dòng {"type":"notification","payload":...} tới các kết nối đã AUTH của user.
+// Trả về số kết nối local đã nhận (instance khác nhận qua broker).
+func (s *Server) SendToUser(userID string, payload any) int {
+	b, err := json.Marshal(struct {
+		Type    string `json:"type"`
+		Payload any    `json:"payload"`
+	}{"notification", payload})
+	if err != nil {
+		logger.Error("marshal failed", logging.Err(err))
+		return 0
+	}
+	s.fan.Publish(remoteNotification{UserID: userID, Line: b})
+	return s.sendLine(userID, append(b, '\n'))
+}
+
+func (s *Server) sendLine(userID string, b []byte) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent := 0
+	for conn, aud := range s.clients {
+		if aud.UserID != userID {
 			continue
 		}
-		// newline-delimited JSON để client đọc theo dòng (TCP là stream)
-		b = append(b, '\n')
+		if err := write(conn, b); err != nil {
+			s.dropLocked(conn)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+func (s *Server) broadcastLoop() {
+	// notification đi qua SendToUser (dispatcher), ở đây chỉ progress/library
+	match := func(e feed.Event) bool { return e.Type != feed.TypeNotification }
+	s.stateMu.Lock()
+	s.looping = true
+	s.stateMu.Unlock()
+	var last uint64
+	for {
+		sub, backlog, _ := s.bus.Subscribe(match, last)
+		for _, evt := range backlog {
+			s.deliver(evt)
+			last = evt.Seq
+		}
+		for evt := range sub.C {
+			s.deliver(evt)
+			last = evt.Seq
+		}
+		// subscription bị đóng do chậm => đăng ký lại từ seq cuối
+		logger.Warn("subscription dropped, resubscribing", slog.Uint64("from_seq", last))
+	}
+}
 
-		s.mu.Lock()
-		for conn := range s.clients {
-			if _, err := conn.Write(b); err != nil {
-				// lỗi write => remove client
-				delete(s.clients, conn)
-				_ = conn.Close()
-			}
+func (s *Server) deliver(evt feed.Event) {
+	ctx := tracing.Extract(logging.WithRequestID(context.Background(), evt.RequestID), evt.Trace)
+	ctx, span := tracing.Start(ctx, tracer, "tcpsync.deliver",
+		attribute.Int64("feed.seq", int64(evt.Seq)), attribute.String("feed.type", evt.Type))
+	defer span.End()
+
+	// progress giữ format cũ (ProgressUpdate), event khác gửi nguyên feed.Event
+	var v any = evt
+	if evt.Type == feed.TypeProgress {
+		v = evt.Payload
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		logger.ErrorContext(ctx, "marshal failed", logging.Err(err))
+		tracing.RecordError(span, err)
+		return
+	}
+	// newline-delimited JSON để client đọc theo dòng (TCP là stream)
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sent := 0
+	for conn, aud := range s.clients {
+		if !aud.Allows(evt) {
+			continue
 		}
-		s.mu.Unlock()
+		if err := write(conn, b); err != nil {
+			// lỗi write (kể cả quá writeTimeout) => remove client
+			s.dropLocked(conn)
+			continue
+		}
+		sent++
 	}
+	span.SetAttributes(attribute.Int("tcpsync.clients", sent))
+	// request_id của event (vd PATCH /progress) để lần theo 1 cập nhật tới tận TCP client
+	logger.DebugContext(ctx, "event delivered",
+		slog.Uint64("seq", evt.Seq), slog.String("type", evt.Type), slog.Int("clients", sent))
 }
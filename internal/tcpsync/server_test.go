@@ -0,0 +1,52 @@
+package tcpsync
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"mangahub/internal/feed"
+)
+
+// client không đọc: write hết hạn sau writeTimeout, client bị bỏ, Health không bị chặn trong lúc đó
+func TestStalledClient(t *testing.T) {
+	s := New("127.0.0.1:0", nil)
+	stalled, peer := net.Pipe() // net.Pipe không có buffer: Write chờ tới khi phía kia đọc
+	defer peer.Close()
+	s.addClient(stalled)
+	s.mu.Lock()
+	s.clients[stalled] = feed.Audience{UserID: "u1"}
+	s.nAuthed.Add(1)
+	s.mu.Unlock()
+
+	done := make(chan int)
+	go func() { done <- s.sendLine("u1", []byte("{}\n")) }()
+
+	time.Sleep(50 * time.Millisecond) // sendLine đang giữ s.mu
+	healthDone := make(chan map[string]any)
+	go func() { healthDone <- s.Health(context.Background()).Details }()
+	select {
+	case d := <-healthDone:
+		if d["clients"] != int64(1) || d["authenticated"] != int64(1) {
+			t.Errorf("health details = %v, want 1 client, 1 authenticated", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Health blocked while a write was in progress")
+	}
+
+	select {
+	case sent := <-done:
+		if sent != 0 {
+			t.Errorf("sent = %d, want 0", sent)
+		}
+	case <-time.After(writeTimeout + 2*time.Second):
+		t.Fatal("write to stalled client did not time out")
+	}
+	if n := s.ClientCount(); n != 0 {
+		t.Errorf("ClientCount = %d after drop, want 0", n)
+	}
+	if n := s.nAuthed.Load(); n != 0 {
+		t.Errorf("authenticated = %d after drop, want 0", n)
+	}
+}
@@ -0,0 +1,30 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Carrier là trace context dạng map (traceparent, tracestate, baggage) để gắn vào event
+// đi qua channel/broker; nil khi ctx không có span.
+type Carrier map[string]string
+
+// Inject lấy trace context hiện tại của ctx
+func Inject(ctx context.Context) Carrier {
+	c := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, c)
+	if len(c) == 0 {
+		return nil
+	}
+	return Carrier(c)
+}
+
+// Extract trả về ctx mang trace context của carrier (span phía sau là con của span đã Inject)
+func Extract(ctx context.Context, c Carrier) context.Context {
+	if len(c) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(c))
+}
@@ -0,0 +1,30 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var dbTracer = Tracer("mangahub/pkg/database")
+
+// DBQuery là database.QueryTracer: mỗi câu SQL chạy trong 1 trace thành span "db.<op>".
+// Span được tạo sau khi câu lệnh chạy xong nên đặt lại thời điểm bắt đầu = start.
+func DBQuery(ctx context.Context, op, query string, start time.Time, err error) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	_, span := dbTracer.Start(ctx, "db."+op,
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation", op),
+			attribute.String("db.statement", query),
+		),
+	)
+	RecordError(span, err)
+	span.End()
+}
@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc/stats"
+)
+
+// path không tạo span (probe và scrape chạy liên tục)
+var untracedPaths = map[string]bool{"/metrics": true, "/livez": true, "/readyz": true, "/health": true}
+
+// Gin tạo span server cho mỗi request, tên span = "METHOD route" (vd "PATCH /progress").
+// Đặt sau RequestIDMiddleware và trước AccessLog để log có trace_id.
+func Gin(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service,
+		otelgin.WithFilter(func(r *http.Request) bool { return !untracedPaths[r.URL.Path] }),
+		otelgin.WithSpanNameFormatter(func(c *gin.Context) string {
+			if route := c.FullPath(); route != "" {
+				return c.Request.Method + " " + route
+			}
+			return c.Request.Method + " unmatched"
+		}),
+	)
+}
+
+// GRPCServerHandler tạo span cho mỗi RPC (unary và stream), đọc traceparent từ metadata.
+// Dùng với grpc.StatsHandler nên span có trước mọi interceptor (log, metrics).
+func GRPCServerHandler() stats.Handler {
+	return otelgrpc.NewServerHandler(otelgrpc.WithFilter(func(info *stats.RPCTagInfo) bool {
+		return info.FullMethodName != "/grpc.health.v1.Health/Check" && info.FullMethodName != "/grpc.health.v1.Health/Watch"
+	}))
+}
@@ -0,0 +1,123 @@
+// Package tracing cấu hình OpenTelemetry: exporter OTLP/stdout, propagator W3C
+// và helper để tạo span cho DB, repo và event bất đồng bộ.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config đọc từ env:
+//
+//	OTEL_TRACES_EXPORTER=none|otlp|stdout (mặc định none: không export, span vẫn có trace_id cho log)
+//	OTEL_EXPORTER_OTLP_PROTOCOL=grpc|http/protobuf (mặc định grpc)
+//	OTEL_SERVICE_NAME (mặc định mangahub)
+//	OTEL_TRACES_SAMPLER_ARG=0..1 (tỉ lệ sample trace gốc, mặc định 1)
+//
+// Endpoint/header OTLP lấy theo env chuẩn của exporter (OTEL_EXPORTER_OTLP_ENDPOINT, ...).
+type Config struct {
+	Exporter    string
+	Protocol    string
+	ServiceName string
+	SampleRatio float64
+}
+
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Exporter:    os.Getenv("OTEL_TRACES_EXPORTER"),
+		Protocol:    os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		SampleRatio: 1,
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "mangahub"
+	}
+	if v := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); v != "" {
+		var r float64
+		if _, err := fmt.Sscan(v, &r); err == nil && r >= 0 && r <= 1 {
+			cfg.SampleRatio = r
+		}
+	}
+	return cfg
+}
+
+// Setup đăng ký TracerProvider + propagator toàn cục; gọi shutdown trước khi thoát để flush span.
+// stdout ghi ra w (nil = os.Stdout).
+func Setup(ctx context.Context, cfg Config, w io.Writer) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+	}
+	switch cfg.Exporter {
+	case "", "none":
+	case "stdout":
+		if w == nil {
+			w = os.Stdout
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case "otlp":
+		var exp sdktrace.SpanExporter
+		switch cfg.Protocol {
+		case "", "grpc":
+			exp, err = otlptracegrpc.New(ctx)
+		case "http/protobuf":
+			exp, err = otlptracehttp.New(ctx)
+		default:
+			return nil, fmt.Errorf("unknown OTLP protocol %q (grpc|http/protobuf)", cfg.Protocol)
+		}
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q (none|otlp|stdout)", cfg.Exporter)
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Tracer của 1 package (vd "mangahub/internal/manga"); lấy từ provider toàn cục lúc gọi
+// nên gán vào biến package-level trước Setup vẫn dùng đúng provider.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Start chỉ tạo span khi ctx đã nằm trong 1 trace (request HTTP/gRPC, event có trace context);
+// job nền (recommend, prune, session check) không sinh ra hàng loạt trace rời rạc.
+func Start(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError đánh dấu span lỗi (err nil thì bỏ qua)
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -1,33 +1,55 @@
 package udpnotify
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net"
 	"strings"
 	"sync"
 	"time"
+
+	"mangahub/internal/broker"
+	"mangahub/internal/health"
+	"mangahub/internal/logging"
 )
 
+var logger = logging.For("udpnotify")
+
 type Notification struct {
 	Type      string `json:"type"` // "notification"
-	Message   string `json:"message"`
+	Topic     string `json:"topic,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Payload   any    `json:"payload,omitempty"`
 	Timestamp int64  `json:"timestamp"`
 }
 
+// subscriber UDP; topics rỗng = nhận tất cả
+type subscriber struct {
+	addr   *net.UDPAddr
+	topics map[string]bool
+}
+
 type Server struct {
 	addr string
 
 	mu      sync.Mutex
-	clients map[string]*net.UDPAddr // key = ip:port
+	clients map[string]*subscriber // key = ip:port
 
 	conn *net.UDPConn
+	fan  *broker.Fanout // nil khi chạy 1 instance không broker
+
+	// trạng thái cho health check
+	stateMu sync.Mutex
+	reading bool
+	lastErr error
 }
 
 func New(addr string) *Server {
 	return &Server{
 		addr:    addr,
-		clients: make(map[string]*net.UDPAddr),
+		clients: make(map[string]*subscriber),
 	}
 }
 
@@ -39,39 +61,53 @@ func (s *Server) Start() error {
 
 	conn, err := net.ListenUDP("udp", udpAddr)
 	if err != nil {
+		s.setErr(err)
 		return err
 	}
 	s.conn = conn
+	s.stateMu.Lock()
+	s.reading = true
+	s.stateMu.Unlock()
+	defer func() {
+		s.stateMu.Lock()
+		s.reading = false
+		s.stateMu.Unlock()
+	}()
 
-	log.Printf("UDP Notify listening on %s", s.addr)
+	logger.Info("listening", slog.String("addr", s.addr))
 
 	buf := make([]byte, 2048)
 	for {
 		n, clientAddr, err := conn.ReadFromUDP(buf)
 		if err != nil {
-			log.Println("udp read:", err)
+			logger.Warn("read failed", logging.Err(err))
+			s.setErr(err)
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
 			continue
 		}
 
-		msg := strings.TrimSpace(string(buf[:n]))
-		upper := strings.ToUpper(msg)
+		fields := strings.Fields(string(buf[:n]))
+		if len(fields) == 0 {
+			continue
+		}
+		cmd := strings.ToUpper(fields[0])
+		topics := fields[1:]
 
 		// Protocol đơn giản:
-		// - client gửi "SUBSCRIBE" -> server lưu addr để broadcast
-		// - client gửi "UNSUBSCRIBE" -> remove
-		if upper == "SUBSCRIBE" {
-			s.mu.Lock()
-			s.clients[clientAddr.String()] = clientAddr
-			s.mu.Unlock()
-			log.Printf("UDP subscribed: %s (total=%d)", clientAddr.String(), s.count())
+		// - client gửi "SUBSCRIBE" -> server lưu addr để broadcast (nhận tất cả)
+		// - client gửi "SUBSCRIBE manga:<id> ..." -> chỉ nhận broadcast chung + các topic đó
+		// - client gửi "UNSUBSCRIBE [topic ...]" -> remove (hoặc bỏ bớt topic)
+		if cmd == "SUBSCRIBE" {
+			s.subscribe(clientAddr, topics)
+			logger.Info("subscribed", slog.String("addr", clientAddr.String()), slog.Any("topics", topics), slog.Int("total", s.ClientCount()))
 			continue
 		}
 
-		if upper == "UNSUBSCRIBE" {
-			s.mu.Lock()
-			delete(s.clients, clientAddr.String())
-			s.mu.Unlock()
-			log.Printf("UDP unsubscribed: %s (total=%d)", clientAddr.String(), s.count())
+		if cmd == "UNSUBSCRIBE" {
+			s.unsubscribe(clientAddr, topics)
+			logger.Info("unsubscribed", slog.String("addr", clientAddr.String()), slog.Any("topics", topics), slog.Int("total", s.ClientCount()))
 			continue
 		}
 
@@ -79,35 +115,133 @@ func (s *Server) Start() error {
 	}
 }
 
-func (s *Server) count() int {
+func (s *Server) subscribe(addr *net.UDPAddr, topics []string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return len(s.clients)
+	sub, ok := s.clients[addr.String()]
+	if !ok || len(topics) == 0 {
+		sub = &subscriber{addr: addr, topics: map[string]bool{}}
+		s.clients[addr.String()] = sub
+	}
+	for _, t := range topics {
+		sub.topics[t] = true
+	}
 }
 
-func (s *Server) Broadcast(message string) {
-	if s.conn == nil {
-		log.Println("udp conn not started yet")
+func (s *Server) unsubscribe(addr *net.UDPAddr, topics []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.clients[addr.String()]
+	if !ok {
+		return
+	}
+	if len(topics) == 0 {
+		delete(s.clients, addr.String())
 		return
 	}
+	for _, t := range topics {
+		delete(sub.topics, t)
+	}
+	if len(sub.topics) == 0 {
+		delete(s.clients, addr.String())
+	}
+}
+
+// ClientCount là số địa chỉ đang subscribe
+func (s *Server) ClientCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+func (s *Server) setErr(err error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.lastErr = err
+}
+
+// Health: up khi socket đã bind và vòng đọc đang chạy
+func (s *Server) Health(ctx context.Context) health.Report {
+	clients := s.ClientCount()
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	details := map[string]any{"addr": s.addr, "read_loop": s.reading, "subscribers": clients}
+	if !s.reading {
+		return health.Down(health.ErrString(s.lastErr), details)
+	}
+	rep := health.Up(details)
+	rep.LastError = health.ErrString(s.lastErr)
+	return rep
+}
 
-	noti := Notification{
+// UseBroker: notification publish ở instance khác cũng gửi tới subscriber của instance này
+func (s *Server) UseBroker(b broker.Broker) error {
+	fan, err := broker.NewFanout(b, "udp.notification", func(data []byte) {
+		var noti Notification
+		if err := json.Unmarshal(data, &noti); err != nil {
+			logger.Warn("broker: bad notification", logging.Err(err))
+			return
+		}
+		s.sendLocal(noti)
+	})
+	if err != nil {
+		return err
+	}
+	s.fan = fan
+	return nil
+}
+
+// Broadcast gửi tới mọi subscriber (không lọc topic)
+func (s *Server) Broadcast(message string) {
+	s.send(Notification{
 		Type:      "notification",
 		Message:   message,
 		Timestamp: time.Now().Unix(),
+	})
+}
+
+// PublishTopic gửi tới subscriber của topic và subscriber nhận tất cả
+func (s *Server) PublishTopic(topic string, payload any) int {
+	return s.send(Notification{
+		Type:      "notification",
+		Topic:     topic,
+		Payload:   payload,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// send gửi cho subscriber local và các instance khác; trả về số subscriber local đã nhận
+func (s *Server) send(noti Notification) int {
+	s.fan.Publish(noti)
+	return s.sendLocal(noti)
+}
+
+func (s *Server) sendLocal(noti Notification) int {
+	if s.conn == nil {
+		logger.Warn("conn not started yet, drop notification")
+		return 0
 	}
+
 	b, err := json.Marshal(noti)
 	if err != nil {
-		log.Println("udp marshal:", err)
-		return
+		logger.Error("marshal notification failed", logging.Err(err))
+		return 0
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for key, addr := range s.clients {
-		if _, err := s.conn.WriteToUDP(b, addr); err != nil {
-			log.Printf("udp send to %s failed: %v", key, err)
+	sent := 0
+	for key, sub := range s.clients {
+		if noti.Topic != "" && len(sub.topics) > 0 && !sub.topics[noti.Topic] {
+			continue
+		}
+		if _, err := s.conn.WriteToUDP(b, sub.addr); err != nil {
+			logger.Warn("send failed", slog.String("addr", key), logging.Err(err))
+			s.setErr(err)
+			continue
 		}
+		sent++
 	}
+	return sent
 }
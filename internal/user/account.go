@@ -0,0 +1,273 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"mangahub/internal/tracing"
+)
+
+// Mục đích của token 1 lần
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// tài khoản đã xoá: chat công khai giữ lại nội dung nhưng gắn với user ẩn danh
+const DeletedUserID = "deleted"
+const DeletedUsername = "[deleted]"
+
+var (
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidToken    = errors.New("invalid or expired token")
+	ErrEmailTaken      = errors.New("email already in use")
+	ErrUsernameTaken   = errors.New("username already taken")
+)
+
+const sqliteTime = "2006-01-02 15:04:05"
+
+// Profile là thông tin tài khoản trả về cho chính chủ (GET /me)
+type Profile struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	DisplayName   string    `json:"display_name"`
+	AvatarURL     string    `json:"avatar_url"`
+	Bio           string    `json:"bio"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ProfileUpdate: field nil = giữ nguyên
+type ProfileUpdate struct {
+	DisplayName *string
+	AvatarURL   *string
+	Bio         *string
+}
+
+func GetProfile(ctx context.Context, db *sql.DB, userID string) (Profile, error) {
+	ctx, span := tracing.Start(ctx, tracer, "user.GetProfile")
+	defer span.End()
+	var p Profile
+	err := db.QueryRowContext(ctx, `
+	SELECT id, username, COALESCE(display_name, ''), COALESCE(avatar_url, ''), COALESCE(bio, ''),
+	       COALESCE(email, ''), COALESCE(email_verified, 0), COALESCE(role, 'user'), created_at
+	FROM users WHERE id=?`, userID).
+		Scan(&p.ID, &p.Username, &p.DisplayName, &p.AvatarURL, &p.Bio, &p.Email, &p.EmailVerified, &p.Role, &p.CreatedAt)
+	return p, err
+}
+
+func UpdateProfile(ctx context.Context, db *sql.DB, userID string, u ProfileUpdate) error {
+	ctx, span := tracing.Start(ctx, tracer, "user.UpdateProfile")
+	defer span.End()
+	_, err := db.ExecContext(ctx, `
+	UPDATE users SET display_name=COALESCE(?, display_name), avatar_url=COALESCE(?, avatar_url), bio=COALESCE(?, bio)
+	WHERE id=?`, u.DisplayName, u.AvatarURL, u.Bio, userID)
+	return err
+}
+
+// SetEmail đổi email (chưa xác thực); email rỗng = xoá email
+func SetEmail(ctx context.Context, db *sql.DB, userID, email string) error {
+	ctx, span := tracing.Start(ctx, tracer, "user.SetEmail")
+	defer span.End()
+	var v any
+	if email != "" {
+		v = email
+		taken, err := EmailTaken(ctx, db, email, userID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrEmailTaken
+		}
+	}
+	_, err := db.ExecContext(ctx, `UPDATE users SET email=?, email_verified=0 WHERE id=?`, v, userID)
+	return err
+}
+
+// EmailTaken: email đã thuộc về user khác exceptID chưa
+func EmailTaken(ctx context.Context, db *sql.DB, email, exceptID string) (bool, error) {
+	ctx, span := tracing.Start(ctx, tracer, "user.EmailTaken")
+	defer span.End()
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE email=? AND id<>?`, email, exceptID).Scan(&n)
+	return n > 0, err
+}
+
+// GetByEmail chỉ tìm email đã xác thực (dùng cho reset password)
+func GetByEmail(ctx context.Context, db *sql.DB, email string) (User, error) {
+	ctx, span := tracing.Start(ctx, tracer, "user.GetByEmail")
+	defer span.End()
+	var u User
+	err := db.QueryRowContext(ctx, `SELECT id, username, COALESCE(role, 'user'), created_at FROM users WHERE email=? AND email_verified=1`, email).
+		Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt)
+	return u, err
+}
+
+func CheckPassword(ctx context.Context, db *sql.DB, userID, password string) error {
+	ctx, span := tracing.Start(ctx, tracer, "user.CheckPassword")
+	defer span.End()
+	var hash string
+	if err := db.QueryRowContext(ctx, `SELECT password_hash FROM users WHERE id=?`, userID).Scan(&hash); err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// SetPassword đổi password và thu hồi mọi JWT đã phát hành trước thời điểm này.
+// Trả về mốc thu hồi; token mới phải có iat >= mốc này.
+func SetPassword(ctx context.Context, db *sql.DB, userID, password string) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, tracer, "user.SetPassword")
+	defer span.End()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	_, err = db.ExecContext(ctx, `UPDATE users SET password_hash=?, tokens_valid_after=? WHERE id=?`, string(hash), now.Format(sqliteTime), userID)
+	return now, err
+}
+
+// TokensValidAfter: JWT có iat trước mốc này không còn hiệu lực (zero = chưa từng thu hồi).
+// Trả về sql.ErrNoRows nếu user không còn tồn tại.
+func TokensValidAfter(ctx context.Context, db *sql.DB, userID string) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, tracer, "user.TokensValidAfter")
+	defer span.End()
+	var t sql.NullTime
+	if err := db.QueryRowContext(ctx, `SELECT tokens_valid_after FROM users WHERE id=?`, userID).Scan(&t); err != nil {
+		return time.Time{}, err
+	}
+	return t.Time, nil
+}
+
+// CreateToken tạo token 1 lần, trả về token gốc (chỉ hash được lưu DB)
+func CreateToken(ctx context.Context, db *sql.DB, userID, purpose, email string, ttl time.Duration) (string, error) {
+	ctx, span := tracing.Start(ctx, tracer, "user.CreateToken")
+	defer span.End()
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := hex.EncodeToString(b)
+	// token cũ cùng mục đích hết hiệu lực
+	if _, err := db.ExecContext(ctx, `DELETE FROM user_tokens WHERE user_id=? AND purpose=?`, userID, purpose); err != nil {
+		return "", err
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO user_tokens(token_hash, user_id, purpose, email, expires_at) VALUES(?,?,?,?,?)`,
+		hashToken(raw), userID, purpose, email, time.Now().UTC().Add(ttl).Format(sqliteTime))
+	return raw, err
+}
+
+// ConsumeToken kiểm tra và đánh dấu đã dùng; trả về user_id và email gắn với token
+func ConsumeToken(ctx context.Context, db *sql.DB, raw, purpose string) (userID, email string, err error) {
+	ctx, span := tracing.Start(ctx, tracer, "user.ConsumeToken")
+	defer span.End()
+	res, err := db.ExecContext(ctx, `UPDATE user_tokens SET used_at=CURRENT_TIMESTAMP
+	WHERE token_hash=? AND purpose=? AND used_at IS NULL AND expires_at > ?`,
+		hashToken(raw), purpose, time.Now().UTC().Format(sqliteTime))
+	if err != nil {
+		return "", "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", "", ErrInvalidToken
+	}
+	err = db.QueryRowContext(ctx, `SELECT user_id, COALESCE(email, '') FROM user_tokens WHERE token_hash=?`, hashToken(raw)).Scan(&userID, &email)
+	return userID, email, err
+}
+
+// MarkEmailVerified chỉ xác thực nếu email của user vẫn là email trong token
+func MarkEmailVerified(ctx context.Context, db *sql.DB, userID, email string) error {
+	ctx, span := tracing.Start(ctx, tracer, "user.MarkEmailVerified")
+	defer span.End()
+	res, err := db.ExecContext(ctx, `UPDATE users SET email_verified=1 WHERE id=? AND email=?`, userID, email)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeleteAccount xoá user và dữ liệu cá nhân trong 1 transaction:
+// library, lịch sử đọc, review, follow, notification, DM bị xoá;
+// message ở room công khai được giữ nhưng gắn với user ẩn danh.
+func DeleteAccount(ctx context.Context, db *sql.DB, userID string) error {
+	ctx, span := tracing.Start(ctx, tracer, "user.DeleteAccount")
+	defer span.End()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// trả lại bộ đếm popularity cho các manga trong thư viện
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE manga_stats SET
+		readers = MAX(readers - 1, 0),
+		completions = MAX(completions - (SELECT COUNT(*) FROM user_progress p
+			WHERE p.user_id=? AND p.manga_id=manga_stats.manga_id AND p.status='completed'), 0)
+	WHERE manga_id IN (SELECT manga_id FROM user_progress WHERE user_id=?)`, userID, userID); err != nil {
+		return err
+	}
+
+	stmts := []string{
+		`DELETE FROM user_progress WHERE user_id=?`,
+		`DELETE FROM reading_history WHERE user_id=?`,
+		`DELETE FROM review_votes WHERE user_id=? OR review_id IN (SELECT id FROM reviews WHERE user_id=?)`,
+		`DELETE FROM reviews WHERE user_id=?`,
+		`DELETE FROM manga_follows WHERE user_id=?`,
+		`DELETE FROM notifications WHERE user_id=?`,
+		`DELETE FROM notification_prefs WHERE user_id=?`,
+		`DELETE FROM user_follows WHERE follower_id=? OR followee_id=?`,
+		`DELETE FROM user_privacy WHERE user_id=?`,
+		`DELETE FROM list_privacy WHERE user_id=?`,
+		`DELETE FROM chat_read_receipts WHERE user_id=?`,
+		`DELETE FROM chat_sanctions WHERE user_id=?`,
+		`DELETE FROM user_tokens WHERE user_id=?`,
+		// DM room có dạng dm:<a>:<b>; so khớp đúng từng phần (LIKE coi "_" trong username là wildcard)
+		`DELETE FROM chat_messages WHERE room LIKE 'dm:%'
+		 AND (substr(room, 1, length(?) + 4) = 'dm:' || ? || ':' OR substr(room, -length(?) - 1) = ':' || ?)`,
+		`DELETE FROM users WHERE id=?`,
+	}
+	for _, q := range stmts {
+		args := make([]any, 0, 2)
+		for i := 0; i < countPlaceholders(q); i++ {
+			args = append(args, userID)
+		}
+		if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE chat_messages SET user_id=?, username=? WHERE user_id=?`,
+		DeletedUserID, DeletedUsername, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func countPlaceholders(q string) int {
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+		}
+	}
+	return n
+}
@@ -1,31 +1,50 @@
 package user
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
+	"github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
+
+	"mangahub/internal/tracing"
 )
 
+var tracer = tracing.Tracer("mangahub/internal/user")
+
 type User struct {
 	ID           string
 	Username     string
 	PasswordHash string
+	Role         string
+	CreatedAt    time.Time
 }
 
-func CreateUser(db *sql.DB, id, username, password string) error {
+func CreateUser(ctx context.Context, db *sql.DB, id, username, password string) error {
+	ctx, span := tracing.Start(ctx, tracer, "user.CreateUser")
+	defer span.End()
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
-	_, err = db.Exec(`INSERT INTO users(id, username, password_hash) VALUES(?,?,?)`, id, username, string(hash))
+	// tokens_valid_after = lúc tạo: JWT của tài khoản cũ cùng username (đã xoá) không dùng lại được
+	_, err = db.ExecContext(ctx, `INSERT INTO users(id, username, password_hash, tokens_valid_after) VALUES(?,?,?,?)`,
+		id, username, string(hash), time.Now().UTC().Truncate(time.Second).Format(sqliteTime))
+	var se sqlite3.Error
+	if errors.As(err, &se) && se.Code == sqlite3.ErrConstraint {
+		return ErrUsernameTaken
+	}
 	return err
 }
 
-func VerifyLogin(db *sql.DB, username, password string) (User, error) {
+func VerifyLogin(ctx context.Context, db *sql.DB, username, password string) (User, error) {
+	ctx, span := tracing.Start(ctx, tracer, "user.VerifyLogin")
+	defer span.End()
 	var u User
-	err := db.QueryRow(`SELECT id, username, password_hash FROM users WHERE username = ?`, username).
-		Scan(&u.ID, &u.Username, &u.PasswordHash)
+	err := db.QueryRowContext(ctx, `SELECT id, username, password_hash, COALESCE(role, 'user') FROM users WHERE username = ?`, username).
+		Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role)
 	if err != nil {
 		return User{}, err
 	}
@@ -34,3 +53,13 @@ func VerifyLogin(db *sql.DB, username, password string) (User, error) {
 	}
 	return u, nil
 }
+
+// GetByUsername dùng cho profile công khai (không kiểm tra password)
+func GetByUsername(ctx context.Context, db *sql.DB, username string) (User, error) {
+	ctx, span := tracing.Start(ctx, tracer, "user.GetByUsername")
+	defer span.End()
+	var u User
+	err := db.QueryRowContext(ctx, `SELECT id, username, COALESCE(role, 'user'), created_at FROM users WHERE username = ?`, username).
+		Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt)
+	return u, err
+}
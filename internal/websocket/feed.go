@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+
+	"mangahub/internal/apierr"
+	"mangahub/internal/feed"
+	"mangahub/internal/logging"
+	"mangahub/internal/tracing"
+)
+
+var tracer = tracing.Tracer("mangahub/internal/websocket")
+
+// MsgFeedReady: gửi đầu tiên trên /ws/feed, payload {seq, resumed}
+const MsgFeedReady = "ready"
+
+type feedReady struct {
+	Seq uint64 `json:"seq"` // seq mới nhất của server
+	// false khi client gửi ?since= nhưng event đã bị mất (buffer ghi đè / server restart):
+	// client cần tải lại trạng thái qua REST
+	Resumed bool `json:"resumed"`
+}
+
+// HandleFeed: /ws/feed?since=<seq> stream progress, library, notification của user
+// và người user follow (cùng rule feed.Audience với TCP sync). Luôn yêu cầu JWT.
+func HandleFeed(bus *feed.Bus, cfg Config) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{bearerSubprotocol},
+		CheckOrigin:     originChecker(cfg.AllowedOrigins),
+	}
+
+	return func(c *gin.Context) {
+		claims, err := authenticate(c.Request, cfg.Secret)
+		if err != nil {
+			apierr.Write(c, apierr.Unauthenticated("invalid or missing token").WithReason("invalid_token"))
+			return
+		}
+		var since uint64
+		if v := c.Query("since"); v != "" {
+			if since, err = strconv.ParseUint(v, 10, 64); err != nil {
+				apierr.Write(c, apierr.InvalidField("since", "invalid", "invalid since"))
+				return
+			}
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			logger.WarnContext(c.Request.Context(), "upgrade failed", logging.Err(err))
+			return
+		}
+
+		aud := bus.Audience(claims.UserID)
+		sub, backlog, resumed := bus.Subscribe(aud.Allows, since)
+		go feedReadPump(conn, sub)
+		go feedWritePump(conn, sub, feedReady{Seq: bus.Seq(), Resumed: resumed}, backlog)
+	}
+}
+
+// client không gửi gì lên feed; chỉ đọc để xử lý pong/close
+func feedReadPump(conn *websocket.Conn, sub *feed.Subscription) {
+	defer sub.Close()
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		return nil
+	})
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func feedWritePump(conn *websocket.Conn, sub *feed.Subscription, ready feedReady, backlog []feed.Event) {
+	ticker := time.NewTicker(54 * time.Second)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	write := func(data []byte) bool {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteMessage(websocket.TextMessage, data) == nil
+	}
+
+	if !write(encode(MsgFeedReady, "", ready)) {
+		return
+	}
+	// mỗi event là 1 span con của span publish (event mang trace context)
+	send := func(e feed.Event) bool {
+		_, span := tracing.Start(tracing.Extract(context.Background(), e.Trace), tracer, "websocket.feed.deliver",
+			attribute.Int64("feed.seq", int64(e.Seq)), attribute.String("feed.type", e.Type))
+		defer span.End()
+		b, err := json.Marshal(e)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return true
+		}
+		return write(b)
+	}
+
+	for _, e := range backlog {
+		if !send(e) {
+			return
+		}
+	}
+
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				// bị đóng do chậm: client reconnect với ?since=<seq cuối>
+				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "lagging, resume with since"))
+				return
+			}
+			if !send(e) {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"mangahub/pkg/models"
+)
+
+// ContentFilter xử lý message trước khi broadcast: sửa nội dung (mask, gắn spoiler)
+// hoặc trả lỗi để từ chối message
+type ContentFilter interface {
+	Apply(msg *models.ChatMessage) error
+}
+
+// FilterFunc cho phép dùng hàm thường làm ContentFilter
+type FilterFunc func(msg *models.ChatMessage) error
+
+func (f FilterFunc) Apply(msg *models.ChatMessage) error { return f(msg) }
+
+// DefaultProfanity là danh sách tối thiểu, có thể thay bằng NewProfanityFilter(list khác)
+var DefaultProfanity = []string{"fuck", "shit", "bitch", "asshole", "cunt", "dickhead"}
+
+// ProfanityFilter thay từ cấm bằng dấu *
+type ProfanityFilter struct {
+	re *regexp.Regexp
+}
+
+func NewProfanityFilter(words []string) *ProfanityFilter {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return &ProfanityFilter{}
+	}
+	return &ProfanityFilter{re: regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)}
+}
+
+func (f *ProfanityFilter) Apply(msg *models.ChatMessage) error {
+	if f.re == nil {
+		return nil
+	}
+	msg.Message = f.re.ReplaceAllStringFunc(msg.Message, func(w string) string {
+		return strings.Repeat("*", len([]rune(w)))
+	})
+	return nil
+}
+
+var linkRe = regexp.MustCompile(`(?i)\b((https?://|www\.)\S+|[a-z0-9-]+\.(com|net|org|io|xyz|ru|info|gg|ly)\b\S*)`)
+
+// LinkFilter chặn message có link, trừ các domain trong AllowedDomains
+type LinkFilter struct {
+	AllowedDomains []string
+}
+
+func (f LinkFilter) Apply(msg *models.ChatMessage) error {
+	for _, link := range linkRe.FindAllString(msg.Message, -1) {
+		if !f.allowed(link) {
+			return errors.New("links are not allowed in chat")
+		}
+	}
+	return nil
+}
+
+func (f LinkFilter) allowed(link string) bool {
+	host := strings.ToLower(link)
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	if i := strings.IndexAny(host, "/?#:"); i >= 0 {
+		host = host[:i]
+	}
+	host = strings.TrimPrefix(host, "www.")
+	for _, d := range f.AllowedDomains {
+		d = strings.ToLower(d)
+		// đúng domain hoặc subdomain của nó (không nhận "mangadex.org.evil.com")
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+var spoilerRe = regexp.MustCompile(`(?is)\|\|.+?\|\||\[spoiler\].*?\[/spoiler\]`)
+
+// SpoilerFilter gắn cờ spoiler khi message có ||...|| hoặc [spoiler]...[/spoiler]
+type SpoilerFilter struct{}
+
+func (SpoilerFilter) Apply(msg *models.ChatMessage) error {
+	if spoilerRe.MatchString(msg.Message) || strings.HasPrefix(strings.ToLower(msg.Message), "spoiler:") {
+		msg.Spoiler = true
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"testing"
+
+	"mangahub/pkg/models"
+)
+
+func TestProfanityFilter(t *testing.T) {
+	f := NewProfanityFilter(DefaultProfanity)
+	tests := []struct {
+		name, in, want string
+	}{
+		{"clean", "chapter 12 was great", "chapter 12 was great"},
+		{"masked", "what the fuck", "what the ****"},
+		{"case insensitive", "SHIT happens", "**** happens"},
+		{"whole words only", "shitake and scunthorpe", "shitake and scunthorpe"},
+		{"several words", "bitch, asshole!", "*****, *******!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &models.ChatMessage{Message: tt.in}
+			if err := f.Apply(msg); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if msg.Message != tt.want {
+				t.Errorf("got %q, want %q", msg.Message, tt.want)
+			}
+		})
+	}
+
+	t.Run("empty list", func(t *testing.T) {
+		msg := &models.ChatMessage{Message: "fuck"}
+		if err := NewProfanityFilter([]string{" ", ""}).Apply(msg); err != nil || msg.Message != "fuck" {
+			t.Errorf("got %q, %v", msg.Message, err)
+		}
+	})
+}
+
+func TestLinkFilter(t *testing.T) {
+	f := LinkFilter{AllowedDomains: []string{"mangadex.org"}}
+	tests := []struct {
+		name    string
+		in      string
+		blocked bool
+	}{
+		{"no link", "see you at chapter 3.5", false},
+		{"http link", "read it at http://example.com/x", true},
+		{"bare domain", "go to spam.xyz now", true},
+		{"www link", "www.example.net", true},
+		{"allowed domain", "https://mangadex.org/title/1", false},
+		{"allowed subdomain", "https://api.mangadex.org/manga", false},
+		{"allowed www", "www.mangadex.org/title/1", false},
+		{"allowed case", "HTTPS://MangaDex.org", false},
+		{"lookalike suffix", "https://mangadex.org.evil.com/x", true},
+		{"lookalike prefix", "https://notmangadex.org", true},
+		{"allowed then blocked", "https://mangadex.org and http://evil.io", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.Apply(&models.ChatMessage{Message: tt.in})
+			if (err != nil) != tt.blocked {
+				t.Errorf("Apply(%q) err = %v, blocked want %v", tt.in, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestSpoilerFilter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"no spoilers here", false},
+		{"he dies ||in chapter 90||", true},
+		{"[spoiler]she wins[/spoiler]", true},
+		{"[SPOILER]multi\nline[/SPOILER]", true},
+		{"Spoiler: the ending", true},
+		{"a || b", false},
+		{"||||", false},
+	}
+	for _, tt := range tests {
+		msg := &models.ChatMessage{Message: tt.in}
+		if err := (SpoilerFilter{}).Apply(msg); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		if msg.Spoiler != tt.want {
+			t.Errorf("Apply(%q) spoiler = %v, want %v", tt.in, msg.Spoiler, tt.want)
+		}
+		if msg.Message != tt.in {
+			t.Errorf("Apply(%q) changed message to %q", tt.in, msg.Message)
+		}
+	}
+}
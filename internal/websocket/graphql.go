@@ -0,0 +1,355 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"mangahub/internal/apierr"
+	"mangahub/internal/auth"
+	"mangahub/internal/logging"
+)
+
+// graphqlSubprotocol: protocol graphql-transport-ws (thư viện graphql-ws phía client)
+const graphqlSubprotocol = "graphql-transport-ws"
+
+// message của graphql-transport-ws
+const (
+	gqlConnectionInit = "connection_init"
+	gqlConnectionAck  = "connection_ack"
+	gqlPing           = "ping"
+	gqlPong           = "pong"
+	gqlSubscribe      = "subscribe"
+	gqlNext           = "next"
+	gqlError          = "error"
+	gqlComplete       = "complete"
+)
+
+// close code riêng của protocol
+const (
+	closeBadRequest    = 4400
+	closeUnauthorized  = 4401
+	closeInitTimeout   = 4408
+	closeDuplicateID   = 4409
+	closeTooManyInits  = 4429
+	gqlInitTimeout     = 10 * time.Second
+	gqlMaxSubscription = 20 // số operation chạy đồng thời trên 1 kết nối
+)
+
+// GraphQLSubscriber là phần của *graphql.Schema mà transport cần
+type GraphQLSubscriber interface {
+	Subscribe(ctx context.Context, query, operationName string, variables map[string]any) (<-chan any, error)
+}
+
+type gqlMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type gqlSubscribePayload struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// HandleGraphQL: subscription GraphQL qua WebSocket theo graphql-transport-ws.
+// JWT lấy như /ws (header, ?token=, cookie) hoặc từ payload connection_init
+// ({"authorization": "Bearer ..."} / {"token": "..."}); không có token => anonymous.
+// prepare gắn viewer + dữ liệu riêng của mỗi operation vào context.
+func HandleGraphQL(schema GraphQLSubscriber, cfg Config, prepare func(ctx context.Context, userID string) context.Context) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{graphqlSubprotocol},
+		CheckOrigin:     originChecker(cfg.AllowedOrigins),
+	}
+
+	return func(c *gin.Context) {
+		claims, err := authenticate(c.Request, cfg.Secret)
+		if err != nil && err != errNoToken {
+			apierr.Write(c, apierr.Unauthenticated("invalid or missing token").WithReason("invalid_token"))
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			logger.WarnContext(c.Request.Context(), "upgrade failed", logging.Err(err))
+			return
+		}
+		if conn.Subprotocol() != graphqlSubprotocol {
+			closeWith(conn, websocket.CloseProtocolError, "subprotocol "+graphqlSubprotocol+" required")
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		g := &gqlConn{
+			conn:    conn,
+			schema:  schema,
+			cfg:     cfg,
+			prepare: prepare,
+			send:    make(chan []byte, 64),
+			subs:    map[string]context.CancelFunc{},
+			ctx:     ctx,
+			cancel:  cancel,
+		}
+		if claims != nil {
+			g.userID = claims.UserID
+		}
+		go g.readPump()
+		go g.writePump()
+	}
+}
+
+type gqlConn struct {
+	conn    *websocket.Conn
+	schema  GraphQLSubscriber
+	cfg     Config
+	prepare func(ctx context.Context, userID string) context.Context
+	send    chan []byte
+
+	userID string // chỉ ghi trong readPump trước khi ack
+	acked  bool
+
+	mu   sync.Mutex
+	subs map[string]context.CancelFunc
+
+	ctx    context.Context // huỷ khi kết nối đóng
+	cancel context.CancelFunc
+}
+
+func (g *gqlConn) readPump() {
+	defer func() {
+		g.cancel()
+		g.conn.Close()
+	}()
+	g.conn.SetReadLimit(64 << 10)
+	// client phải gửi connection_init trong gqlInitTimeout
+	initTimer := time.AfterFunc(gqlInitTimeout, func() {
+		closeWith(g.conn, closeInitTimeout, "Connection initialisation timeout")
+	})
+	defer initTimer.Stop()
+	g.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	g.conn.SetPongHandler(func(string) error {
+		g.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		return nil
+	})
+
+	for {
+		_, data, err := g.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		g.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		var msg gqlMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+			closeWith(g.conn, closeBadRequest, "Invalid message received")
+			return
+		}
+
+		switch msg.Type {
+		case gqlConnectionInit:
+			if g.acked {
+				closeWith(g.conn, closeTooManyInits, "Too many initialisation requests")
+				return
+			}
+			if !g.init(msg.Payload) {
+				closeWith(g.conn, websocket.ClosePolicyViolation, "Forbidden")
+				return
+			}
+			initTimer.Stop()
+			g.acked = true
+			g.write(gqlMessage{Type: gqlConnectionAck})
+		case gqlPing:
+			g.write(gqlMessage{Type: gqlPong})
+		case gqlPong:
+		case gqlSubscribe:
+			if !g.acked {
+				closeWith(g.conn, closeUnauthorized, "Unauthorized")
+				return
+			}
+			if !g.subscribe(msg) {
+				return
+			}
+		case gqlComplete:
+			g.stop(msg.ID)
+		default:
+			closeWith(g.conn, closeBadRequest, "Invalid message received")
+			return
+		}
+	}
+}
+
+// init đọc token trong payload connection_init (nếu kết nối chưa xác thực lúc upgrade)
+func (g *gqlConn) init(raw json.RawMessage) bool {
+	var p struct {
+		Authorization string `json:"authorization"`
+		Token         string `json:"token"`
+	}
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return false
+		}
+	}
+	token := p.Token
+	if v, ok := strings.CutPrefix(p.Authorization, "Bearer "); ok {
+		token = v
+	}
+	if token == "" || g.userID != "" {
+		return true
+	}
+	claims, err := auth.ParseJWT(g.cfg.Secret, token)
+	if err != nil {
+		return false
+	}
+	g.userID = claims.UserID
+	return true
+}
+
+// subscribe chạy 1 operation (subscription, hoặc query/mutation trả 1 kết quả);
+// false => kết nối đã bị đóng do vi phạm protocol
+func (g *gqlConn) subscribe(msg gqlMessage) bool {
+	var p gqlSubscribePayload
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &p) != nil || p.Query == "" {
+		closeWith(g.conn, closeBadRequest, "Invalid message received")
+		return false
+	}
+
+	g.mu.Lock()
+	if _, dup := g.subs[msg.ID]; dup {
+		g.mu.Unlock()
+		closeWith(g.conn, closeDuplicateID, "Subscriber for "+msg.ID+" already exists")
+		return false
+	}
+	if len(g.subs) >= gqlMaxSubscription {
+		g.mu.Unlock()
+		g.writeErrors(msg.ID, "too many active subscriptions")
+		return true
+	}
+	ctx, cancel := context.WithCancel(g.prepare(g.ctx, g.userID))
+	g.subs[msg.ID] = cancel
+	g.mu.Unlock()
+
+	results, err := g.schema.Subscribe(ctx, p.Query, p.OperationName, p.Variables)
+	if err != nil {
+		g.done(msg.ID)
+		g.writeErrors(msg.ID, err.Error())
+		return true
+	}
+	go g.forward(ctx, msg.ID, results)
+	return true
+}
+
+// forward đẩy từng kết quả thành "next"; lỗi trước khi chạy (parse/validate) thành "error"
+func (g *gqlConn) forward(ctx context.Context, id string, results <-chan any) {
+	defer g.done(id)
+	first := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case res, ok := <-results:
+			if !ok {
+				g.write(gqlMessage{ID: id, Type: gqlComplete})
+				return
+			}
+			payload, err := json.Marshal(res)
+			if err != nil {
+				logger.WarnContext(ctx, "graphql result marshal failed", logging.Err(err))
+				continue
+			}
+			if first && onlyErrors(payload) {
+				var out struct {
+					Errors json.RawMessage `json:"errors"`
+				}
+				json.Unmarshal(payload, &out)
+				g.write(gqlMessage{ID: id, Type: gqlError, Payload: out.Errors})
+				return
+			}
+			first = false
+			g.write(gqlMessage{ID: id, Type: gqlNext, Payload: payload})
+		}
+	}
+}
+
+// onlyErrors: kết quả không có data => operation không chạy được (query sai, biến sai...)
+func onlyErrors(payload []byte) bool {
+	var r struct {
+		Data   json.RawMessage `json:"data"`
+		Errors json.RawMessage `json:"errors"`
+	}
+	if json.Unmarshal(payload, &r) != nil {
+		return false
+	}
+	return len(r.Errors) > 0 && (len(r.Data) == 0 || string(r.Data) == "null")
+}
+
+func (g *gqlConn) writeErrors(id, message string) {
+	payload, _ := json.Marshal([]map[string]string{{"message": message}})
+	g.write(gqlMessage{ID: id, Type: gqlError, Payload: payload})
+}
+
+// stop: client gửi complete => huỷ operation, không gửi complete ngược lại
+func (g *gqlConn) stop(id string) {
+	g.mu.Lock()
+	cancel, ok := g.subs[id]
+	g.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (g *gqlConn) done(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if cancel, ok := g.subs[id]; ok {
+		cancel()
+		delete(g.subs, id)
+	}
+}
+
+func (g *gqlConn) write(msg gqlMessage) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	select {
+	case g.send <- b:
+	case <-g.ctx.Done():
+	}
+}
+
+func (g *gqlConn) writePump() {
+	ticker := time.NewTicker(54 * time.Second)
+	defer func() {
+		ticker.Stop()
+		g.conn.Close()
+	}()
+	for {
+		select {
+		case b := <-g.send:
+			g.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := g.conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				return
+			}
+		case <-ticker.C:
+			g.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := g.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-g.ctx.Done():
+			return
+		}
+	}
+}
+
+// closeWith gửi close frame kèm code rồi đóng kết nối (WriteControl an toàn khi ghi đồng thời)
+func closeWith(conn *websocket.Conn, code int, text string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	conn.Close()
+}
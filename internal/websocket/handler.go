@@ -2,64 +2,160 @@ package websocket
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
+	"mangahub/internal/apierr"
+	"mangahub/internal/auth"
+	"mangahub/internal/logging"
 	"mangahub/pkg/models"
 )
 
-// upgrade http cho websocket
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+// subprotocol để browser gửi JWT: new WebSocket(url, ["bearer", token])
+const bearerSubprotocol = "bearer"
+
+// cookie chứa JWT (tuỳ chọn, thay cho query ?token=)
+const TokenCookie = "mangahub_token"
+
+// Config cho endpoint /ws
+type Config struct {
+	Secret []byte
+	// cho phép kết nối không có token ở chế độ chỉ đọc (nhận message, không gửi được)
+	AllowAnonymous bool
+	// danh sách Origin được phép (vd "http://localhost:8080"); rỗng = chỉ same-origin
+	AllowedOrigins []string
 }
 
 type client struct {
 	hub      *ChatHub
 	conn     *websocket.Conn
 	send     chan []byte
+	userID   string
 	username string
+	role     string
+	readOnly bool
 }
 
-func HandleWebSocket(hub *ChatHub) gin.HandlerFunc {
+var errNoToken = errors.New("missing token")
+
+func HandleWebSocket(hub *ChatHub, cfg Config) gin.HandlerFunc {
+	// upgrade http cho websocket
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{bearerSubprotocol},
+		CheckOrigin:     originChecker(cfg.AllowedOrigins),
+	}
+
 	return func(c *gin.Context) {
+		// xác thực trước khi upgrade để trả được 401
+		claims, err := authenticate(c.Request, cfg.Secret)
+		if err != nil && (err != errNoToken || !cfg.AllowAnonymous) {
+			apierr.Write(c, apierr.Unauthenticated("invalid or missing token").WithReason("invalid_token"))
+			return
+		}
+
+		if claims != nil && hub.isBanned(claims.UserID) {
+			apierr.Write(c, apierr.Forbidden("banned from chat").WithReason("chat_banned"))
+			return
+		}
+
 		// upgrade connection
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
-			log.Println("websocket upgrade:", err)
+			logger.WarnContext(c.Request.Context(), "upgrade failed", logging.Err(err))
 			return
 		}
 
-		// Lấy username từ Query params
-		username := c.Query("username")
-		if username == "" {
-			username = "anonymous"
-		}
-
-		// Tạo client
-		sendChan := make(chan []byte, 256) // tạo channel
-		client := &client{
+		// Identity lấy từ JWT claims, không tin query params
+		cl := &client{
 			hub:      hub,
 			conn:     conn,
-			send:     sendChan,
-			username: username,
+			send:     make(chan []byte, 256),
+			username: "anonymous",
+			readOnly: true,
+		}
+		if claims != nil {
+			cl.userID = claims.UserID
+			cl.username = claims.Username
+			cl.role = claims.Role
+			cl.readOnly = false
+		}
+
+		// frame quá lớn => gorilla đóng kết nối
+		hub.modMu.Lock()
+		conn.SetReadLimit(hub.mod.readLimit())
+		hub.modMu.Unlock()
+
+		// Đăng ký client qua event loop của hub (hub tự cho vào lobby)
+		reg := registration{
+			client: ClientConnection{Conn: conn, UserID: cl.userID, Username: cl.username, Role: cl.role},
+			send:   cl.send,
+			ready:  make(chan struct{}),
 		}
-		// Đăng ký client vào hub
-		hub.mu.Lock()
-		hub.clients[conn] = username
-		hub.sendChans[conn] = sendChan
-		hub.mu.Unlock()
+		hub.register <- reg
+		<-reg.ready
+		cl.replay(LobbyRoom)
 
 		// Chạy goroutine để gửi và nhận messages
-		go client.readPump()
-		go client.writePump()
+		go cl.readPump()
+		go cl.writePump()
+	}
+}
+
+// token lấy theo thứ tự: Authorization header, query ?token=, subprotocol, cookie
+func authenticate(r *http.Request, secret []byte) (*auth.Claims, error) {
+	token := ""
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimPrefix(h, "Bearer ")
+	}
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		protocols := websocket.Subprotocols(r)
+		for i, p := range protocols {
+			if p == bearerSubprotocol && i+1 < len(protocols) {
+				token = protocols[i+1]
+				break
+			}
+		}
+	}
+	if token == "" {
+		if ck, err := r.Cookie(TokenCookie); err == nil {
+			token = ck.Value
+		}
+	}
+	if token == "" {
+		return nil, errNoToken
+	}
+	return auth.ParseJWT(secret, token)
+}
+
+func originChecker(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			// client không phải browser (CLI, test tool)
+			return true
+		}
+		for _, o := range allowed {
+			if o == "*" || strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		return strings.EqualFold(u.Host, r.Host)
 	}
 }
 
@@ -81,29 +177,170 @@ func (c *client) readPump() {
 		_, messageBytes, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("websocket error: %v", err)
+				logger.Warn("read failed", slog.String("username", c.username), logging.Err(err))
 			}
 			break
 		}
 
-		// parse json
-		var msg models.ChatMessage
-		if err := json.Unmarshal(messageBytes, &msg); err != nil {
-			log.Printf("error unmarshaling message: %v", err)
+		// parse envelope
+		var env Envelope
+		if err := json.Unmarshal(messageBytes, &env); err != nil {
+			c.sendError("", "invalid envelope: "+err.Error())
+			continue
+		}
+
+		// anonymous chỉ được đọc: join/leave room để xem, hỏi who; không chat/typing/read
+		if c.readOnly && env.Type != MsgJoin && env.Type != MsgLeave && env.Type != MsgWho {
+			c.sendError(env.Type, "read-only connection: login required to send messages")
 			continue
 		}
 
-		// Tự động set username và user_id từ connection (tránh spoofing)
-		msg.Username = c.username
-		msg.UserID = c.username // dùng username làm user_id (consistent với user model)
+		if err := c.handle(env); err != nil {
+			c.sendError(env.Type, err.Error())
+		}
+	}
+}
 
-		// set mốc thời gian
-		if msg.Timestamp == 0 {
-			msg.Timestamp = time.Now().Unix()
+// handle xử lý 1 envelope từ client; lỗi trả về được gửi lại dạng {"type":"error"}
+func (c *client) handle(env Envelope) error {
+	switch env.Type {
+	case MsgMute, MsgUnmute, MsgBan, MsgUnban, MsgDelete:
+		var cmd modCommand
+		if err := decodePayload(env.Payload, &cmd); err != nil {
+			return errors.New("invalid moderation command")
 		}
+		cmd.Type = env.Type
+		if err := c.hub.moderate(c, cmd); err != nil {
+			return err
+		}
+		c.sendJSON(MsgModOK, "", map[string]any{"command": cmd.Type, "target": cmd.Target, "message_id": cmd.MessageID})
+		return nil
+	}
+
+	room, err := normalizeRoom(env.Room, c.userID)
+	if err != nil {
+		return err
+	}
+	_, _, isDM := dmParticipants(room)
+
+	switch env.Type {
+	case MsgJoin:
+		if err := c.hub.CheckRoomAccess(room, c.userID); err != nil {
+			return err
+		}
+		if c.hub.join(c.conn, room) {
+			if !isDM {
+				c.hub.publish(MsgPresence, room, Presence{Action: MsgJoin, UserID: c.userID, Username: c.username}, nil)
+			}
+			c.replay(room)
+		}
+	case MsgLeave:
+		if c.hub.leave(c.conn, room) {
+			p := Presence{Action: MsgLeave, UserID: c.userID, Username: c.username}
+			if !isDM {
+				c.hub.publish(MsgPresence, room, p, nil)
+			}
+			c.sendJSON(MsgPresence, room, p) // người rời room cũng nhận xác nhận
+		}
+	case MsgWho:
+		if !isDM && !c.hub.isMember(c.conn, room) {
+			return errors.New("join the room first: " + room)
+		}
+		c.sendJSON(MsgWho, room, c.hub.who(room))
+	case MsgTypingStart, MsgTypingStop:
+		if !isDM && !c.hub.isMember(c.conn, room) {
+			return errors.New("join the room first: " + room)
+		}
+		c.hub.publish(env.Type, room, Typing{UserID: c.userID, Username: c.username}, c.conn)
+	case MsgRead:
+		if !isDM && !c.hub.isMember(c.conn, room) {
+			return errors.New("join the room first: " + room)
+		}
+		var p readPayload
+		if err := decodePayload(env.Payload, &p); err != nil || p.MessageID <= 0 {
+			return errors.New("message_id required")
+		}
+		// id message chỉ có khi lưu lịch sử; id của room khác / không tồn tại sẽ ghim receipt mãi (MAX)
+		if c.hub.store == nil {
+			return errors.New("message history disabled")
+		}
+		ok, err := c.hub.store.HasMessage(room, p.MessageID)
+		if err != nil {
+			logger.Error("check read receipt message failed", slog.String("room", room), logging.Err(err))
+			return errors.New("failed to save read receipt")
+		}
+		if !ok {
+			return errors.New("message not found in room")
+		}
+		if err := c.hub.store.MarkRead(room, c.userID, p.MessageID); err != nil {
+			logger.Error("save read receipt failed", slog.String("room", room), logging.Err(err))
+			return errors.New("failed to save read receipt")
+		}
+		c.hub.publish(MsgRead, room, ReadReceipt{UserID: c.userID, Username: c.username, MessageID: p.MessageID}, nil)
+	case MsgChat:
+		var p chatPayload
+		if err := decodePayload(env.Payload, &p); err != nil {
+			return errors.New("invalid chat payload")
+		}
+		// DM không cần join, room thường phải là thành viên
+		if !isDM && !c.hub.isMember(c.conn, room) {
+			return errors.New("join the room first: " + room)
+		}
+
+		// user lấy từ JWT của connection (tránh spoofing), timestamp lấy từ server
+		msg := models.ChatMessage{
+			Type: MsgChat, Room: room, UserID: c.userID, Username: c.username,
+			Message: p.Message, Timestamp: time.Now().Unix(),
+		}
+		// rate limit, mute, độ dài, filter chain
+		if err := c.hub.admit(c.userID, &msg); err != nil {
+			return err
+		}
+		if c.hub.store != nil {
+			if err := c.hub.store.Save(&msg); err != nil {
+				logger.Error("save message failed", slog.String("room", room), logging.Err(err))
+				return errors.New("failed to save message")
+			}
+		}
+		c.hub.publish(MsgChat, room, msg, nil)
+	default:
+		return errors.New("unknown message type: " + env.Type)
+	}
+	return nil
+}
+
+func decodePayload(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// replay gửi lại N message gần nhất của room cho client vừa join (kèm read receipts)
+func (c *client) replay(room string) {
+	if c.hub.store == nil || c.hub.replayOnJoin <= 0 {
+		return
+	}
+	msgs, err := c.hub.store.Recent(room, c.hub.replayOnJoin)
+	if err != nil {
+		logger.Error("load history failed", slog.String("room", room), logging.Err(err))
+		return
+	}
+	receipts, err := c.hub.store.Receipts(room)
+	if err != nil {
+		logger.Error("load receipts failed", slog.String("room", room), logging.Err(err))
+	}
+	c.sendJSON(MsgHistory, room, map[string]any{"messages": msgs, "read": receipts})
+}
+
+func (c *client) sendError(request, message string) {
+	c.sendJSON(MsgError, "", ErrorPayload{Message: message, Request: request})
+}
 
-		// broadcast message
-		c.hub.broadcast <- msg
+// gửi riêng cho client này (không qua broadcast)
+func (c *client) sendJSON(typ, room string, payload any) {
+	if data := encode(typ, room, payload); data != nil {
+		c.hub.sendTo(c.conn, data)
 	}
 }
 
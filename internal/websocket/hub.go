@@ -1,87 +1,441 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 
+	"mangahub/internal/broker"
+	"mangahub/internal/health"
+	"mangahub/internal/logging"
+	"mangahub/internal/metrics"
+	"mangahub/internal/ratelimit"
 	"mangahub/pkg/models"
 )
 
+var logger = logging.For("websocket")
+
 // Kết nối client
 type ClientConnection struct {
 	Conn     *websocket.Conn
+	UserID   string // "" nếu anonymous
 	Username string
+	Role     string
+	rooms    map[string]struct{} // các room client đang tham gia
+}
+
+// MessageStore lưu lịch sử chat (implement bởi internal/chat)
+type MessageStore interface {
+	Save(msg *models.ChatMessage) error
+	Recent(room string, limit int) ([]models.ChatMessage, error)
+	Delete(id int64) (room string, err error)
+	HasMessage(room string, id int64) (bool, error) // message chưa bị xoá thuộc room
+	MarkRead(room, userID string, messageID int64) error
+	Receipts(room string) (map[string]int64, error)
+}
+
+// SanctionStore lưu mute/ban để còn hiệu lực sau restart và cho instance khởi động sau
+// (implement bởi internal/chat); kind là MsgMute hoặc MsgBan
+type SanctionStore interface {
+	SetSanction(kind, userID string, until time.Time, by, reason string) error
+	ClearSanction(kind, userID string) error
+	ActiveSanctions(kind string) (map[string]time.Time, error)
+}
+
+// roomEvent là envelope đã encode, gửi tới người nhận của room (trừ skip)
+type roomEvent struct {
+	room string
+	data []byte
+	skip *websocket.Conn
+}
+
+// registration đi qua event loop; ready đóng khi client đã vào hub + lobby
+type registration struct {
+	client ClientConnection
+	send   chan []byte
+	ready  chan struct{}
 }
 
 // Hub duy trì kết nối client
 type ChatHub struct {
+	store        MessageStore
+	replayOnJoin int
+	listAccess   ListAccessFunc
+
+	// moderation: giới hạn, filter, mute/ban (cache của sanctions, broker chỉ để đồng bộ live)
+	sanctions SanctionStore
+	modMu     sync.Mutex
+	mod       Moderation
+	buckets   map[string]*ratelimit.Bucket // user_id -> token bucket
+	mutes     map[string]time.Time         // user_id -> hết hạn (zero = vĩnh viễn)
+	bans      map[string]time.Time
+
+	// fan-out sang các instance khác (nil khi chạy 1 instance không broker)
+	fanRoom *broker.Fanout
+	fanUser *broker.Fanout
+	fanMod  *broker.Fanout
+
 	mu         sync.Mutex
-	clients    map[*websocket.Conn]string
+	clients    map[*websocket.Conn]ClientConnection
 	sendChans  map[*websocket.Conn]chan []byte
-	broadcast  chan models.ChatMessage
-	register   chan ClientConnection
+	rooms      map[string]map[*websocket.Conn]struct{} // room -> thành viên
+	broadcast  chan roomEvent
+	register   chan registration
 	unregister chan *websocket.Conn
+	ping       chan chan struct{} // health check: Run trả lời => event loop còn chạy
 }
 
 // Tạo mới hub
 func NewHub() *ChatHub {
 	return &ChatHub{
-		clients:    make(map[*websocket.Conn]string),
+		mod:        DefaultModeration(),
+		buckets:    make(map[string]*ratelimit.Bucket),
+		mutes:      make(map[string]time.Time),
+		bans:       make(map[string]time.Time),
+		clients:    make(map[*websocket.Conn]ClientConnection),
 		sendChans:  make(map[*websocket.Conn]chan []byte),
-		broadcast:  make(chan models.ChatMessage),
-		register:   make(chan ClientConnection),
+		rooms:      make(map[string]map[*websocket.Conn]struct{}),
+		broadcast:  make(chan roomEvent),
+		register:   make(chan registration),
 		unregister: make(chan *websocket.Conn),
+		ping:       make(chan chan struct{}),
+	}
+}
+
+// UseStore bật lưu lịch sử; replayOnJoin = số message gần nhất gửi lại khi client join room
+func (h *ChatHub) UseStore(store MessageStore, replayOnJoin int) {
+	h.store = store
+	h.replayOnJoin = replayOnJoin
+}
+
+// payload trao đổi giữa các instance qua broker
+type remoteRoomEvent struct {
+	Room string          `json:"room"`
+	Data json.RawMessage `json:"data"`
+}
+
+type remoteUserEvent struct {
+	UserID string          `json:"user_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// UseBroker nối hub với các instance khác: room event, notification theo user
+// và trạng thái mute/ban được publish lên broker và nhận lại từ node khác.
+// `who` vẫn chỉ liệt kê kết nối trên node hiện tại.
+func (h *ChatHub) UseBroker(b broker.Broker) error {
+	var err error
+	h.fanRoom, err = broker.NewFanout(b, "chat.room", func(data []byte) {
+		var ev remoteRoomEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			logger.Warn("broker: bad room event", logging.Err(err))
+			return
+		}
+		h.broadcast <- roomEvent{room: ev.Room, data: ev.Data}
+	})
+	if err != nil {
+		return err
+	}
+	h.fanUser, err = broker.NewFanout(b, "chat.user", func(data []byte) {
+		var ev remoteUserEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			logger.Warn("broker: bad user event", logging.Err(err))
+			return
+		}
+		h.mu.Lock()
+		h.sendToUserLocked(ev.UserID, ev.Data)
+		h.mu.Unlock()
+	})
+	if err != nil {
+		return err
+	}
+	h.fanMod, err = broker.NewFanout(b, "chat.moderation", func(data []byte) {
+		var st modState
+		if err := json.Unmarshal(data, &st); err != nil {
+			logger.Warn("broker: bad moderation event", logging.Err(err))
+			return
+		}
+		h.applyMod(st)
+	})
+	return err
+}
+
+// ClientCount là số kết nối WebSocket chat trên instance này
+func (h *ChatHub) ClientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// Health gửi ping qua event loop; Run bị kẹt hoặc chưa chạy => down khi ctx hết hạn
+func (h *ChatHub) Health(ctx context.Context) health.Report {
+	h.mu.Lock()
+	details := map[string]any{"clients": len(h.clients), "rooms": len(h.rooms)}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	select {
+	case h.ping <- done:
+	case <-ctx.Done():
+		return health.Down("event loop not responding", details)
+	}
+	select {
+	case <-done:
+		return health.Up(details)
+	case <-ctx.Done():
+		return health.Down("event loop not responding", details)
+	}
+}
+
+// fanoutRoom gửi room event (đã deliver local) cho các instance khác
+func (h *ChatHub) fanoutRoom(ev roomEvent) {
+	h.fanRoom.Publish(remoteRoomEvent{Room: ev.room, Data: ev.data})
+}
+
+// SendToUser gửi payload (bọc trong {"type":"notification"}) tới mọi kết nối của user.
+// Trả về số kết nối local đã nhận (kết nối ở instance khác nhận qua broker).
+func (h *ChatHub) SendToUser(userID string, payload any) int {
+	data := encode(MsgNotification, "", payload)
+	if data == nil {
+		return 0
+	}
+	h.fanUser.Publish(remoteUserEvent{UserID: userID, Data: data})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sendToUserLocked(userID, data)
+}
+
+func (h *ChatHub) sendToUserLocked(userID string, data []byte) int {
+	sent := 0
+	for conn, cc := range h.clients {
+		if cc.UserID == "" || cc.UserID != userID {
+			continue
+		}
+		select {
+		case h.sendChans[conn] <- data:
+			sent++
+		default:
+			logger.Warn("send channel full, drop notification", slog.String("username", cc.Username))
+			metrics.Dropped(metrics.DropChatSend)
+		}
 	}
+	return sent
+}
+
+// sendTo gửi data cho 1 kết nối (bỏ qua nếu đã bị remove hoặc buffer đầy)
+func (h *ChatHub) sendTo(conn *websocket.Conn, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sendChan, ok := h.sendChans[conn]; ok {
+		select {
+		case sendChan <- data:
+		default:
+			metrics.Dropped(metrics.DropChatSend)
+		}
+	}
+}
+
+// join thêm client vào room; trả về false nếu đã là thành viên
+func (h *ChatHub) join(conn *websocket.Conn, room string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cc, ok := h.clients[conn]
+	if !ok {
+		return false
+	}
+	if _, in := cc.rooms[room]; in {
+		return false
+	}
+	cc.rooms[room] = struct{}{}
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*websocket.Conn]struct{})
+	}
+	h.rooms[room][conn] = struct{}{}
+	return true
+}
+
+// leave bỏ client khỏi room; trả về false nếu không phải thành viên
+func (h *ChatHub) leave(conn *websocket.Conn, room string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cc, ok := h.clients[conn]
+	if !ok {
+		return false
+	}
+	if _, in := cc.rooms[room]; !in {
+		return false
+	}
+	h.leaveLocked(conn, cc, room)
+	return true
+}
+
+func (h *ChatHub) isMember(conn *websocket.Conn, room string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.rooms[room][conn]
+	return ok
+}
+
+func (h *ChatHub) leaveLocked(conn *websocket.Conn, cc ClientConnection, room string) {
+	delete(cc.rooms, room)
+	delete(h.rooms[room], conn)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// removeLocked xoá client khỏi hub và mọi room (caller giữ h.mu)
+func (h *ChatHub) removeLocked(conn *websocket.Conn) {
+	cc, ok := h.clients[conn]
+	if !ok {
+		return
+	}
+	for room := range cc.rooms {
+		h.leaveLocked(conn, cc, room)
+	}
+	delete(h.clients, conn)
+	if sendChan, ok := h.sendChans[conn]; ok {
+		close(sendChan)
+		delete(h.sendChans, conn)
+	}
+	conn.Close()
+}
+
+// người nhận của 1 message: thành viên room, riêng DM thì mọi kết nối của 2 user
+func (h *ChatHub) recipientsLocked(room string) []*websocket.Conn {
+	var conns []*websocket.Conn
+	if a, b, ok := dmParticipants(room); ok {
+		for conn, cc := range h.clients {
+			if cc.UserID == a || cc.UserID == b {
+				conns = append(conns, conn)
+			}
+		}
+		return conns
+	}
+	for conn := range h.rooms[room] {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// publish gửi envelope tới room qua event loop
+func (h *ChatHub) publish(typ, room string, payload any, skip *websocket.Conn) {
+	if data := encode(typ, room, payload); data != nil {
+		ev := roomEvent{room: room, data: data, skip: skip}
+		h.broadcast <- ev
+		h.fanoutRoom(roomEvent{room: room, data: data})
+	}
+}
+
+// deliverLocked gửi data cho người nhận của room; client đầy buffer bị remove
+func (h *ChatHub) deliverLocked(ev roomEvent) {
+	for _, conn := range h.recipientsLocked(ev.room) {
+		if conn == ev.skip {
+			continue
+		}
+		select {
+		case h.sendChans[conn] <- ev.data:
+		default:
+			if cc, ok := h.clients[conn]; ok {
+				logger.Warn("send channel full, removing client", slog.String("username", cc.Username))
+			}
+			metrics.Dropped(metrics.DropChatSend)
+			h.disconnectLocked(conn)
+		}
+	}
+}
+
+// disconnectLocked remove client và báo presence leave cho các room nó đang ở
+func (h *ChatHub) disconnectLocked(conn *websocket.Conn) {
+	cc, ok := h.clients[conn]
+	if !ok {
+		return
+	}
+	rooms := make([]string, 0, len(cc.rooms))
+	for room := range cc.rooms {
+		rooms = append(rooms, room)
+	}
+	h.removeLocked(conn)
+	for _, room := range rooms {
+		if _, _, isDM := dmParticipants(room); isDM {
+			continue
+		}
+		p := Presence{Action: MsgLeave, UserID: cc.UserID, Username: cc.Username}
+		if data := encode(MsgPresence, room, p); data != nil {
+			h.deliverLocked(roomEvent{room: room, data: data})
+			h.fanoutRoom(roomEvent{room: room, data: data})
+		}
+	}
+}
+
+// who liệt kê user online trong room (gộp nhiều kết nối của cùng user)
+func (h *ChatHub) who(room string) WhoResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	res := WhoResult{Users: []OnlineUser{}}
+	index := map[string]int{}
+	for conn := range h.rooms[room] {
+		cc := h.clients[conn]
+		if cc.UserID == "" {
+			res.Anonymous++
+			continue
+		}
+		if i, ok := index[cc.UserID]; ok {
+			res.Users[i].Connections++
+			continue
+		}
+		index[cc.UserID] = len(res.Users)
+		res.Users = append(res.Users, OnlineUser{UserID: cc.UserID, Username: cc.Username, Connections: 1})
+	}
+	sort.Slice(res.Users, func(i, j int) bool { return res.Users[i].Username < res.Users[j].Username })
+	return res
 }
 
 // Chạy loop để handle connection vs broadcasting
 func (h *ChatHub) Run() {
 	for {
 		select {
-		case client := <-h.register:
+		case reg := <-h.register:
+			client := reg.client
+			if client.rooms == nil {
+				client.rooms = make(map[string]struct{})
+			}
 			h.mu.Lock()
-			h.clients[client.Conn] = client.Username
+			h.clients[client.Conn] = client
+			h.sendChans[client.Conn] = reg.send
 			h.mu.Unlock()
-			log.Printf("Client %s connected", client.Username)
-		case conn := <-h.unregister:
+			// mặc định vào lobby
+			h.join(client.Conn, LobbyRoom)
 			h.mu.Lock()
-			if username, ok := h.clients[conn]; ok {
-				delete(h.clients, conn)
-				if sendChan, ok := h.sendChans[conn]; ok {
-					close(sendChan)
-					delete(h.sendChans, conn)
-				}
-				conn.Close()
-				log.Printf("Client %s disconnected", username)
+			p := Presence{Action: MsgJoin, UserID: client.UserID, Username: client.Username}
+			if data := encode(MsgPresence, LobbyRoom, p); data != nil {
+				h.deliverLocked(roomEvent{room: LobbyRoom, data: data, skip: client.Conn})
+				h.fanoutRoom(roomEvent{room: LobbyRoom, data: data})
 			}
 			h.mu.Unlock()
+			close(reg.ready)
+			logger.Info("client connected", slog.String("username", client.Username))
 
-		case message := <-h.broadcast:
-			data, err := json.Marshal(message)
-			if err != nil {
-				log.Println("Error marshalling message:", err)
-				continue
+		case conn := <-h.unregister:
+			h.mu.Lock()
+			if cc, ok := h.clients[conn]; ok {
+				h.disconnectLocked(conn)
+				logger.Info("client disconnected", slog.String("username", cc.Username))
 			}
+			h.mu.Unlock()
 
+		case ev := <-h.broadcast:
 			h.mu.Lock()
-			for conn, sendChan := range h.sendChans {
-				select {
-				case sendChan <- data:
-
-				default:
-					if username, ok := h.clients[conn]; ok {
-						log.Printf("Client %s send channel full, removing", username)
-					}
-					delete(h.clients, conn)
-					delete(h.sendChans, conn)
-					close(sendChan)
-					conn.Close()
-				}
-			}
+			h.deliverLocked(ev)
 			h.mu.Unlock()
+
+		case done := <-h.ping:
+			close(done)
 		}
 	}
 }
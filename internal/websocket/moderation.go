@@ -0,0 +1,227 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+	"unicode/utf8"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/logging"
+	"mangahub/internal/ratelimit"
+	"mangahub/pkg/models"
+)
+
+// Lệnh moderation (chỉ admin/moderator)
+const (
+	MsgMute   = "mute"
+	MsgUnmute = "unmute"
+	MsgBan    = "ban"
+	MsgUnban  = "unban"
+	MsgDelete = "delete"
+)
+
+// Moderation cấu hình giới hạn + filter, áp dụng trong hub
+type Moderation struct {
+	RatePerSec    float64 // số message/giây mỗi user (token bucket)
+	Burst         int
+	MaxMessageLen int // số ký tự tối đa của 1 message
+	Filters       []ContentFilter
+}
+
+func DefaultModeration() Moderation {
+	return Moderation{
+		RatePerSec:    1,
+		Burst:         5,
+		MaxMessageLen: 1000,
+		Filters:       []ContentFilter{NewProfanityFilter(DefaultProfanity), LinkFilter{}, SpoilerFilter{}},
+	}
+}
+
+// giới hạn frame cho SetReadLimit: message (UTF-8 tối đa 4 byte/ký tự) + phần JSON bao ngoài
+func (m Moderation) readLimit() int64 {
+	return int64(m.MaxMessageLen*4 + 1024)
+}
+
+type modCommand struct {
+	Type      string `json:"type"`
+	Target    string `json:"target"`   // user_id bị mute/ban
+	Duration  int    `json:"duration"` // giây, 0 = vĩnh viễn
+	MessageID int64  `json:"message_id"`
+	Reason    string `json:"reason"`
+}
+
+func isModerator(role string) bool {
+	return role == auth.RoleAdmin || role == auth.RoleModerator
+}
+
+// UseModeration thay cấu hình moderation mặc định
+func (h *ChatHub) UseModeration(m Moderation) {
+	h.modMu.Lock()
+	defer h.modMu.Unlock()
+	h.mod = m
+	h.buckets = make(map[string]*ratelimit.Bucket)
+}
+
+// UseSanctions lưu mute/ban vào store và nạp lại các mute/ban còn hiệu lực
+func (h *ChatHub) UseSanctions(store SanctionStore) error {
+	mutes, err := store.ActiveSanctions(MsgMute)
+	if err != nil {
+		return err
+	}
+	bans, err := store.ActiveSanctions(MsgBan)
+	if err != nil {
+		return err
+	}
+	h.modMu.Lock()
+	defer h.modMu.Unlock()
+	h.sanctions = store
+	maps.Copy(h.mutes, mutes)
+	maps.Copy(h.bans, bans)
+	return nil
+}
+
+// admit kiểm tra mute, rate limit, độ dài và chạy filter chain; có thể sửa msg
+func (h *ChatHub) admit(userID string, msg *models.ChatMessage) error {
+	h.modMu.Lock()
+	mod := h.mod
+	if until, ok := h.mutes[userID]; ok {
+		if active(until) {
+			h.modMu.Unlock()
+			return errors.New("you are muted")
+		}
+		delete(h.mutes, userID)
+	}
+	b, ok := h.buckets[userID]
+	if !ok {
+		b = ratelimit.NewBucket(mod.RatePerSec, mod.Burst)
+		h.buckets[userID] = b
+	}
+	h.modMu.Unlock()
+
+	if ok, wait := b.AllowAt(time.Now()); !ok {
+		return fmt.Errorf("rate limited, retry in %.1fs", wait.Seconds())
+	}
+	if mod.MaxMessageLen > 0 && utf8.RuneCountInString(msg.Message) > mod.MaxMessageLen {
+		return fmt.Errorf("message too long (max %d characters)", mod.MaxMessageLen)
+	}
+	for _, f := range mod.Filters {
+		if err := f.Apply(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *ChatHub) isBanned(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	h.modMu.Lock()
+	defer h.modMu.Unlock()
+	until, ok := h.bans[userID]
+	if ok && !active(until) {
+		delete(h.bans, userID)
+		return false
+	}
+	return ok
+}
+
+// moderate xử lý lệnh mute/ban/delete từ moderator
+func (h *ChatHub) moderate(c *client, cmd modCommand) error {
+	if !isModerator(c.role) {
+		return errors.New("moderator role required")
+	}
+	if cmd.Type != MsgDelete && cmd.Target == "" {
+		return errors.New("target required")
+	}
+
+	var until time.Time // zero = vĩnh viễn
+	if cmd.Duration > 0 {
+		until = time.Now().Add(time.Duration(cmd.Duration) * time.Second)
+	}
+
+	switch cmd.Type {
+	case MsgMute, MsgUnmute, MsgBan, MsgUnban:
+		if err := h.saveSanction(c.userID, cmd, until); err != nil {
+			logger.Error("save sanction failed", slog.String("action", cmd.Type), slog.String("target", cmd.Target), logging.Err(err))
+			return errors.New("failed to save moderation")
+		}
+		st := modState{Type: cmd.Type, Target: cmd.Target, Until: until}
+		h.applyMod(st)
+		h.fanMod.Publish(st)
+	case MsgDelete:
+		if h.store == nil {
+			return errors.New("message history disabled")
+		}
+		room, err := h.store.Delete(cmd.MessageID)
+		if err != nil {
+			return errors.New("message not found")
+		}
+		// client xoá message khỏi UI khi nhận event này
+		h.publish(MsgDelete, room, map[string]any{
+			"id": cmd.MessageID, "user_id": c.userID, "username": c.username,
+		}, nil)
+	}
+
+	logger.Info("moderation", slog.String("action", cmd.Type), slog.String("target", cmd.Target),
+		slog.Int64("message_id", cmd.MessageID), slog.Int("duration_s", cmd.Duration), slog.String("by", c.userID), slog.String("reason", cmd.Reason))
+	return nil
+}
+
+func (h *ChatHub) saveSanction(by string, cmd modCommand, until time.Time) error {
+	if h.sanctions == nil {
+		return nil
+	}
+	switch cmd.Type {
+	case MsgMute, MsgBan:
+		return h.sanctions.SetSanction(cmd.Type, cmd.Target, until, by, cmd.Reason)
+	case MsgUnmute:
+		return h.sanctions.ClearSanction(MsgMute, cmd.Target)
+	default:
+		return h.sanctions.ClearSanction(MsgBan, cmd.Target)
+	}
+}
+
+// modState là thay đổi mute/ban, đồng bộ giữa các instance qua broker
+type modState struct {
+	Type   string    `json:"type"`
+	Target string    `json:"target"`
+	Until  time.Time `json:"until"` // zero = vĩnh viễn
+}
+
+func (h *ChatHub) applyMod(st modState) {
+	h.modMu.Lock()
+	switch st.Type {
+	case MsgMute:
+		h.mutes[st.Target] = st.Until
+	case MsgUnmute:
+		delete(h.mutes, st.Target)
+	case MsgBan:
+		h.bans[st.Target] = st.Until
+	case MsgUnban:
+		delete(h.bans, st.Target)
+	}
+	h.modMu.Unlock()
+
+	if st.Type == MsgBan {
+		h.disconnectUser(st.Target)
+	}
+}
+
+// disconnectUser đóng mọi kết nối của user (sau khi bị ban)
+func (h *ChatHub) disconnectUser(userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for conn, cc := range h.clients {
+		if cc.UserID == userID {
+			h.disconnectLocked(conn)
+		}
+	}
+}
+
+func active(until time.Time) bool {
+	return until.IsZero() || time.Now().Before(until)
+}
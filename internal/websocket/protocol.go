@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"mangahub/internal/logging"
+)
+
+// Các type của envelope ngoài chat/join/leave (rooms.go) và lệnh moderation (moderation.go)
+const (
+	MsgTypingStart  = "typing_start"
+	MsgTypingStop   = "typing_stop"
+	MsgRead         = "read"     // read receipt
+	MsgWho          = "who"      // hỏi danh sách user online trong room
+	MsgPresence     = "presence" // server -> client: có người join/leave room
+	MsgHistory      = "history"
+	MsgError        = "error"
+	MsgNotification = "notification"
+	MsgModOK        = "mod_ok"
+)
+
+// Envelope là khung chung của mọi message WebSocket (2 chiều):
+//
+//	{"type": "chat", "room": "manga:one-piece", "payload": {"message": "hi"}}
+type Envelope struct {
+	Type    string          `json:"type"`
+	Room    string          `json:"room,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// payload client gửi lên
+type chatPayload struct {
+	Message string `json:"message"`
+}
+
+type readPayload struct {
+	MessageID int64 `json:"message_id"`
+}
+
+// payload server gửi xuống
+type Presence struct {
+	Action   string `json:"action"` // join | leave
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type Typing struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type ReadReceipt struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	MessageID int64  `json:"message_id"`
+}
+
+type OnlineUser struct {
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	Connections int    `json:"connections"`
+}
+
+type WhoResult struct {
+	Users     []OnlineUser `json:"users"`
+	Anonymous int          `json:"anonymous"` // kết nối chỉ đọc, không có user
+}
+
+type ErrorPayload struct {
+	Message string `json:"message"`
+	Request string `json:"request,omitempty"` // type của envelope gây lỗi
+}
+
+// encode đóng gói payload vào envelope; lỗi marshal chỉ log (payload do server tạo)
+func encode(typ, room string, payload any) []byte {
+	var raw json.RawMessage
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			logger.Error("marshal payload failed", slog.String("type", typ), logging.Err(err))
+			return nil
+		}
+		raw = b
+	}
+	data, err := json.Marshal(Envelope{Type: typ, Room: room, Payload: raw})
+	if err != nil {
+		logger.Error("marshal envelope failed", slog.String("type", typ), logging.Err(err))
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,153 @@
+package websocket
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+const (
+	MsgChat  = "chat"
+	MsgJoin  = "join"
+	MsgLeave = "leave"
+)
+
+// Room mặc định, mọi client tự join khi kết nối
+const LobbyRoom = "lobby"
+
+var errInvalidRoom = errors.New("invalid room")
+
+// NormalizeRoom cho HTTP (vd GET /chat/:room/messages)
+func NormalizeRoom(room, userID string) (string, error) {
+	return normalizeRoom(room, userID)
+}
+
+// NormalizeRoomAdmin cho thao tác của admin (vd retention): DM phải ghi đủ "dm:<a>:<b>"
+// vì admin không phải người trong cuộc
+func NormalizeRoomAdmin(room string) (string, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(room), "dm:")
+	if !ok {
+		return normalizeRoom(room, "")
+	}
+	a, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", errors.New("direct message room must be dm:<user_a>:<user_b>")
+	}
+	return normalizeRoom(room, a)
+}
+
+// IsDMParticipant: room không phải DM => true; DM => user phải là 1 trong 2 người
+func IsDMParticipant(room, userID string) bool {
+	a, b, ok := dmParticipants(room)
+	return !ok || userID == a || userID == b
+}
+
+// normalizeRoom kiểm tra tên room và chuẩn hoá DM thành "dm:<a>:<b>" (a < b).
+// userID là người gửi/join, cần cho DM ("dm:<người kia>").
+func normalizeRoom(room, userID string) (string, error) {
+	room = strings.TrimSpace(room)
+	if room == "" || room == LobbyRoom {
+		return LobbyRoom, nil
+	}
+
+	kind, rest, ok := strings.Cut(room, ":")
+	if !ok || rest == "" {
+		return "", errInvalidRoom
+	}
+	parts := strings.Split(rest, ":")
+	for _, p := range parts {
+		if !validName(p) {
+			return "", errInvalidRoom
+		}
+	}
+
+	switch kind {
+	case "manga":
+		if len(parts) != 1 {
+			return "", errInvalidRoom
+		}
+		return room, nil
+	case "list":
+		// list:<owner>:<list_name>
+		if len(parts) != 2 {
+			return "", errInvalidRoom
+		}
+		return room, nil
+	case "dm":
+		if userID == "" {
+			return "", errors.New("login required for direct messages")
+		}
+		users := parts
+		if len(parts) == 1 {
+			users = []string{userID, parts[0]}
+		}
+		if len(users) != 2 || (users[0] != userID && users[1] != userID) || users[0] == users[1] {
+			return "", errInvalidRoom
+		}
+		sort.Strings(users)
+		return "dm:" + users[0] + ":" + users[1], nil
+	}
+	return "", errInvalidRoom
+}
+
+// ListAccessFunc kiểm tra viewerID có được vào room list:<owner>:<list_name> không
+// (list tồn tại, chủ list hoặc follower, list không private); nil = được phép
+type ListAccessFunc func(owner, listName, viewerID string) error
+
+// UseListAccess bật kiểm tra quyền cho room reading list; chưa cấu hình thì chỉ chủ list được vào
+func (h *ChatHub) UseListAccess(fn ListAccessFunc) {
+	h.listAccess = fn
+}
+
+// CheckRoomAccess: quyền đọc/join room đã chuẩn hoá (DM: 2 người trong cuộc, list: theo ListAccessFunc)
+func (h *ChatHub) CheckRoomAccess(room, userID string) error {
+	if !IsDMParticipant(room, userID) {
+		return errors.New("not a participant of this conversation")
+	}
+	owner, listName, ok := listRoom(room)
+	if !ok {
+		return nil
+	}
+	if userID == "" {
+		return errors.New("login required for list rooms")
+	}
+	if h.listAccess == nil {
+		if owner != userID {
+			return errors.New("list room is only open to its owner")
+		}
+		return nil
+	}
+	return h.listAccess(owner, listName, userID)
+}
+
+// listRoom tách "list:<owner>:<list_name>"
+func listRoom(room string) (owner, listName string, ok bool) {
+	rest, ok := strings.CutPrefix(room, "list:")
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
+
+// dmParticipants trả về 2 user của room DM đã chuẩn hoá
+func dmParticipants(room string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(room, "dm:")
+	if !ok {
+		return "", "", false
+	}
+	a, b, ok := strings.Cut(rest, ":")
+	return a, b, ok
+}
+
+// giống sanitizeMangaID: chữ, số, '-', '_'
+func validName(s string) bool {
+	if s == "" || len(s) > 50 {
+		return false
+	}
+	for _, r := range s {
+		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverName là sqlite3 bọc thêm hook đo thời gian mỗi câu lệnh
+const driverName = "sqlite3_hooked"
+
+// QueryHook nhận loại câu lệnh (select/insert/update/delete/other), thời gian chạy và lỗi
+type QueryHook func(op string, d time.Duration, err error)
+
+var queryHook QueryHook
+
+// SetQueryHook đăng ký hook (vd. metrics); gọi trước khi chạy query
+func SetQueryHook(fn QueryHook) {
+	queryHook = fn
+}
+
+// QueryTracer nhận thêm ctx của caller và câu SQL (vd. tạo span con của request);
+// gọi ngay sau khi câu lệnh chạy xong, start là lúc bắt đầu.
+type QueryTracer func(ctx context.Context, op, query string, start time.Time, err error)
+
+var queryTracer QueryTracer
+
+// SetQueryTracer đăng ký tracer; gọi trước khi chạy query
+func SetQueryTracer(fn QueryTracer) {
+	queryTracer = fn
+}
+
+func init() {
+	sql.Register(driverName, &hookDriver{&sqlite3.SQLiteDriver{}})
+}
+
+type hookDriver struct {
+	driver.Driver
+}
+
+func (d *hookDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &hookConn{conn}, nil
+}
+
+type hookConn struct {
+	driver.Conn
+}
+
+func (c *hookConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	observe(ctx, query, start, err)
+	return res, err
+}
+
+func (c *hookConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	observe(ctx, query, start, err)
+	return rows, err
+}
+
+func (c *hookConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *hookConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+// observe: thời gian Query chỉ tính tới lúc có rows, không gồm lúc scan
+func observe(ctx context.Context, query string, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	op := queryOp(query)
+	if queryHook != nil {
+		queryHook(op, time.Since(start), err)
+	}
+	if queryTracer != nil {
+		queryTracer(ctx, op, query, start, err)
+	}
+}
+
+func queryOp(query string) string {
+	q := strings.TrimSpace(query)
+	if i := strings.IndexAny(q, " \t\n\r("); i > 0 {
+		q = q[:i]
+	}
+	switch op := strings.ToLower(q); op {
+	case "select", "insert", "update", "delete":
+		return op
+	case "with":
+		return "select"
+	}
+	return "other"
+}
@@ -30,6 +30,157 @@ func Migrate(db *sql.DB) error {
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			PRIMARY KEY (user_id, manga_id)
 		);`,
+		// lịch sử đọc: mỗi lần cập nhật progress là 1 dòng (dùng cho /me/stats)
+		`CREATE TABLE IF NOT EXISTS reading_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT,
+			manga_id TEXT,
+			chapter INTEGER,
+			chapters_read INTEGER,
+			status TEXT,
+			read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_reading_history_user ON reading_history(user_id, read_at);`,
+		// rating 1-10 + review (text tuỳ chọn), mỗi user 1 review / manga
+		`CREATE TABLE IF NOT EXISTS reviews (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT,
+			manga_id TEXT,
+			score INTEGER CHECK (score BETWEEN 1 AND 10),
+			body TEXT,
+			spoiler INTEGER DEFAULT 0,
+			hidden INTEGER DEFAULT 0,
+			hidden_reason TEXT,
+			moderated_by TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, manga_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_reviews_manga ON reviews(manga_id, hidden);`,
+		`CREATE TABLE IF NOT EXISTS review_votes (
+			review_id INTEGER,
+			user_id TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (review_id, user_id)
+		);`,
+		// item-item similarity, tính offline bởi package recommend
+		`CREATE TABLE IF NOT EXISTS manga_similarity (
+			manga_id TEXT,
+			similar_id TEXT,
+			score REAL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (manga_id, similar_id)
+		);`,
+		// bộ đếm popularity (tổng) + bucket theo ngày cho trending 7/30 ngày
+		`CREATE TABLE IF NOT EXISTS manga_stats (
+			manga_id TEXT PRIMARY KEY,
+			readers INTEGER DEFAULT 0,
+			completions INTEGER DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS manga_daily_counts (
+			manga_id TEXT,
+			day TEXT,
+			adds INTEGER DEFAULT 0,
+			completions INTEGER DEFAULT 0,
+			PRIMARY KEY (manga_id, day)
+		);`,
+		// follow manga để nhận thông báo chapter mới
+		`CREATE TABLE IF NOT EXISTS manga_follows (
+			user_id TEXT,
+			manga_id TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, manga_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS chapter_releases (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			manga_id TEXT,
+			chapter INTEGER,
+			title TEXT,
+			released_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (manga_id, chapter)
+		);`,
+		`CREATE TABLE IF NOT EXISTS notifications (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT,
+			type TEXT,
+			manga_id TEXT,
+			chapter INTEGER,
+			message TEXT,
+			read_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, read_at);`,
+		`CREATE TABLE IF NOT EXISTS notification_prefs (
+			user_id TEXT PRIMARY KEY,
+			websocket INTEGER DEFAULT 1,
+			tcp INTEGER DEFAULT 1,
+			udp INTEGER DEFAULT 1,
+			grpc INTEGER DEFAULT 1
+		);`,
+		// lịch sử chat theo room, cursor = id
+		`CREATE TABLE IF NOT EXISTS chat_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			room TEXT,
+			user_id TEXT,
+			username TEXT,
+			type TEXT,
+			message TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_chat_messages_room ON chat_messages(room, id);`,
+		// follow giữa user (social graph)
+		`CREATE TABLE IF NOT EXISTS user_follows (
+			follower_id TEXT,
+			followee_id TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (follower_id, followee_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_user_follows_followee ON user_follows(followee_id);`,
+		// privacy: không có dòng = public hết
+		`CREATE TABLE IF NOT EXISTS user_privacy (
+			user_id TEXT PRIMARY KEY,
+			profile_public INTEGER DEFAULT 1,
+			activity_public INTEGER DEFAULT 1
+		);`,
+		// reading list ẩn khỏi profile và activity feed
+		`CREATE TABLE IF NOT EXISTS list_privacy (
+			user_id TEXT,
+			list_name TEXT,
+			PRIMARY KEY (user_id, list_name)
+		);`,
+		// token 1 lần (xác thực email, reset password); chỉ lưu hash
+		`CREATE TABLE IF NOT EXISTS user_tokens (
+			token_hash TEXT PRIMARY KEY,
+			user_id TEXT,
+			purpose TEXT,
+			email TEXT,
+			expires_at TIMESTAMP,
+			used_at TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_user_tokens_user ON user_tokens(user_id, purpose);`,
+		// read receipt: message mới nhất user đã đọc trong room
+		`CREATE TABLE IF NOT EXISTS chat_read_receipts (
+			room TEXT,
+			user_id TEXT,
+			last_read_id INTEGER,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (room, user_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS chat_room_settings (
+			room TEXT PRIMARY KEY,
+			retention_hours INTEGER
+		);`,
+		// mute/ban trong chat (until NULL = vĩnh viễn), hub nạp lại khi khởi động
+		`CREATE TABLE IF NOT EXISTS chat_sanctions (
+			user_id TEXT,
+			kind TEXT, -- mute | ban
+			until TIMESTAMP,
+			created_by TEXT,
+			reason TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, kind)
+		);`,
 	}
 
 	for i, s := range stmts {
@@ -42,5 +193,47 @@ func Migrate(db *sql.DB) error {
 	// SQLite doesn't support IF NOT EXISTS for ALTER TABLE, so we'll try and ignore error if column exists
 	_, _ = db.Exec(`ALTER TABLE user_progress ADD COLUMN list_name TEXT DEFAULT '';`)
 
+	// role cho user ("user" | "admin"), admin dùng cho moderation
+	_, _ = db.Exec(`ALTER TABLE users ADD COLUMN role TEXT DEFAULT 'user';`)
+	// message bị moderator xoá (soft delete, ẩn khỏi history)
+	_, _ = db.Exec(`ALTER TABLE chat_messages ADD COLUMN deleted_at TIMESTAMP;`)
+	_, _ = db.Exec(`ALTER TABLE chat_messages ADD COLUMN spoiler INTEGER DEFAULT 0;`)
+	// profile + account lifecycle
+	_, _ = db.Exec(`ALTER TABLE users ADD COLUMN display_name TEXT DEFAULT '';`)
+	_, _ = db.Exec(`ALTER TABLE users ADD COLUMN avatar_url TEXT DEFAULT '';`)
+	_, _ = db.Exec(`ALTER TABLE users ADD COLUMN bio TEXT DEFAULT '';`)
+	_, _ = db.Exec(`ALTER TABLE users ADD COLUMN email TEXT;`)
+	_, _ = db.Exec(`ALTER TABLE users ADD COLUMN email_verified INTEGER DEFAULT 0;`)
+	// JWT phát hành trước mốc này bị thu hồi (đổi/reset password)
+	_, _ = db.Exec(`ALTER TABLE users ADD COLUMN tokens_valid_after TIMESTAMP;`)
+	if _, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email IS NOT NULL;`); err != nil {
+		return fmt.Errorf("migrate users email index: %w", err)
+	}
+	// activity feed: loại event (library | progress) và list tại thời điểm ghi
+	_, _ = db.Exec(`ALTER TABLE reading_history ADD COLUMN event TEXT DEFAULT 'progress';`)
+	_, _ = db.Exec(`ALTER TABLE reading_history ADD COLUMN list_name TEXT DEFAULT 'default';`)
+
+	// mốc sửa đổi của manga cho ETag / Last-Modified; ALTER không cho default CURRENT_TIMESTAMP
+	// nên backfill dòng cũ rồi để trigger tự set khi INSERT/UPDATE không ghi updated_at
+	_, _ = db.Exec(`ALTER TABLE manga ADD COLUMN updated_at TIMESTAMP;`)
+	for i, s := range []string{
+		`UPDATE manga SET updated_at = CURRENT_TIMESTAMP WHERE updated_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_manga_updated ON manga(updated_at);`,
+		`CREATE TRIGGER IF NOT EXISTS manga_touch_insert AFTER INSERT ON manga
+		FOR EACH ROW WHEN NEW.updated_at IS NULL
+		BEGIN
+			UPDATE manga SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS manga_touch AFTER UPDATE ON manga
+		FOR EACH ROW WHEN NEW.updated_at IS OLD.updated_at
+		BEGIN
+			UPDATE manga SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+		END;`,
+	} {
+		if _, err := db.Exec(s); err != nil {
+			return fmt.Errorf("migrate manga updated_at %d: %w", i, err)
+		}
+	}
+
 	return nil
 }
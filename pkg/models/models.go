@@ -26,13 +26,22 @@ type ProgressUpdate struct {
 	UserID    string `json:"user_id"`
 	MangaID   string `json:"manga_id"`
 	Chapter   int    `json:"chapter"`
+	ListName  string `json:"list_name,omitempty"`
 	Timestamp int64  `json:"timestamp"`
+	RequestID string `json:"request_id,omitempty"` // request PATCH /progress sinh ra update
+	// trace context (W3C traceparent) của request, chỉ dùng nội bộ để nối span publish vào trace
+	Trace map[string]string `json:"-"`
 }
 
 // chat format
+// Type: "chat" (mặc định), "join", "leave"; Room: "lobby", "manga:<id>", "list:<owner>:<name>", "dm:<user>"
 type ChatMessage struct {
+	ID        int64  `json:"id,omitempty"` // gán khi lưu vào chat_messages
+	Type      string `json:"type,omitempty"`
+	Room      string `json:"room,omitempty"`
 	UserID    string `json:"user_id"`
 	Username  string `json:"username"`
 	Message   string `json:"message"`
+	Spoiler   bool   `json:"spoiler,omitempty"` // gắn bởi spoiler filter
 	Timestamp int64  `json:"timestamp"`
 }
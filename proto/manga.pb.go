@@ -9,6 +9,7 @@ package proto
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -159,12 +160,17 @@ func (x *MangaResponse) GetDescription() string {
 }
 
 type SearchRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
-	Genre         string                 `protobuf:"bytes,2,opt,name=genre,proto3" json:"genre,omitempty"`
-	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
-	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
-	Offset        int32                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Query        string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Genre        string                 `protobuf:"bytes,2,opt,name=genre,proto3" json:"genre,omitempty"`
+	Status       string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Limit        int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset       int32                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"` // chỉ cho client cũ, bị bỏ qua khi có cursor
+	SortBy       string                 `protobuf:"bytes,6,opt,name=sort_by,json=sortBy,proto3" json:"sort_by,omitempty"`
+	Cursor       string                 `protobuf:"bytes,7,opt,name=cursor,proto3" json:"cursor,omitempty"` // next_cursor của trang trước (cùng query/genre/status/sort_by)
+	IncludeTotal bool                   `protobuf:"varint,8,opt,name=include_total,json=includeTotal,proto3" json:"include_total,omitempty"`
+	// field của MangaResponse cần trả (vd paths: ["title", "author"]); trống = đủ field, id luôn có
+	ReadMask      *fieldmaskpb.FieldMask `protobuf:"bytes,9,opt,name=read_mask,json=readMask,proto3" json:"read_mask,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -234,11 +240,42 @@ func (x *SearchRequest) GetOffset() int32 {
 	return 0
 }
 
+func (x *SearchRequest) GetSortBy() string {
+	if x != nil {
+		return x.SortBy
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetIncludeTotal() bool {
+	if x != nil {
+		return x.IncludeTotal
+	}
+	return false
+}
+
+func (x *SearchRequest) GetReadMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.ReadMask
+	}
+	return nil
+}
+
 type SearchResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Results       []*MangaResponse       `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
 	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
 	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	SortBy        string                 `protobuf:"bytes,4,opt,name=sort_by,json=sortBy,proto3" json:"sort_by,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,5,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // rỗng khi đã hết kết quả
+	Total         *int32                 `protobuf:"varint,6,opt,name=total,proto3,oneof" json:"total,omitempty"`                      // chỉ có khi include_total
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -294,6 +331,27 @@ func (x *SearchResponse) GetOffset() int32 {
 	return 0
 }
 
+func (x *SearchResponse) GetSortBy() string {
+	if x != nil {
+		return x.SortBy
+	}
+	return ""
+}
+
+func (x *SearchResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+func (x *SearchResponse) GetTotal() int32 {
+	if x != nil && x.Total != nil {
+		return *x.Total
+	}
+	return 0
+}
+
 type ProgressRequest struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	UserId         string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -414,11 +472,607 @@ func (x *ProgressResponse) GetMessage() string {
 	return ""
 }
 
+type UserStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserStatsRequest) Reset() {
+	*x = UserStatsRequest{}
+	mi := &file_proto_manga_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserStatsRequest) ProtoMessage() {}
+
+func (x *UserStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserStatsRequest.ProtoReflect.Descriptor instead.
+func (*UserStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UserStatsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type PeriodCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Period        string                 `protobuf:"bytes,1,opt,name=period,proto3" json:"period,omitempty"`
+	Chapters      int32                  `protobuf:"varint,2,opt,name=chapters,proto3" json:"chapters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeriodCount) Reset() {
+	*x = PeriodCount{}
+	mi := &file_proto_manga_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeriodCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeriodCount) ProtoMessage() {}
+
+func (x *PeriodCount) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeriodCount.ProtoReflect.Descriptor instead.
+func (*PeriodCount) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PeriodCount) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *PeriodCount) GetChapters() int32 {
+	if x != nil {
+		return x.Chapters
+	}
+	return 0
+}
+
+type NameCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NameCount) Reset() {
+	*x = NameCount{}
+	mi := &file_proto_manga_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NameCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NameCount) ProtoMessage() {}
+
+func (x *NameCount) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NameCount.ProtoReflect.Descriptor instead.
+func (*NameCount) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *NameCount) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NameCount) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type UserStatsResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	UserId            string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	TotalManga        int32                  `protobuf:"varint,2,opt,name=total_manga,json=totalManga,proto3" json:"total_manga,omitempty"`
+	StatusCounts      map[string]int32       `protobuf:"bytes,3,rep,name=status_counts,json=statusCounts,proto3" json:"status_counts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	CompletionRate    float64                `protobuf:"fixed64,4,opt,name=completion_rate,json=completionRate,proto3" json:"completion_rate,omitempty"`
+	AvgDaysToFinish   float64                `protobuf:"fixed64,5,opt,name=avg_days_to_finish,json=avgDaysToFinish,proto3" json:"avg_days_to_finish,omitempty"`
+	ChaptersToday     int32                  `protobuf:"varint,6,opt,name=chapters_today,json=chaptersToday,proto3" json:"chapters_today,omitempty"`
+	ChaptersThisWeek  int32                  `protobuf:"varint,7,opt,name=chapters_this_week,json=chaptersThisWeek,proto3" json:"chapters_this_week,omitempty"`
+	ChaptersThisMonth int32                  `protobuf:"varint,8,opt,name=chapters_this_month,json=chaptersThisMonth,proto3" json:"chapters_this_month,omitempty"`
+	CurrentStreak     int32                  `protobuf:"varint,9,opt,name=current_streak,json=currentStreak,proto3" json:"current_streak,omitempty"`
+	LongestStreak     int32                  `protobuf:"varint,10,opt,name=longest_streak,json=longestStreak,proto3" json:"longest_streak,omitempty"`
+	Daily             []*PeriodCount         `protobuf:"bytes,11,rep,name=daily,proto3" json:"daily,omitempty"`
+	Weekly            []*PeriodCount         `protobuf:"bytes,12,rep,name=weekly,proto3" json:"weekly,omitempty"`
+	Monthly           []*PeriodCount         `protobuf:"bytes,13,rep,name=monthly,proto3" json:"monthly,omitempty"`
+	Genres            []*NameCount           `protobuf:"bytes,14,rep,name=genres,proto3" json:"genres,omitempty"`
+	Authors           []*NameCount           `protobuf:"bytes,15,rep,name=authors,proto3" json:"authors,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *UserStatsResponse) Reset() {
+	*x = UserStatsResponse{}
+	mi := &file_proto_manga_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserStatsResponse) ProtoMessage() {}
+
+func (x *UserStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserStatsResponse.ProtoReflect.Descriptor instead.
+func (*UserStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UserStatsResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UserStatsResponse) GetTotalManga() int32 {
+	if x != nil {
+		return x.TotalManga
+	}
+	return 0
+}
+
+func (x *UserStatsResponse) GetStatusCounts() map[string]int32 {
+	if x != nil {
+		return x.StatusCounts
+	}
+	return nil
+}
+
+func (x *UserStatsResponse) GetCompletionRate() float64 {
+	if x != nil {
+		return x.CompletionRate
+	}
+	return 0
+}
+
+func (x *UserStatsResponse) GetAvgDaysToFinish() float64 {
+	if x != nil {
+		return x.AvgDaysToFinish
+	}
+	return 0
+}
+
+func (x *UserStatsResponse) GetChaptersToday() int32 {
+	if x != nil {
+		return x.ChaptersToday
+	}
+	return 0
+}
+
+func (x *UserStatsResponse) GetChaptersThisWeek() int32 {
+	if x != nil {
+		return x.ChaptersThisWeek
+	}
+	return 0
+}
+
+func (x *UserStatsResponse) GetChaptersThisMonth() int32 {
+	if x != nil {
+		return x.ChaptersThisMonth
+	}
+	return 0
+}
+
+func (x *UserStatsResponse) GetCurrentStreak() int32 {
+	if x != nil {
+		return x.CurrentStreak
+	}
+	return 0
+}
+
+func (x *UserStatsResponse) GetLongestStreak() int32 {
+	if x != nil {
+		return x.LongestStreak
+	}
+	return 0
+}
+
+func (x *UserStatsResponse) GetDaily() []*PeriodCount {
+	if x != nil {
+		return x.Daily
+	}
+	return nil
+}
+
+func (x *UserStatsResponse) GetWeekly() []*PeriodCount {
+	if x != nil {
+		return x.Weekly
+	}
+	return nil
+}
+
+func (x *UserStatsResponse) GetMonthly() []*PeriodCount {
+	if x != nil {
+		return x.Monthly
+	}
+	return nil
+}
+
+func (x *UserStatsResponse) GetGenres() []*NameCount {
+	if x != nil {
+		return x.Genres
+	}
+	return nil
+}
+
+func (x *UserStatsResponse) GetAuthors() []*NameCount {
+	if x != nil {
+		return x.Authors
+	}
+	return nil
+}
+
+type RecommendRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecommendRequest) Reset() {
+	*x = RecommendRequest{}
+	mi := &file_proto_manga_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecommendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecommendRequest) ProtoMessage() {}
+
+func (x *RecommendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecommendRequest.ProtoReflect.Descriptor instead.
+func (*RecommendRequest) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RecommendRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *RecommendRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type Recommendation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Manga         *MangaResponse         `protobuf:"bytes,1,opt,name=manga,proto3" json:"manga,omitempty"`
+	Score         float64                `protobuf:"fixed64,2,opt,name=score,proto3" json:"score,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Recommendation) Reset() {
+	*x = Recommendation{}
+	mi := &file_proto_manga_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Recommendation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Recommendation) ProtoMessage() {}
+
+func (x *Recommendation) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Recommendation.ProtoReflect.Descriptor instead.
+func (*Recommendation) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Recommendation) GetManga() *MangaResponse {
+	if x != nil {
+		return x.Manga
+	}
+	return nil
+}
+
+func (x *Recommendation) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Recommendation) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type RecommendResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*Recommendation      `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecommendResponse) Reset() {
+	*x = RecommendResponse{}
+	mi := &file_proto_manga_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecommendResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecommendResponse) ProtoMessage() {}
+
+func (x *RecommendResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecommendResponse.ProtoReflect.Descriptor instead.
+func (*RecommendResponse) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *RecommendResponse) GetResults() []*Recommendation {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type NotificationStreamRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NotificationStreamRequest) Reset() {
+	*x = NotificationStreamRequest{}
+	mi := &file_proto_manga_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NotificationStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NotificationStreamRequest) ProtoMessage() {}
+
+func (x *NotificationStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NotificationStreamRequest.ProtoReflect.Descriptor instead.
+func (*NotificationStreamRequest) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *NotificationStreamRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type Notification struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	MangaId       string                 `protobuf:"bytes,4,opt,name=manga_id,json=mangaId,proto3" json:"manga_id,omitempty"`
+	Chapter       int32                  `protobuf:"varint,5,opt,name=chapter,proto3" json:"chapter,omitempty"`
+	Message       string                 `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Notification) Reset() {
+	*x = Notification{}
+	mi := &file_proto_manga_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Notification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Notification) ProtoMessage() {}
+
+func (x *Notification) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Notification.ProtoReflect.Descriptor instead.
+func (*Notification) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Notification) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Notification) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Notification) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Notification) GetMangaId() string {
+	if x != nil {
+		return x.MangaId
+	}
+	return ""
+}
+
+func (x *Notification) GetChapter() int32 {
+	if x != nil {
+		return x.Chapter
+	}
+	return 0
+}
+
+func (x *Notification) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Notification) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
 var File_proto_manga_proto protoreflect.FileDescriptor
 
 const file_proto_manga_proto_rawDesc = "" +
 	"\n" +
-	"\x11proto/manga.proto\x12\bmangahub\"!\n" +
+	"\x11proto/manga.proto\x12\bmangahub\x1a google/protobuf/field_mask.proto\"!\n" +
 	"\x0fGetMangaRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\"\xc6\x01\n" +
 	"\rMangaResponse\x12\x0e\n" +
@@ -428,17 +1082,26 @@ const file_proto_manga_proto_rawDesc = "" +
 	"\x06genres\x18\x04 \x03(\tR\x06genres\x12\x16\n" +
 	"\x06status\x18\x05 \x01(\tR\x06status\x12%\n" +
 	"\x0etotal_chapters\x18\x06 \x01(\x05R\rtotalChapters\x12 \n" +
-	"\vdescription\x18\a \x01(\tR\vdescription\"\x81\x01\n" +
+	"\vdescription\x18\a \x01(\tR\vdescription\"\x90\x02\n" +
 	"\rSearchRequest\x12\x14\n" +
 	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
 	"\x05genre\x18\x02 \x01(\tR\x05genre\x12\x16\n" +
 	"\x06status\x18\x03 \x01(\tR\x06status\x12\x14\n" +
 	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x16\n" +
-	"\x06offset\x18\x05 \x01(\x05R\x06offset\"q\n" +
+	"\x06offset\x18\x05 \x01(\x05R\x06offset\x12\x17\n" +
+	"\asort_by\x18\x06 \x01(\tR\x06sortBy\x12\x16\n" +
+	"\x06cursor\x18\a \x01(\tR\x06cursor\x12#\n" +
+	"\rinclude_total\x18\b \x01(\bR\fincludeTotal\x127\n" +
+	"\tread_mask\x18\t \x01(\v2\x1a.google.protobuf.FieldMaskR\breadMask\"\xd0\x01\n" +
 	"\x0eSearchResponse\x121\n" +
 	"\aresults\x18\x01 \x03(\v2\x17.mangahub.MangaResponseR\aresults\x12\x14\n" +
 	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
-	"\x06offset\x18\x03 \x01(\x05R\x06offset\"\x86\x01\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\x12\x17\n" +
+	"\asort_by\x18\x04 \x01(\tR\x06sortBy\x12\x1f\n" +
+	"\vnext_cursor\x18\x05 \x01(\tR\n" +
+	"nextCursor\x12\x19\n" +
+	"\x05total\x18\x06 \x01(\x05H\x00R\x05total\x88\x01\x01B\b\n" +
+	"\x06_total\"\x86\x01\n" +
 	"\x0fProgressRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x19\n" +
 	"\bmanga_id\x18\x02 \x01(\tR\amangaId\x12'\n" +
@@ -446,11 +1109,63 @@ const file_proto_manga_proto_rawDesc = "" +
 	"\x06status\x18\x04 \x01(\tR\x06status\"F\n" +
 	"\x10ProgressResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2\xd9\x01\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"+\n" +
+	"\x10UserStatsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"A\n" +
+	"\vPeriodCount\x12\x16\n" +
+	"\x06period\x18\x01 \x01(\tR\x06period\x12\x1a\n" +
+	"\bchapters\x18\x02 \x01(\x05R\bchapters\"5\n" +
+	"\tNameCount\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\"\xf4\x05\n" +
+	"\x11UserStatsResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1f\n" +
+	"\vtotal_manga\x18\x02 \x01(\x05R\n" +
+	"totalManga\x12R\n" +
+	"\rstatus_counts\x18\x03 \x03(\v2-.mangahub.UserStatsResponse.StatusCountsEntryR\fstatusCounts\x12'\n" +
+	"\x0fcompletion_rate\x18\x04 \x01(\x01R\x0ecompletionRate\x12+\n" +
+	"\x12avg_days_to_finish\x18\x05 \x01(\x01R\x0favgDaysToFinish\x12%\n" +
+	"\x0echapters_today\x18\x06 \x01(\x05R\rchaptersToday\x12,\n" +
+	"\x12chapters_this_week\x18\a \x01(\x05R\x10chaptersThisWeek\x12.\n" +
+	"\x13chapters_this_month\x18\b \x01(\x05R\x11chaptersThisMonth\x12%\n" +
+	"\x0ecurrent_streak\x18\t \x01(\x05R\rcurrentStreak\x12%\n" +
+	"\x0elongest_streak\x18\n" +
+	" \x01(\x05R\rlongestStreak\x12+\n" +
+	"\x05daily\x18\v \x03(\v2\x15.mangahub.PeriodCountR\x05daily\x12-\n" +
+	"\x06weekly\x18\f \x03(\v2\x15.mangahub.PeriodCountR\x06weekly\x12/\n" +
+	"\amonthly\x18\r \x03(\v2\x15.mangahub.PeriodCountR\amonthly\x12+\n" +
+	"\x06genres\x18\x0e \x03(\v2\x13.mangahub.NameCountR\x06genres\x12-\n" +
+	"\aauthors\x18\x0f \x03(\v2\x13.mangahub.NameCountR\aauthors\x1a?\n" +
+	"\x11StatusCountsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"A\n" +
+	"\x10RecommendRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"m\n" +
+	"\x0eRecommendation\x12-\n" +
+	"\x05manga\x18\x01 \x01(\v2\x17.mangahub.MangaResponseR\x05manga\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"G\n" +
+	"\x11RecommendResponse\x122\n" +
+	"\aresults\x18\x01 \x03(\v2\x18.mangahub.RecommendationR\aresults\"4\n" +
+	"\x19NotificationStreamRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"\xb9\x01\n" +
+	"\fNotification\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x19\n" +
+	"\bmanga_id\x18\x04 \x01(\tR\amangaId\x12\x18\n" +
+	"\achapter\x18\x05 \x01(\x05R\achapter\x12\x18\n" +
+	"\amessage\x18\x06 \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\a \x01(\x03R\tcreatedAt2\xbe\x03\n" +
 	"\fMangaService\x12>\n" +
 	"\bGetManga\x12\x19.mangahub.GetMangaRequest\x1a\x17.mangahub.MangaResponse\x12@\n" +
 	"\vSearchManga\x12\x17.mangahub.SearchRequest\x1a\x18.mangahub.SearchResponse\x12G\n" +
-	"\x0eUpdateProgress\x12\x19.mangahub.ProgressRequest\x1a\x1a.mangahub.ProgressResponseB\x10Z\x0emangahub/protob\x06proto3"
+	"\x0eUpdateProgress\x12\x19.mangahub.ProgressRequest\x1a\x1a.mangahub.ProgressResponse\x12G\n" +
+	"\fGetUserStats\x12\x1a.mangahub.UserStatsRequest\x1a\x1b.mangahub.UserStatsResponse\x12D\n" +
+	"\tRecommend\x12\x1a.mangahub.RecommendRequest\x1a\x1b.mangahub.RecommendResponse\x12T\n" +
+	"\x13StreamNotifications\x12#.mangahub.NotificationStreamRequest\x1a\x16.mangahub.Notification0\x01B\x10Z\x0emangahub/protob\x06proto3"
 
 var (
 	file_proto_manga_proto_rawDescOnce sync.Once
@@ -464,28 +1179,54 @@ func file_proto_manga_proto_rawDescGZIP() []byte {
 	return file_proto_manga_proto_rawDescData
 }
 
-var file_proto_manga_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_manga_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
 var file_proto_manga_proto_goTypes = []any{
-	(*GetMangaRequest)(nil),  // 0: mangahub.GetMangaRequest
-	(*MangaResponse)(nil),    // 1: mangahub.MangaResponse
-	(*SearchRequest)(nil),    // 2: mangahub.SearchRequest
-	(*SearchResponse)(nil),   // 3: mangahub.SearchResponse
-	(*ProgressRequest)(nil),  // 4: mangahub.ProgressRequest
-	(*ProgressResponse)(nil), // 5: mangahub.ProgressResponse
+	(*GetMangaRequest)(nil),           // 0: mangahub.GetMangaRequest
+	(*MangaResponse)(nil),             // 1: mangahub.MangaResponse
+	(*SearchRequest)(nil),             // 2: mangahub.SearchRequest
+	(*SearchResponse)(nil),            // 3: mangahub.SearchResponse
+	(*ProgressRequest)(nil),           // 4: mangahub.ProgressRequest
+	(*ProgressResponse)(nil),          // 5: mangahub.ProgressResponse
+	(*UserStatsRequest)(nil),          // 6: mangahub.UserStatsRequest
+	(*PeriodCount)(nil),               // 7: mangahub.PeriodCount
+	(*NameCount)(nil),                 // 8: mangahub.NameCount
+	(*UserStatsResponse)(nil),         // 9: mangahub.UserStatsResponse
+	(*RecommendRequest)(nil),          // 10: mangahub.RecommendRequest
+	(*Recommendation)(nil),            // 11: mangahub.Recommendation
+	(*RecommendResponse)(nil),         // 12: mangahub.RecommendResponse
+	(*NotificationStreamRequest)(nil), // 13: mangahub.NotificationStreamRequest
+	(*Notification)(nil),              // 14: mangahub.Notification
+	nil,                               // 15: mangahub.UserStatsResponse.StatusCountsEntry
+	(*fieldmaskpb.FieldMask)(nil),     // 16: google.protobuf.FieldMask
 }
 var file_proto_manga_proto_depIdxs = []int32{
-	1, // 0: mangahub.SearchResponse.results:type_name -> mangahub.MangaResponse
-	0, // 1: mangahub.MangaService.GetManga:input_type -> mangahub.GetMangaRequest
-	2, // 2: mangahub.MangaService.SearchManga:input_type -> mangahub.SearchRequest
-	4, // 3: mangahub.MangaService.UpdateProgress:input_type -> mangahub.ProgressRequest
-	1, // 4: mangahub.MangaService.GetManga:output_type -> mangahub.MangaResponse
-	3, // 5: mangahub.MangaService.SearchManga:output_type -> mangahub.SearchResponse
-	5, // 6: mangahub.MangaService.UpdateProgress:output_type -> mangahub.ProgressResponse
-	4, // [4:7] is the sub-list for method output_type
-	1, // [1:4] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	16, // 0: mangahub.SearchRequest.read_mask:type_name -> google.protobuf.FieldMask
+	1,  // 1: mangahub.SearchResponse.results:type_name -> mangahub.MangaResponse
+	15, // 2: mangahub.UserStatsResponse.status_counts:type_name -> mangahub.UserStatsResponse.StatusCountsEntry
+	7,  // 3: mangahub.UserStatsResponse.daily:type_name -> mangahub.PeriodCount
+	7,  // 4: mangahub.UserStatsResponse.weekly:type_name -> mangahub.PeriodCount
+	7,  // 5: mangahub.UserStatsResponse.monthly:type_name -> mangahub.PeriodCount
+	8,  // 6: mangahub.UserStatsResponse.genres:type_name -> mangahub.NameCount
+	8,  // 7: mangahub.UserStatsResponse.authors:type_name -> mangahub.NameCount
+	1,  // 8: mangahub.Recommendation.manga:type_name -> mangahub.MangaResponse
+	11, // 9: mangahub.RecommendResponse.results:type_name -> mangahub.Recommendation
+	0,  // 10: mangahub.MangaService.GetManga:input_type -> mangahub.GetMangaRequest
+	2,  // 11: mangahub.MangaService.SearchManga:input_type -> mangahub.SearchRequest
+	4,  // 12: mangahub.MangaService.UpdateProgress:input_type -> mangahub.ProgressRequest
+	6,  // 13: mangahub.MangaService.GetUserStats:input_type -> mangahub.UserStatsRequest
+	10, // 14: mangahub.MangaService.Recommend:input_type -> mangahub.RecommendRequest
+	13, // 15: mangahub.MangaService.StreamNotifications:input_type -> mangahub.NotificationStreamRequest
+	1,  // 16: mangahub.MangaService.GetManga:output_type -> mangahub.MangaResponse
+	3,  // 17: mangahub.MangaService.SearchManga:output_type -> mangahub.SearchResponse
+	5,  // 18: mangahub.MangaService.UpdateProgress:output_type -> mangahub.ProgressResponse
+	9,  // 19: mangahub.MangaService.GetUserStats:output_type -> mangahub.UserStatsResponse
+	12, // 20: mangahub.MangaService.Recommend:output_type -> mangahub.RecommendResponse
+	14, // 21: mangahub.MangaService.StreamNotifications:output_type -> mangahub.Notification
+	16, // [16:22] is the sub-list for method output_type
+	10, // [10:16] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_proto_manga_proto_init() }
@@ -493,13 +1234,14 @@ func file_proto_manga_proto_init() {
 	if File_proto_manga_proto != nil {
 		return
 	}
+	file_proto_manga_proto_msgTypes[3].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_manga_proto_rawDesc), len(file_proto_manga_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   16,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,9 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MangaService_GetManga_FullMethodName       = "/mangahub.MangaService/GetManga"
-	MangaService_SearchManga_FullMethodName    = "/mangahub.MangaService/SearchManga"
-	MangaService_UpdateProgress_FullMethodName = "/mangahub.MangaService/UpdateProgress"
+	MangaService_GetManga_FullMethodName            = "/mangahub.MangaService/GetManga"
+	MangaService_SearchManga_FullMethodName         = "/mangahub.MangaService/SearchManga"
+	MangaService_UpdateProgress_FullMethodName      = "/mangahub.MangaService/UpdateProgress"
+	MangaService_GetUserStats_FullMethodName        = "/mangahub.MangaService/GetUserStats"
+	MangaService_Recommend_FullMethodName           = "/mangahub.MangaService/Recommend"
+	MangaService_StreamNotifications_FullMethodName = "/mangahub.MangaService/StreamNotifications"
 )
 
 // MangaServiceClient is the client API for MangaService service.
@@ -33,6 +36,9 @@ type MangaServiceClient interface {
 	GetManga(ctx context.Context, in *GetMangaRequest, opts ...grpc.CallOption) (*MangaResponse, error)
 	SearchManga(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
 	UpdateProgress(ctx context.Context, in *ProgressRequest, opts ...grpc.CallOption) (*ProgressResponse, error)
+	GetUserStats(ctx context.Context, in *UserStatsRequest, opts ...grpc.CallOption) (*UserStatsResponse, error)
+	Recommend(ctx context.Context, in *RecommendRequest, opts ...grpc.CallOption) (*RecommendResponse, error)
+	StreamNotifications(ctx context.Context, in *NotificationStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Notification], error)
 }
 
 type mangaServiceClient struct {
@@ -73,6 +79,45 @@ func (c *mangaServiceClient) UpdateProgress(ctx context.Context, in *ProgressReq
 	return out, nil
 }
 
+func (c *mangaServiceClient) GetUserStats(ctx context.Context, in *UserStatsRequest, opts ...grpc.CallOption) (*UserStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserStatsResponse)
+	err := c.cc.Invoke(ctx, MangaService_GetUserStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mangaServiceClient) Recommend(ctx context.Context, in *RecommendRequest, opts ...grpc.CallOption) (*RecommendResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecommendResponse)
+	err := c.cc.Invoke(ctx, MangaService_Recommend_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mangaServiceClient) StreamNotifications(ctx context.Context, in *NotificationStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Notification], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MangaService_ServiceDesc.Streams[0], MangaService_StreamNotifications_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[NotificationStreamRequest, Notification]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MangaService_StreamNotificationsClient = grpc.ServerStreamingClient[Notification]
+
 // MangaServiceServer is the server API for MangaService service.
 // All implementations must embed UnimplementedMangaServiceServer
 // for forward compatibility.
@@ -82,6 +127,9 @@ type MangaServiceServer interface {
 	GetManga(context.Context, *GetMangaRequest) (*MangaResponse, error)
 	SearchManga(context.Context, *SearchRequest) (*SearchResponse, error)
 	UpdateProgress(context.Context, *ProgressRequest) (*ProgressResponse, error)
+	GetUserStats(context.Context, *UserStatsRequest) (*UserStatsResponse, error)
+	Recommend(context.Context, *RecommendRequest) (*RecommendResponse, error)
+	StreamNotifications(*NotificationStreamRequest, grpc.ServerStreamingServer[Notification]) error
 	mustEmbedUnimplementedMangaServiceServer()
 }
 
@@ -101,6 +149,15 @@ func (UnimplementedMangaServiceServer) SearchManga(context.Context, *SearchReque
 func (UnimplementedMangaServiceServer) UpdateProgress(context.Context, *ProgressRequest) (*ProgressResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method UpdateProgress not implemented")
 }
+func (UnimplementedMangaServiceServer) GetUserStats(context.Context, *UserStatsRequest) (*UserStatsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetUserStats not implemented")
+}
+func (UnimplementedMangaServiceServer) Recommend(context.Context, *RecommendRequest) (*RecommendResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Recommend not implemented")
+}
+func (UnimplementedMangaServiceServer) StreamNotifications(*NotificationStreamRequest, grpc.ServerStreamingServer[Notification]) error {
+	return status.Error(codes.Unimplemented, "method StreamNotifications not implemented")
+}
 func (UnimplementedMangaServiceServer) mustEmbedUnimplementedMangaServiceServer() {}
 func (UnimplementedMangaServiceServer) testEmbeddedByValue()                      {}
 
@@ -176,6 +233,53 @@ func _MangaService_UpdateProgress_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MangaService_GetUserStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MangaServiceServer).GetUserStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MangaService_GetUserStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MangaServiceServer).GetUserStats(ctx, req.(*UserStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MangaService_Recommend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecommendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MangaServiceServer).Recommend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MangaService_Recommend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MangaServiceServer).Recommend(ctx, req.(*RecommendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MangaService_StreamNotifications_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(NotificationStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MangaServiceServer).StreamNotifications(m, &grpc.GenericServerStream[NotificationStreamRequest, Notification]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MangaService_StreamNotificationsServer = grpc.ServerStreamingServer[Notification]
+
 // MangaService_ServiceDesc is the grpc.ServiceDesc for MangaService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -195,7 +299,21 @@ var MangaService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateProgress",
 			Handler:    _MangaService_UpdateProgress_Handler,
 		},
+		{
+			MethodName: "GetUserStats",
+			Handler:    _MangaService_GetUserStats_Handler,
+		},
+		{
+			MethodName: "Recommend",
+			Handler:    _MangaService_Recommend_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamNotifications",
+			Handler:       _MangaService_StreamNotifications_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/manga.proto",
 }